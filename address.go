@@ -0,0 +1,77 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// addressAbbreviations maps USPS street suffixes, directionals and unit designators, and their common variants, to the USPS standard abbreviation
+var addressAbbreviations = map[string]string{
+	// Directionals
+	"north": "n", "south": "s", "east": "e", "west": "w",
+	"northeast": "ne", "northwest": "nw", "southeast": "se", "southwest": "sw",
+	// Street suffixes
+	"alley": "aly", "allee": "aly", "ally": "aly",
+	"avenue": "ave", "av": "ave", "aven": "ave", "avenu": "ave", "avn": "ave", "avnue": "ave",
+	"boulevard": "blvd", "boul": "blvd", "boulv": "blvd",
+	"circle": "cir", "circ": "cir", "circl": "cir", "crcl": "cir", "crcle": "cir",
+	"court": "ct", "crt": "ct",
+	"crossing": "xing", "crssng": "xing",
+	"drive": "dr", "driv": "dr", "drv": "dr",
+	"expressway": "expy", "expr": "expy", "express": "expy", "expw": "expy",
+	"freeway": "fwy", "frway": "fwy", "frwy": "fwy",
+	"highway": "hwy", "highwy": "hwy", "hiway": "hwy", "hiwy": "hwy", "hway": "hwy",
+	"lane":    "ln",
+	"parkway": "pkwy", "parkwy": "pkwy", "pkway": "pkwy", "pky": "pkwy",
+	"place": "pl",
+	"plaza": "plz", "plza": "plz",
+	"point":  "pt",
+	"road":   "rd",
+	"square": "sq", "sqr": "sq", "sqre": "sq", "squ": "sq",
+	"street": "st", "strt": "st", "str": "st",
+	"terrace": "ter", "terr": "ter",
+	"trail": "trl", "trails": "trl", "trls": "trl",
+	// Unit designators
+	"apartment": "apt", "building": "bldg", "floor": "fl", "suite": "ste", "unit": "unit", "room": "rm",
+}
+
+var reAddressPunctuation = regexp.MustCompile(`[^\p{L}\p{N}\s]`)
+
+// normalizeAddress puts an address line in a canonical form so variants fingerprint the same
+// It lowercases, strips punctuation, collapses whitespace and abbreviates street suffixes, directionals and unit designators,
+// so "123 North Main Street" and "123 N. Main St" both become "123 n main st". Unknown words are left unchanged.
+func normalizeAddress(line string) string {
+	var words = strings.Fields(reAddressPunctuation.ReplaceAllString(strings.ToLower(line), ""))
+	for i, word := range words {
+		if abbr, ok := addressAbbreviations[word]; ok {
+			words[i] = abbr
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// addressKeys are the keys looked up by fingerprint_address_map for each address part, in order of preference
+var addressKeys = [][]string{
+	{"address", "line1", "address1", "street"},
+	{"city", "locality"},
+	{"state", "region", "province"},
+	{"zip", "postal_code", "postalCode", "zipcode"},
+	{"plus4", "plus4Code", "zip4"},
+}
+
+// addressPart converts an address part to a string, keeping numbers such as a json.Number zip code
+func addressPart(i interface{}) string {
+	switch v := i.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	s, err := interfaceToString(i)
+	if err != nil {
+		return fmt.Sprint(i)
+	}
+	return s
+}
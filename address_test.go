@@ -0,0 +1,45 @@
+package template
+
+import (
+	"testing"
+)
+
+func TestNormalizeAddress(t *testing.T) {
+	var cases = map[string]string{
+		"123 North Main Street":             "123 n main st",
+		"123 N. Main St.":                   "123 n main st",
+		"123  n main   STR":                 "123 n main st",
+		"45 Southwest Park Avenue":          "45 sw park ave",
+		"45 SW Park Ave.":                   "45 sw park ave",
+		"9 Sunset Boulevard, Suite 5":       "9 sunset blvd ste 5",
+		"9 Sunset Blvd #5":                  "9 sunset blvd 5",
+		"1 Pacific Coast Highway":           "1 pacific coast hwy",
+		"77 Oak Lane Apartment 3B":          "77 oak ln apt 3b",
+		"500 Country Club Drive East":       "500 country club dr e",
+		"12 Martin Luther King Jr Pkwy":     "12 martin luther king jr pkwy",
+		"12 Martin Luther King Jr. Parkway": "12 martin luther king jr pkwy",
+		"8 O'Connell Court":                 "8 oconnell ct",
+		"Calle Mayor 5":                     "calle mayor 5",
+	}
+	for input, expected := range cases {
+		res, err := Interpolate(map[string]interface{}{"line": input}, `{{ normalizeAddress .line }}`)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if res != expected {
+			t.Errorf(`Unexpected result %q for %q`, res, input)
+		}
+	}
+}
+
+func TestNormalizeAddressFingerprint(t *testing.T) {
+	res, err := Interpolate(nil, `{{ fingerprint_address (normalizeAddress "123 North Main Street") "City" "ST" "12345" "" }}|{{ fingerprint_address (normalizeAddress "123 N Main St.") "City" "ST" "12345" "" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "123_n_main_st_city_st_12345_|123_n_main_st_city_st_12345_" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
@@ -0,0 +1,38 @@
+package template
+
+import (
+	"fmt"
+)
+
+// AssertionError is returned when a template fails a check with assert or fail
+// Unlike other errors it reports invalid data rather than a broken template or system, find it with errors.As
+type AssertionError struct {
+	// Message passed to assert or fail
+	Message string
+}
+
+// Error implementation for AssertionError
+func (e *AssertionError) Error() string {
+	return "assertion failed: " + e.Message
+}
+
+// isTruthy reports whether v is true, or isn't false or empty as reported by isEmptyValue
+func isTruthy(v interface{}) bool {
+	if b, ok := v.(bool); ok {
+		return b
+	}
+	return !isEmptyValue(v)
+}
+
+// fail aborts the execution with an AssertionError carrying message
+func fail(message interface{}) (string, error) {
+	return "", &AssertionError{Message: fmt.Sprint(message)}
+}
+
+// assert aborts the execution with an AssertionError carrying message when condition is false or empty, returning an empty string otherwise
+func assert(condition interface{}, message interface{}) (string, error) {
+	if isTruthy(condition) {
+		return "", nil
+	}
+	return fail(message)
+}
@@ -0,0 +1,49 @@
+package template
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestAssert(t *testing.T) {
+	var data = map[string]interface{}{"amount": 12.5, "currency": "USD", "items": []interface{}{"a"}}
+	result, err := InterpolateStrict(data, `{{ assert (gt (float64 .amount) 0.0) "amount must be positive" }}{{ assert .items "no items" }}{{ assert true "unreachable" }}ok`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if result != "ok" {
+		t.Errorf(`Unexpected result %q`, result)
+	}
+
+	var cases = map[string]string{
+		`{{ assert (lt (float64 .amount) 0.0) "amount must be negative" }}`: "amount must be negative",
+		`{{ assert false "false" }}`:                                        "false",
+		`{{ assert 0 "zero" }}`:                                             "zero",
+		`{{ assert .missing "missing" }}`:                                   "missing",
+		`{{ assert (list) "empty list" }}`:                                  "empty list",
+		`{{ assert .zero "json zero" }}`:                                    "json zero",
+		`{{ if not (or (eq .currency "EUR") (eq .currency "GBP")) }}{{ fail "unsupported currency" }}{{ end }}`: "unsupported currency",
+		`{{ fail 42 }}`: "42",
+	}
+	data["zero"] = json.Number("0")
+	for src, message := range cases {
+		_, err := InterpolateStrict(data, src)
+		var assertErr *AssertionError
+		if !errors.As(err, &assertErr) {
+			t.Errorf(`Expected AssertionError for %s, got %v`, src, err)
+			continue
+		}
+		if assertErr.Message != message {
+			t.Errorf(`Unexpected message %q for %s`, assertErr.Message, src)
+		}
+	}
+
+	// Other errors aren't assertion failures
+	_, err = InterpolateStrict(data, `{{ semverParse .currency }}`)
+	var assertErr *AssertionError
+	if err == nil || errors.As(err, &assertErr) {
+		t.Errorf(`Unexpected error %v`, err)
+	}
+}
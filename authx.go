@@ -0,0 +1,156 @@
+package template
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var authxCacheTTL time.Duration
+var authxRefreshMargin = time.Minute
+
+// SetAuthXCacheTTL caps how long authx bearer tokens are cached, regardless of their expiry
+// Zero removes the cap, caching tokens until the refresh margin before they expire
+func SetAuthXCacheTTL(ttl time.Duration) {
+	authxCacheTTL = ttl
+}
+
+// SetAuthXRefreshMargin sets how long before expiry a cached authx bearer token is refreshed
+// Defaults to 1 minute
+func SetAuthXRefreshMargin(margin time.Duration) {
+	authxRefreshMargin = margin
+}
+
+// AuthXCacheKey returns the key a bearer token fetched by getAuthXBearerToken is cached under
+// The format is "<authxURL>::<authxToken>::<authorizationId>"
+func AuthXCacheKey(authxURL, authxToken, authorizationId string) string {
+	return strings.Join([]string{authxURL, authxToken, authorizationId}, "::")
+}
+
+// PurgeAuthXToken removes a cached bearer token by its AuthXCacheKey so the next call fetches a new one
+func PurgeAuthXToken(cacheKey string) {
+	authxTokenCache.Delete(cacheKey)
+}
+
+// authxBearerTokenFunc returns getAuthXBearerToken making its requests with do
+// getAuthXBearerToken fetches a bearer token for an authorization from authx, caching it until shortly before it expires
+func authxBearerTokenFunc(do httpDoer) func(authxURL, authxToken, authorizationId string) (string, error) {
+	return func(authxURL, authxToken, authorizationId string) (string, error) {
+		return authxBearerToken(do, authxURL, authxToken, authorizationId)
+	}
+}
+
+// authxBearerTokenFreshFunc returns getAuthXBearerTokenFresh making its requests with do
+// getAuthXBearerTokenFresh fetches a bearer token from authx, bypassing and then replacing any cached token,
+// which is useful after the upstream has revoked a cached token
+func authxBearerTokenFreshFunc(do httpDoer) func(authxURL, authxToken, authorizationId string) (string, error) {
+	return func(authxURL, authxToken, authorizationId string) (string, error) {
+		return authxBearerTokenFresh(do, authxURL, authxToken, authorizationId)
+	}
+}
+
+// authxBearerToken is getAuthXBearerToken with the token requested by do
+func authxBearerToken(do httpDoer, authxURL, authxToken, authorizationId string) (string, error) {
+	cachedToken, _ := authxTokenCache.Get(AuthXCacheKey(authxURL, authxToken, authorizationId))
+	if cachedTokenString, ok := cachedToken.(string); ok {
+		return cachedTokenString, nil
+	}
+	return authxBearerTokenFresh(do, authxURL, authxToken, authorizationId)
+}
+
+// authxBearerTokenFresh is getAuthXBearerTokenFresh with the token requested by do
+func authxBearerTokenFresh(do httpDoer, authxURL, authxToken, authorizationId string) (string, error) {
+	var cacheKey = AuthXCacheKey(authxURL, authxToken, authorizationId)
+	var err error
+	var graphqlQuery = fmt.Sprintf(`query {
+			authorization(id: %q) {
+				token(format:BEARER)
+			}
+		}`, authorizationId)
+	var requestQuery = map[string]interface{}{
+		"query": graphqlQuery,
+	}
+	var requestBody []byte
+	requestBody, err = json.Marshal(requestQuery)
+	if err != nil {
+		return "", fmt.Errorf("authx %s: %w", authxURL, err)
+	}
+	var req *http.Request
+	req, err = http.NewRequest("POST", authxURL, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return "", fmt.Errorf("authx %s: %w", authxURL, err)
+	}
+	req.Header.Set("Authorization", authxToken)
+	req.Header.Set("Content-Type", "application/json")
+	var res *http.Response
+	res, err = do(req)
+	if err != nil {
+		return "", fmt.Errorf("authx %s: %w", authxURL, err)
+	}
+	defer res.Body.Close()
+	var body []byte
+	body, err = readJSONLimited(res.Body)
+	if err != nil {
+		return "", fmt.Errorf("authx %s: %w", authxURL, err)
+	}
+	var tokenResponse struct {
+		Errors []struct {
+			Message string
+		}
+		Data struct {
+			Authorization struct {
+				Token string
+			}
+		}
+	}
+	err = unmarshalJSONLimited(body, &tokenResponse)
+	if err != nil {
+		return "", fmt.Errorf("authx %s: %w", authxURL, err)
+	}
+	if len(tokenResponse.Errors) > 0 {
+		return "", fmt.Errorf("authx %s error: %s", authxURL, tokenResponse.Errors[0].Message)
+	}
+	var authxBearerToken = tokenResponse.Data.Authorization.Token
+	authParts := strings.Split(authxBearerToken, " ")
+	if len(authParts) != 2 {
+		return "", fmt.Errorf("authx %s: malformed bearer token", authxURL)
+	}
+	tokenParts := strings.Split(authParts[1], ".")
+	if len(tokenParts) != 3 {
+		return "", fmt.Errorf("authx %s: malformed jwt", authxURL)
+	}
+	jwtBase64 := tokenParts[1]
+	var jwtBytes []byte
+	jwtBytes, err = base64.RawURLEncoding.DecodeString(jwtBase64)
+	if err != nil {
+		return "", fmt.Errorf("authx %s: %w", authxURL, err)
+	}
+	var jwt struct {
+		AID    string
+		Scopes []string
+		IAT    int64
+		EXP    int64
+		ISS    string
+		SUB    string
+		JTI    string
+	}
+	err = json.Unmarshal(jwtBytes, &jwt)
+	if err != nil {
+		return "", fmt.Errorf("authx %s: %w", authxURL, err)
+	}
+	var expireAt = time.Duration(jwt.EXP-time.Now().Unix())*time.Second - authxRefreshMargin
+	if authxCacheTTL > 0 && authxCacheTTL < expireAt {
+		expireAt = authxCacheTTL
+	}
+	// A token already within the refresh margin is returned but not cached, replacing any cached token
+	if expireAt <= 0 {
+		authxTokenCache.Delete(cacheKey)
+		return authxBearerToken, nil
+	}
+	authxTokenCache.SetEx(cacheKey, authxBearerToken, expireAt)
+	return authxBearerToken, nil
+}
@@ -0,0 +1,208 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newAuthXTestServer(token string) *httptest.Server {
+	return newCountingAuthXTestServer(token, new(int))
+}
+
+func newCountingAuthXTestServer(token string, hits *int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*hits++
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"data": map[string]interface{}{
+				"authorization": map[string]interface{}{
+					"token": token,
+				},
+			},
+		})
+	}))
+}
+
+func TestGetAuthXBearerTokenConnectionClosed(t *testing.T) {
+	var err error
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			return
+		}
+		conn.Close()
+	}))
+	defer srv.Close()
+
+	var jsondata = []byte(`"{{ getAuthXBearerToken .url \"token\" \"closed\" }}"`)
+	var tmpl *Template
+	err = json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{
+		"url": srv.URL,
+	})
+	if err == nil {
+		t.Error("Expected error when connection is closed")
+		return
+	}
+	if !strings.Contains(err.Error(), srv.URL) {
+		t.Errorf(`Expected error to include authx url, got %q`, err.Error())
+	}
+}
+
+func TestGetAuthXBearerTokenMalformed(t *testing.T) {
+	var err error
+	for _, token := range []string{"", "Bearer", "Bearer abc"} {
+		srv := newAuthXTestServer(token)
+		_, err = authxBearerToken(doHTTP, srv.URL, "token", "malformed")
+		srv.Close()
+		if err == nil {
+			t.Errorf(`Expected error for malformed token %q`, token)
+		}
+	}
+}
+
+func TestGetAuthXBearerToken(t *testing.T) {
+	var err error
+	claims, _ := json.Marshal(map[string]interface{}{
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	var token = fmt.Sprintf("Bearer e30.%s.sig", base64.RawURLEncoding.EncodeToString(claims))
+	srv := newAuthXTestServer(token)
+	defer srv.Close()
+
+	var jsondata = []byte(`"{{ getAuthXBearerToken .url \"token\" \"valid\" }}"`)
+	var tmpl *Template
+	err = json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{
+		"url": srv.URL,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != token {
+		t.Errorf(`Unexpected result %q`, buf.String())
+	}
+}
+
+func TestGetAuthXBearerTokenFreshAndPurge(t *testing.T) {
+	var err error
+	claims, _ := json.Marshal(map[string]interface{}{
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	var token = fmt.Sprintf("Bearer e30.%s.sig", base64.RawURLEncoding.EncodeToString(claims))
+	var hits int
+	srv := newCountingAuthXTestServer(token, &hits)
+	defer srv.Close()
+
+	var jsondata = []byte(`"{{ getAuthXBearerToken .url \"token\" \"fresh\" }}{{ getAuthXBearerToken .url \"token\" \"fresh\" }}{{ getAuthXBearerTokenFresh .url \"token\" \"fresh\" }}"`)
+	var tmpl *Template
+	err = json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{
+		"url": srv.URL,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if hits != 2 {
+		t.Errorf(`Expected 2 requests to authx, got %d`, hits)
+	}
+
+	PurgeAuthXToken(AuthXCacheKey(srv.URL, "token", "fresh"))
+	_, err = authxBearerToken(doHTTP, srv.URL, "token", "fresh")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if hits != 3 {
+		t.Errorf(`Expected purged token to be fetched again, got %d requests`, hits)
+	}
+}
+
+func TestGetAuthXBearerTokenContext(t *testing.T) {
+	claims, _ := json.Marshal(map[string]interface{}{
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+	srv := newAuthXTestServer(fmt.Sprintf("Bearer e30.%s.sig", base64.RawURLEncoding.EncodeToString(claims)))
+	defer srv.Close()
+	defer PurgeAuthXToken(AuthXCacheKey(srv.URL, "token", "context"))
+
+	var traceIDs []interface{}
+	SetHTTPLogger(func(ctx context.Context, method, url string, header http.Header, status int, duration time.Duration, err error) {
+		traceIDs = append(traceIDs, ctx.Value(traceIDKey{}))
+	}, nil)
+	defer SetHTTPLogger(nil, nil)
+
+	tmpl, err := Parse(`{{ getAuthXBearerToken .url "token" "context" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var data = map[string]interface{}{"url": srv.URL}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = tmpl.ExecuteContext(ctx, &bytes.Buffer{}, data)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf(`Expected the cancelled context to stop the request, got %v`, err)
+	}
+
+	err = tmpl.ExecuteContext(context.WithValue(context.Background(), traceIDKey{}, "trace-1"), &bytes.Buffer{}, data)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(traceIDs) != 2 || traceIDs[1] != "trace-1" {
+		t.Errorf(`Unexpected trace IDs %v`, traceIDs)
+	}
+}
+
+func TestGetAuthXBearerTokenExpired(t *testing.T) {
+	claims, _ := json.Marshal(map[string]interface{}{
+		"exp": time.Now().Add(-time.Minute).Unix(),
+	})
+	var token = fmt.Sprintf("Bearer e30.%s.sig", base64.RawURLEncoding.EncodeToString(claims))
+	var hits int
+	srv := newCountingAuthXTestServer(token, &hits)
+	defer srv.Close()
+	for i := 0; i < 2; i++ {
+		res, err := authxBearerToken(doHTTP, srv.URL, "token", "expired")
+		if err != nil || res != token {
+			t.Errorf(`Unexpected result %q: %v`, res, err)
+			return
+		}
+	}
+	if hits != 2 {
+		t.Errorf(`Expected an expired token not to be cached, got %d requests`, hits)
+	}
+}
+
+func TestAuthXCacheKey(t *testing.T) {
+	if AuthXCacheKey("https://authx", "token", "id") != "https://authx::token::id" {
+		t.Fail()
+	}
+}
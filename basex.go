@@ -0,0 +1,158 @@
+package template
+
+import (
+	"encoding/base32"
+	"fmt"
+	"strings"
+)
+
+// crockfordAlphabet is Douglas Crockford's base32 alphabet, which excludes I, L, O and U
+const crockfordAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+var crockfordEncoding = base32.NewEncoding(crockfordAlphabet).WithPadding(base32.NoPadding)
+
+// crockfordNormalizer maps the characters Crockford decoding accepts in place of others, and drops hyphens
+var crockfordNormalizer = strings.NewReplacer("I", "1", "L", "1", "O", "0", "-", "")
+
+// base58Alphabet is the Bitcoin base58 alphabet, which excludes 0, O, I and l
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+var base58Index = func() (index [256]int8) {
+	for i := range index {
+		index[i] = -1
+	}
+	for i := 0; i < len(base58Alphabet); i++ {
+		index[base58Alphabet[i]] = int8(i)
+	}
+	return
+}()
+
+// interfaceToBytes returns the bytes of a string or []byte
+func interfaceToBytes(v interface{}) ([]byte, error) {
+	switch b := v.(type) {
+	case string:
+		return []byte(b), nil
+	case []byte:
+		return b, nil
+	default:
+		return nil, fmt.Errorf("expected a string or []byte, got %T", v)
+	}
+}
+
+// base32Alphabet returns the encoding named by the optional alphabet argument of b32enc and b32dec
+func base32Alphabet(alphabet []string) (*base32.Encoding, error) {
+	if len(alphabet) == 0 {
+		return base32.StdEncoding, nil
+	}
+	switch strings.ToLower(alphabet[0]) {
+	case "std", "standard", "":
+		return base32.StdEncoding, nil
+	case "crockford":
+		return crockfordEncoding, nil
+	default:
+		return nil, fmt.Errorf("unknown base32 alphabet %q, expected std or crockford", alphabet[0])
+	}
+}
+
+// b32enc encodes v as padded RFC 4648 base32, or unpadded Crockford base32 when the alphabet is "crockford"
+func b32enc(v interface{}, alphabet ...string) (string, error) {
+	b, err := interfaceToBytes(v)
+	if err != nil {
+		return "", err
+	}
+	enc, err := base32Alphabet(alphabet)
+	if err != nil {
+		return "", err
+	}
+	return enc.EncodeToString(b), nil
+}
+
+// b32dec decodes base32 encoded with b32enc and the same alphabet
+// Crockford input is case insensitive, may contain hyphens, and reads I and L as 1 and O as 0
+func b32dec(v interface{}, alphabet ...string) (string, error) {
+	b, err := interfaceToBytes(v)
+	if err != nil {
+		return "", err
+	}
+	enc, err := base32Alphabet(alphabet)
+	if err != nil {
+		return "", err
+	}
+	var s = string(b)
+	if enc == crockfordEncoding {
+		s = crockfordNormalizer.Replace(strings.ToUpper(s))
+	}
+	decoded, err := enc.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("b32dec: %w", err)
+	}
+	return string(decoded), nil
+}
+
+// base58enc encodes v with the Bitcoin base58 alphabet, each leading zero byte is encoded as a leading 1
+func base58enc(v interface{}) (string, error) {
+	b, err := interfaceToBytes(v)
+	if err != nil {
+		return "", err
+	}
+	var zeros int
+	for zeros < len(b) && b[zeros] == 0 {
+		zeros++
+	}
+	// Base 58 digits, least significant first; log(256)/log(58) < 1.37
+	var digits = make([]byte, 0, len(b)*137/100+1)
+	for _, c := range b[zeros:] {
+		var carry = int(c)
+		for i := range digits {
+			carry += int(digits[i]) << 8
+			digits[i] = byte(carry % 58)
+			carry /= 58
+		}
+		for carry > 0 {
+			digits = append(digits, byte(carry%58))
+			carry /= 58
+		}
+	}
+	var out = make([]byte, zeros+len(digits))
+	for i := 0; i < zeros; i++ {
+		out[i] = base58Alphabet[0]
+	}
+	for i, d := range digits {
+		out[len(out)-1-i] = base58Alphabet[d]
+	}
+	return string(out), nil
+}
+
+// base58dec decodes Bitcoin base58 encoded with base58enc
+func base58dec(v interface{}) (string, error) {
+	b, err := interfaceToBytes(v)
+	if err != nil {
+		return "", err
+	}
+	var zeros int
+	for zeros < len(b) && b[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	// Bytes, least significant first
+	var bytes = make([]byte, 0, len(b)*733/1000+1)
+	for i, c := range b[zeros:] {
+		var carry = int(base58Index[c])
+		if carry < 0 {
+			return "", fmt.Errorf("base58dec: illegal base58 data at input byte %d", zeros+i)
+		}
+		for j := range bytes {
+			carry += int(bytes[j]) * 58
+			bytes[j] = byte(carry)
+			carry >>= 8
+		}
+		for carry > 0 {
+			bytes = append(bytes, byte(carry))
+			carry >>= 8
+		}
+	}
+	var out = make([]byte, zeros+len(bytes))
+	for i, c := range bytes {
+		out[len(out)-1-i] = c
+	}
+	return string(out), nil
+}
@@ -0,0 +1,92 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBase32(t *testing.T) {
+	var cases = []struct {
+		src, alphabet, encoded string
+	}{
+		{"", "", ""},
+		{"f", "", "MY======"},
+		{"foobar", "", "MZXW6YTBOI======"},
+		{"foobar", "crockford", "CSQPYRK1E8"},
+		{"\x00\x01\xff", "crockford", "000ZY"},
+	}
+	for _, c := range cases {
+		res, err := InterpolateStrict(map[string]interface{}{"s": c.src, "a": c.alphabet}, `{{ b32enc .s .a }}`)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if res != c.encoded {
+			t.Errorf(`Unexpected encoding of %q: %q`, c.src, res)
+		}
+		decoded, err := b32dec(res, c.alphabet)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if decoded != c.src {
+			t.Errorf(`Unexpected round trip of %q: %q`, c.src, decoded)
+		}
+	}
+
+	res, err := b32dec("csqp-yrkl-e8", "crockford")
+	if err != nil || res != "foobar" {
+		t.Errorf(`Unexpected lenient crockford decoding %q, %v`, res, err)
+	}
+	_, err = b32dec("MZXW6YTBOI=====!")
+	if err == nil {
+		t.Errorf(`Expected invalid base32 to fail`)
+	}
+	_, err = b32dec("CSQPYRKUE8", "crockford")
+	if err == nil {
+		t.Errorf(`Expected U to be rejected by crockford decoding`)
+	}
+	_, err = b32enc("x", "hex")
+	if err == nil || !strings.Contains(err.Error(), "unknown base32 alphabet") {
+		t.Errorf(`Expected unknown alphabet error, got %v`, err)
+	}
+	_, err = b32enc(42)
+	if err == nil {
+		t.Errorf(`Expected non string input to fail`)
+	}
+}
+
+func TestBase58(t *testing.T) {
+	var cases = []struct {
+		src, encoded string
+	}{
+		{"", ""},
+		{"Hello World!", "2NEpo7TZRRrLZSi2U"},
+		{"The quick brown fox jumps over the lazy dog.", "USm3fpXnKG5EUBx2ndxBDMPVciP5hGey2Jh4NDv6gmeo1LkMeiKrLJUUBk6Z"},
+		{"\x00\x00\x28\x7f\xb4\xcd", "11233QC4"},
+		{"\x00", "1"},
+	}
+	for _, c := range cases {
+		res, err := InterpolateStrict(map[string]interface{}{"s": []byte(c.src)}, `{{ base58enc .s }}`)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if res != c.encoded {
+			t.Errorf(`Unexpected encoding of %q: %q`, c.src, res)
+		}
+		decoded, err := base58dec(res)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if decoded != c.src {
+			t.Errorf(`Unexpected round trip of %q: %q`, c.src, decoded)
+		}
+	}
+
+	_, err := base58dec("2NEpo7TZRR0LZSi2U")
+	if err == nil || !strings.Contains(err.Error(), "input byte 10") {
+		t.Errorf(`Expected invalid base58 to fail, got %v`, err)
+	}
+}
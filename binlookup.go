@@ -0,0 +1,121 @@
+package template
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultBINLookupURL is the endpoint binLookup requests unless another is set with SetBINLookupURL
+const DefaultBINLookupURL = "https://lookup.binlist.net/{bin}"
+
+// binLookupTTL is how long binLookup caches answers, BIN assignments rarely change
+const binLookupTTL = 24 * time.Hour
+
+var binLookupURL = DefaultBINLookupURL
+var binLookupCache = newTTLCache(binLookupTTL)
+
+// SetBINLookupURL sets the endpoint binLookup requests, with {bin} replaced by the BIN
+// The endpoint must answer in the binlist.net format. An empty url restores DefaultBINLookupURL.
+func SetBINLookupURL(url string) {
+	if url == "" {
+		url = DefaultBINLookupURL
+	}
+	binLookupURL = url
+}
+
+// binLookupFunc returns the binLookup template function sending requests with do
+// Dry runs don't use the cache, so their canned answers never replace real ones.
+func binLookupFunc(do httpDoer, cached bool) func(bin interface{}) (map[string]interface{}, error) {
+	return func(bin interface{}) (map[string]interface{}, error) {
+		return binLookup(do, cached, bin)
+	}
+}
+
+// binLookup returns the scheme, type, brand and country of a card BIN (the first 6 to 8 digits of a card number)
+// Unknown BINs return nil. Answers, including unknown BINs, are cached for 24 hours. Requests are subject to
+// the http rate limits, and the endpoint refusing a request for exceeding its own rate limit is an error.
+func binLookup(do httpDoer, cached bool, bin interface{}) (map[string]interface{}, error) {
+	s, err := interfaceToString(bin)
+	if err != nil {
+		return nil, fmt.Errorf("binLookup: %w", err)
+	}
+	s = strings.TrimSpace(s)
+	if len(s) < 6 || len(s) > 8 || strings.Trim(s, "0123456789") != "" {
+		return nil, fmt.Errorf("binLookup: BIN must be 6 to 8 digits, got %q", s)
+	}
+	var url = strings.ReplaceAll(binLookupURL, "{bin}", s)
+	if cached {
+		if v, err := binLookupCache.Get(url); err == nil {
+			result, _ := v.(map[string]interface{})
+			return copyBINResult(result), nil
+		}
+	}
+	result, err := requestBIN(do, url)
+	if err != nil {
+		return nil, fmt.Errorf("binLookup %s: %w", s, err)
+	}
+	if cached {
+		binLookupCache.SetEx(url, result, binLookupTTL)
+	}
+	return copyBINResult(result), nil
+}
+
+// requestBIN requests url and parses the binlist.net answer, returning nil for unknown BINs
+func requestBIN(do httpDoer, url string) (map[string]interface{}, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("Accept-Version", "3")
+	res, err := do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	body, err := readJSONLimited(res.Body)
+	if err != nil {
+		return nil, err
+	}
+	switch res.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, nil
+	case http.StatusTooManyRequests:
+		return nil, fmt.Errorf("%s is rate limiting requests", req.URL.Redacted())
+	default:
+		return nil, fmt.Errorf("%s responded %s", req.URL.Redacted(), res.Status)
+	}
+	var answer struct {
+		Scheme  string `json:"scheme"`
+		Type    string `json:"type"`
+		Brand   string `json:"brand"`
+		Country struct {
+			Alpha2 string `json:"alpha2"`
+		} `json:"country"`
+	}
+	err = unmarshalJSONLimited(body, &answer)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", req.URL.Redacted(), err)
+	}
+	return map[string]interface{}{
+		"scheme":  answer.Scheme,
+		"type":    answer.Type,
+		"brand":   answer.Brand,
+		"country": answer.Country.Alpha2,
+	}, nil
+}
+
+// copyBINResult copies a cached result so templates modifying it don't modify the cache
+func copyBINResult(result map[string]interface{}) map[string]interface{} {
+	if result == nil {
+		return nil
+	}
+	var m = make(map[string]interface{}, len(result))
+	for k, v := range result {
+		m[k] = v
+	}
+	return m
+}
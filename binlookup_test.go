@@ -0,0 +1,205 @@
+package template
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBINLookup(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("Accept-Version") != "3" {
+			t.Errorf(`Unexpected Accept-Version %q`, r.Header.Get("Accept-Version"))
+		}
+		switch r.URL.Path {
+		case "/372723":
+			fmt.Fprint(w, `{"number":{},"scheme":"amex","type":"credit","brand":"Green","country":{"alpha2":"US","name":"United States of America (the)"},"bank":{}}`)
+		case "/45717360":
+			fmt.Fprint(w, `{"scheme":"visa","type":"debit","brand":"Visa/Dankort","country":{"alpha2":"DK"}}`)
+		case "/999999":
+			http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+		case "/888888":
+			http.Error(w, "oops", http.StatusInternalServerError)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	SetBINLookupURL(srv.URL + "/{bin}")
+	t.Cleanup(func() {
+		SetBINLookupURL("")
+		binLookupCache.Flush()
+	})
+	binLookupCache.Flush()
+
+	result, err := InterpolateStrict(map[string]interface{}{"bin": "372723"}, `{{ with binLookup .bin }}{{ .scheme }} {{ .type }} {{ .brand }} {{ .country }}{{ end }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if result != "amex credit Green US" {
+		t.Errorf(`Unexpected result %q`, result)
+	}
+	info, err := binLookup(doHTTP, true, int64(45717360))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if info["scheme"] != "visa" || info["country"] != "DK" {
+		t.Errorf(`Unexpected result %v`, info)
+	}
+
+	// Cached answers are not requested again and can't be modified through the result
+	info["scheme"] = "changed"
+	info, err = binLookup(doHTTP, true, "45717360")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if info["scheme"] != "visa" {
+		t.Errorf(`Unexpected result %v`, info)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf(`Expected 2 requests, got %d`, n)
+	}
+
+	// Unknown BINs are nil and cached
+	for i := 0; i < 2; i++ {
+		info, err = binLookup(doHTTP, true, "123456")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if info != nil {
+			t.Errorf(`Unexpected result %v`, info)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Errorf(`Expected 3 requests, got %d`, n)
+	}
+	result, err = InterpolateStrict(nil, `{{ with binLookup "123456" }}{{ .scheme }}{{ else }}unknown{{ end }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if result != "unknown" {
+		t.Errorf(`Unexpected result %q`, result)
+	}
+
+	// Failures are errors and are not cached
+	for bin, msg := range map[string]string{"999999": "rate limiting", "888888": "500"} {
+		for i := 0; i < 2; i++ {
+			_, err = binLookup(doHTTP, true, bin)
+			if err == nil || !strings.Contains(err.Error(), msg) {
+				t.Errorf(`Unexpected error for %s: %v`, bin, err)
+			}
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 7 {
+		t.Errorf(`Expected 7 requests, got %d`, n)
+	}
+	if stats := CacheStats()["binLookup"]; stats.Entries != 3 || stats.Hits != 3 {
+		t.Errorf(`Unexpected cache stats %+v`, stats)
+	}
+
+	for _, bin := range []interface{}{"12345", "123456789", "3727a3", "", 372723.5} {
+		_, err = binLookup(doHTTP, true, bin)
+		if err == nil {
+			t.Errorf(`Expected error for %v`, bin)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 7 {
+		t.Errorf(`Expected 7 requests, got %d`, n)
+	}
+}
+
+func TestBINLookupRateLimit(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `{"scheme":"visa"}`)
+	}))
+	defer srv.Close()
+	SetBINLookupURL(srv.URL + "/{bin}")
+	err := SetHTTPRateLimits(map[string]float64{"127.0.0.1": 10})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	t.Cleanup(func() {
+		SetBINLookupURL("")
+		SetHTTPRateLimits(nil)
+		binLookupCache.Flush()
+	})
+	binLookupCache.Flush()
+
+	var start = time.Now()
+	result, err := InterpolateStrict(nil, `{{ (binLookup "411111").scheme }} {{ (binLookup "422222").scheme }} {{ (binLookup "411111").scheme }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if result != "visa visa visa" {
+		t.Errorf(`Unexpected result %q`, result)
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond || elapsed > time.Second {
+		t.Errorf(`Expected the second request to wait for the rate limit, took %s`, elapsed)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf(`Expected 2 requests, got %d`, n)
+	}
+}
+
+func TestBINLookupDryRun(t *testing.T) {
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `{"scheme":"visa"}`)
+	}))
+	defer srv.Close()
+	SetBINLookupURL(srv.URL + "/{bin}")
+	t.Cleanup(func() {
+		SetBINLookupURL("")
+		binLookupCache.Flush()
+	})
+	binLookupCache.Flush()
+
+	var dryRun = &DryRun{Responses: map[string]DryRunResponse{
+		srv.URL + "/*": {Body: `{"scheme":"amex"}`},
+	}}
+	for i := 0; i < 2; i++ {
+		result, err := InterpolateDryRun(dryRun, nil, `{{ (binLookup "372723").scheme }}`)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if result != "amex" {
+			t.Errorf(`Unexpected result %q`, result)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf(`Expected no requests to be sent, got %d`, n)
+	}
+	// Dry runs neither read nor write the cache, so every lookup is recorded
+	if calls := dryRun.Calls(); len(calls) != 2 || calls[0].URL != srv.URL+"/372723" {
+		t.Errorf(`Unexpected calls %+v`, calls)
+	}
+
+	result, err := InterpolateStrict(nil, `{{ (binLookup "372723").scheme }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if result != "visa" {
+		t.Errorf(`Unexpected result %q`, result)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf(`Expected 1 request, got %d`, n)
+	}
+}
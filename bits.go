@@ -0,0 +1,79 @@
+package template
+
+import (
+	"fmt"
+)
+
+// bitOperands converts the operands of a bitwise function to int64, failing on fractional values
+func bitOperands(name string, values []interface{}) ([]int64, error) {
+	var ints = make([]int64, len(values))
+	for i, v := range values {
+		n, err := interfaceToWholeInt64(v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		ints[i] = n
+	}
+	return ints, nil
+}
+
+// bitReduce folds op over a, b and more
+func bitReduce(name string, op func(x, y int64) int64, a, b interface{}, more []interface{}) (int64, error) {
+	ints, err := bitOperands(name, append([]interface{}{a, b}, more...))
+	if err != nil {
+		return 0, err
+	}
+	var res = ints[0]
+	for _, n := range ints[1:] {
+		res = op(res, n)
+	}
+	return res, nil
+}
+
+// bitAnd returns the bitwise AND of its arguments
+func bitAnd(a, b interface{}, more ...interface{}) (int64, error) {
+	return bitReduce("bitAnd", func(x, y int64) int64 { return x & y }, a, b, more)
+}
+
+// bitOr returns the bitwise OR of its arguments
+func bitOr(a, b interface{}, more ...interface{}) (int64, error) {
+	return bitReduce("bitOr", func(x, y int64) int64 { return x | y }, a, b, more)
+}
+
+// bitXor returns the bitwise XOR of its arguments
+func bitXor(a, b interface{}, more ...interface{}) (int64, error) {
+	return bitReduce("bitXor", func(x, y int64) int64 { return x ^ y }, a, b, more)
+}
+
+// bitShift converts the operands of a shift, the shift count must be from 0 to 63
+func bitShift(name string, n, count interface{}) (int64, uint, error) {
+	ints, err := bitOperands(name, []interface{}{n, count})
+	if err != nil {
+		return 0, 0, err
+	}
+	if ints[1] < 0 || ints[1] > 63 {
+		return 0, 0, fmt.Errorf("%s: shift count %d is out of range 0 to 63", name, ints[1])
+	}
+	return ints[0], uint(ints[1]), nil
+}
+
+// bitShiftLeft shifts n left by count bits
+func bitShiftLeft(n, count interface{}) (int64, error) {
+	x, s, err := bitShift("bitShiftLeft", n, count)
+	return x << s, err
+}
+
+// bitShiftRight shifts n right by count bits, preserving its sign
+func bitShiftRight(n, count interface{}) (int64, error) {
+	x, s, err := bitShift("bitShiftRight", n, count)
+	return x >> s, err
+}
+
+// hasBit reports whether every bit set in flag is also set in mask
+func hasBit(mask, flag interface{}) (bool, error) {
+	ints, err := bitOperands("hasBit", []interface{}{mask, flag})
+	if err != nil {
+		return false, err
+	}
+	return ints[0]&ints[1] == ints[1], nil
+}
@@ -0,0 +1,47 @@
+package template
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBitFuncs(t *testing.T) {
+	var data = map[string]interface{}{"flags": json.Number("13"), "bit": "2"}
+	var cases = map[string]string{
+		`{{ bitAnd .flags 6 }}`:                            "4",
+		`{{ bitOr .flags 2 16 }}`:                          "31",
+		`{{ bitXor .flags 1 }}`:                            "12",
+		`{{ bitShiftLeft 1 .bit }}`:                        "4",
+		`{{ bitShiftRight .flags 2 }}`:                     "3",
+		`{{ bitShiftRight -8 1 }}`:                         "-4",
+		`{{ bitAnd 7.0 3 }}`:                               "3",
+		`{{ if hasBit .flags 4 }}priority{{ end }}`:        "priority",
+		`{{ if hasBit .flags 5 }}both{{ end }}`:            "both",
+		`{{ if hasBit .flags 2 }}x{{ else }}none{{ end }}`: "none",
+	}
+	for src, expected := range cases {
+		res, err := InterpolateStrict(data, src)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if res != expected {
+			t.Errorf(`Unexpected result %q for %s`, res, src)
+		}
+	}
+
+	var errCases = map[string]string{
+		`{{ bitAnd 1.5 1 }}`:              "bitAnd: 1.5 is not an integer",
+		`{{ bitOr (parseJSON "2.5") 1 }}`: "bitOr: 2.5 is not an integer",
+		`{{ bitShiftLeft 1 64 }}`:         "out of range",
+		`{{ bitShiftRight 1 -1 }}`:        "out of range",
+		`{{ hasBit "x" 1 }}`:              "hasBit",
+	}
+	for src, expected := range errCases {
+		_, err := InterpolateStrict(nil, src)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf(`Expected error %q for %s, got %v`, expected, src, err)
+		}
+	}
+}
@@ -0,0 +1,455 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/the-control-group/go-timeutils"
+	"github.com/the-control-group/go-ttlcache"
+	"golang.org/x/sync/singleflight"
+)
+
+// ErrCacheMiss is returned by a Cache when a key is not present
+var ErrCacheMiss = errors.New("cache miss")
+
+// Cache is a backend for the cacheSet/cacheGet family of template functions
+// Implementations shared between processes (e.g. Redis) will typically round-trip values through JSON,
+// in which case cached values come back as json.Number, string, bool, []interface{} and map[string]interface{}
+// rather than the types that were set. Templates should not rely on the concrete type of cached values.
+type Cache interface {
+	// Get returns the value at key, or ErrCacheMiss if it is not present
+	Get(key string) (interface{}, error)
+	// SetEx sets the value at key, expiring it after ttl
+	SetEx(key string, value interface{}, ttl time.Duration) error
+	// Delete removes key, returning ErrCacheMiss if it is not present
+	Delete(key string) error
+}
+
+// Incrementer may be implemented by a Cache to make cacheIncr/cacheDecr atomic across processes
+// Caches that don't implement it are incremented with Get and SetEx under a process-wide lock, which keeps the
+// expiry a key was created with for the default cache but restarts the ttl on each increment for other caches
+type Incrementer interface {
+	// Incr adds delta to the integer at key, creating it with ttl if it is not present, and returns the new value
+	Incr(key string, delta int64, ttl time.Duration) (int64, error)
+}
+
+// ttlReporter is implemented by caches that know the remaining ttl of their keys, such as the default cache
+// cacheIncr uses it to keep the expiry a counter was created with
+type ttlReporter interface {
+	remainingTTL(key string) (time.Duration, bool)
+}
+
+// CacheStat reports the usage of a cache
+type CacheStat struct {
+	// Entries currently stored
+	Entries int `json:"entries"`
+	// Lookups that found a value
+	Hits uint64 `json:"hits"`
+	// Lookups that found nothing
+	Misses uint64 `json:"misses"`
+	// Entries removed because they expired
+	Evictions uint64 `json:"evictions"`
+}
+
+// ttlCache adapts a ttlcache.TTLCache to the Cache interface, tracking usage statistics
+type ttlCache struct {
+	mu         sync.Mutex
+	cache      *ttlcache.TTLCache
+	defaultTTL time.Duration
+	expires    map[string]time.Time
+	// sets since expired keys were last pruned from expires
+	sets int
+	stat CacheStat
+}
+
+func newTTLCache(defaultTTL time.Duration) *ttlCache {
+	return &ttlCache{
+		cache:      ttlcache.NewTTLCache(defaultTTL),
+		defaultTTL: defaultTTL,
+		expires:    map[string]time.Time{},
+	}
+}
+
+// Get implementation for Cache
+func (c *ttlCache) Get(key string) (interface{}, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, err := c.cache.Get(key)
+	if err == ttlcache.ERR_KEY_NO_EXISTS {
+		if _, ok := c.expires[key]; ok {
+			delete(c.expires, key)
+			c.stat.Evictions++
+		}
+		c.stat.Misses++
+		return nil, ErrCacheMiss
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.stat.Hits++
+	return v, nil
+}
+
+// SetEx implementation for Cache
+func (c *ttlCache) SetEx(key string, value interface{}, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.expires[key] = time.Now().Add(ttl)
+	err := c.cache.SetEx(key, value, ttl)
+	// Keys that are never read again are only removed from expires by pruning, which is amortised over the sets
+	c.sets++
+	if c.sets >= 64 && c.sets >= len(c.expires)/2 {
+		c.prune()
+	}
+	return err
+}
+
+// prune removes expired keys from expires, counting them as evictions, c.mu must be held
+func (c *ttlCache) prune() {
+	var now = time.Now()
+	for key, expireAt := range c.expires {
+		if !expireAt.After(now) || !c.cache.Exists(key) {
+			delete(c.expires, key)
+			c.stat.Evictions++
+		}
+	}
+	c.sets = 0
+}
+
+// remainingTTL returns how long until key expires, ok is false when it is not present
+func (c *ttlCache) remainingTTL(key string) (ttl time.Duration, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expireAt, ok := c.expires[key]
+	if !ok {
+		return 0, false
+	}
+	ttl = time.Until(expireAt)
+	return ttl, ttl > 0
+}
+
+// Delete implementation for Cache
+func (c *ttlCache) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.expires, key)
+	err := c.cache.Expire(key)
+	if err == ttlcache.ERR_KEY_NO_EXISTS {
+		return ErrCacheMiss
+	}
+	return err
+}
+
+// Stats returns the usage statistics of the cache
+func (c *ttlCache) Stats() CacheStat {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prune()
+	var stat = c.stat
+	stat.Entries = len(c.expires)
+	return stat
+}
+
+// Flush removes all entries from the cache
+func (c *ttlCache) Flush() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// Pending expirations fire against the old cache, so the new one is unaffected
+	c.cache = ttlcache.NewTTLCache(c.defaultTTL)
+	c.expires = map[string]time.Time{}
+	c.sets = 0
+	return nil
+}
+
+// CacheStats returns usage statistics for the internal caches, keyed by "template", "authx" and "binLookup"
+// A custom cache backend is only reported if it implements Stats() CacheStat
+func CacheStats() map[string]CacheStat {
+	var stats = map[string]CacheStat{
+		"authx":     authxTokenCache.Stats(),
+		"binLookup": binLookupCache.Stats(),
+	}
+	if c, ok := currentTemplateCache().(interface{ Stats() CacheStat }); ok {
+		stats["template"] = c.Stats()
+	} else {
+		stats["template"] = CacheStat{}
+	}
+	return stats
+}
+
+// FlushCaches removes all entries from the internal caches
+// A custom cache backend is only flushed if it implements Flush() error
+func FlushCaches() error {
+	authxTokenCache.Flush()
+	binLookupCache.Flush()
+	if c, ok := currentTemplateCache().(interface{ Flush() error }); ok {
+		return c.Flush()
+	}
+	return nil
+}
+
+var templateCacheMu sync.RWMutex
+var templateCache Cache
+var templateCacheGroup singleflight.Group
+var templateCacheIncrMu sync.Mutex
+
+// SetCacheBackend replaces the cache used by the cacheSet/cacheGet family of template functions
+// Passing nil restores the default in-process cache
+func SetCacheBackend(c Cache) {
+	if c == nil {
+		c = defaultTemplateCache
+	}
+	templateCacheMu.Lock()
+	templateCache = c
+	templateCacheMu.Unlock()
+}
+
+func currentTemplateCache() Cache {
+	templateCacheMu.RLock()
+	defer templateCacheMu.RUnlock()
+	return templateCache
+}
+
+// CacheGetOrSet returns the value cached at key, calling fetch to compute and cache it for ttl when absent
+// Concurrent callers missing the same key share a single call to fetch. Errors returned by fetch are not cached.
+func CacheGetOrSet(key string, ttl time.Duration, fetch func() (interface{}, error)) (interface{}, error) {
+	var cache = currentTemplateCache()
+	if v, err := cache.Get(key); err == nil {
+		return v, nil
+	}
+	v, err, _ := templateCacheGroup.Do(key, func() (interface{}, error) {
+		// Another caller may have populated the key while we waited to enter the group
+		if v, err := cache.Get(key); err == nil {
+			return v, nil
+		}
+		v, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+		return v, cache.SetEx(key, v, ttl)
+	})
+	return v, err
+}
+
+// cacheNamespaceSep separates the namespace of a key set by a namespaced template from the key
+// Keys outside a namespace can't contain it, so they can't refer to a key within one
+const cacheNamespaceSep = "\x00"
+
+// checkCacheKey errors if a key used outside a namespace contains cacheNamespaceSep
+func checkCacheKey(key string) error {
+	if strings.Contains(key, cacheNamespaceSep) {
+		return fmt.Errorf("cache key %q must not contain a NUL character", key)
+	}
+	return nil
+}
+
+// cacheSet caches value at key for the expire duration and returns value
+func cacheSet(key string, value interface{}, expire interface{}) (interface{}, error) {
+	if err := checkCacheKey(key); err != nil {
+		return value, err
+	}
+	return storeCache(key, value, expire)
+}
+
+// storeCache is cacheSet without checking key
+func storeCache(key string, value interface{}, expire interface{}) (interface{}, error) {
+	exp, err := timeutils.InterfaceToApproxBigDuration(expire)
+	if err != nil {
+		return value, err
+	}
+	return value, currentTemplateCache().SetEx(key, value, time.Duration(exp))
+}
+
+// cacheGet returns the value cached at key, or nil when absent
+func cacheGet(key string) interface{} {
+	if checkCacheKey(key) != nil {
+		return nil
+	}
+	v, _ := currentTemplateCache().Get(key)
+	return v
+}
+
+// cacheHas reports whether key is present, even if its value is falsy
+func cacheHas(key string) bool {
+	if checkCacheKey(key) != nil {
+		return false
+	}
+	_, err := currentTemplateCache().Get(key)
+	return err == nil
+}
+
+// cacheDelete removes key and reports whether it was present
+func cacheDelete(key string) bool {
+	if checkCacheKey(key) != nil {
+		return false
+	}
+	return currentTemplateCache().Delete(key) == nil
+}
+
+// cacheGetOrSet returns the value cached at key, otherwise it executes src as a template with data and caches the output
+// src is only executed on a cache miss and at most once across concurrent executions
+func cacheGetOrSet(key string, expire interface{}, src string, data interface{}) (interface{}, error) {
+	return cacheGetOrSetChain(nil, newRenderOverlay(nil), "", key, expire, src, data)
+}
+
+// cacheGetOrSetChain is cacheGetOrSet with src executed as part of the render with the overlay funcs, in the chain of
+// files and partials rendering it, and with its cache funcs in namespace when not empty
+// Namespaced keys are passed with their namespace. A dry run renders src on a miss without caching it.
+func cacheGetOrSetChain(chain []string, overlay map[string]interface{}, namespace, key string, expire interface{}, src string, data interface{}) (interface{}, error) {
+	if namespace == "" {
+		if err := checkCacheKey(key); err != nil {
+			return nil, err
+		}
+	}
+	exp, err := timeutils.InterfaceToApproxBigDuration(expire)
+	if err != nil {
+		return nil, err
+	}
+	var srcOverlay = overlay
+	if namespace != "" {
+		srcOverlay = make(map[string]interface{}, len(overlay))
+		for name, fn := range overlay {
+			srcOverlay[name] = fn
+		}
+		for name, fn := range cacheNamespaceFuncs(namespace, chain, overlay) {
+			srcOverlay[name] = fn
+		}
+	}
+	var render = func() (interface{}, error) {
+		return interpolateChain(chain, srcOverlay, "", data, src)
+	}
+	if s := renderStateOf(overlay); s != nil && s.dryRun {
+		if v, err := currentTemplateCache().Get(key); err == nil {
+			return v, nil
+		}
+		return render()
+	}
+	return CacheGetOrSet(key, time.Duration(exp), render)
+}
+
+// cacheIncr adds delta to the integer cached at key, creating it with a ttl when absent, and returns the new value
+// Increments don't extend the ttl, so a counter covers a fixed window from its first use
+func cacheIncr(key string, delta interface{}, expire interface{}) (int64, error) {
+	if err := checkCacheKey(key); err != nil {
+		return 0, err
+	}
+	return incrCache(key, delta, expire)
+}
+
+// incrCache is cacheIncr without checking key
+func incrCache(key string, delta interface{}, expire interface{}) (int64, error) {
+	d, err := interfaceToInt64(delta)
+	if err != nil {
+		return 0, err
+	}
+	exp, err := timeutils.InterfaceToApproxBigDuration(expire)
+	if err != nil {
+		return 0, err
+	}
+	var cache = currentTemplateCache()
+	if incrementer, ok := cache.(Incrementer); ok {
+		return incrementer.Incr(key, d, time.Duration(exp))
+	}
+	templateCacheIncrMu.Lock()
+	defer templateCacheIncrMu.Unlock()
+	var n int64
+	var ttl = time.Duration(exp)
+	v, err := cache.Get(key)
+	if err == nil {
+		n, err = interfaceToInt64(v)
+		if err != nil {
+			return 0, fmt.Errorf("cacheIncr %q: %w", key, err)
+		}
+		// The ttl is set when the key is created, so later increments keep its expiry rather than extending it
+		if r, ok := cache.(ttlReporter); ok {
+			if remaining, ok := r.remainingTTL(key); ok {
+				ttl = remaining
+			}
+		}
+	} else if err != ErrCacheMiss {
+		return 0, err
+	}
+	n += d
+	return n, cache.SetEx(key, n, ttl)
+}
+
+// cacheDecr subtracts delta from the integer cached at key, creating it with a ttl when absent, and returns the new value
+func cacheDecr(key string, delta interface{}, expire interface{}) (int64, error) {
+	d, err := interfaceToInt64(delta)
+	if err != nil {
+		return 0, err
+	}
+	return cacheIncr(key, -d, expire)
+}
+
+// cacheNamespaceFuncs returns the cache template functions with keys prefixed by namespace
+// Writes go through those of the overlay when it replaces them, as a dry run does, and cacheGetOrSet renders as part
+// of the render with the overlay funcs in the chain of files and partials rendering it.
+func cacheNamespaceFuncs(namespace string, chain []string, overlay map[string]interface{}) map[string]interface{} {
+	var nsKey = func(key string) string {
+		return namespace + cacheNamespaceSep + key
+	}
+	var set = storeCache
+	if fn, ok := overlay["cacheSet"].(func(string, interface{}, interface{}) (interface{}, error)); ok {
+		set = fn
+	}
+	var incr = incrCache
+	if fn, ok := overlay["cacheIncr"].(func(string, interface{}, interface{}) (int64, error)); ok {
+		incr = fn
+	}
+	var decr = func(key string, delta interface{}, expire interface{}) (int64, error) {
+		d, err := interfaceToInt64(delta)
+		if err != nil {
+			return 0, err
+		}
+		return incrCache(key, -d, expire)
+	}
+	if fn, ok := overlay["cacheDecr"].(func(string, interface{}, interface{}) (int64, error)); ok {
+		decr = fn
+	}
+	var del = func(key string) bool {
+		return currentTemplateCache().Delete(key) == nil
+	}
+	if fn, ok := overlay["cacheDelete"].(func(string) bool); ok {
+		del = fn
+	}
+	return map[string]interface{}{
+		"cacheSet": func(key string, value interface{}, expire interface{}) (interface{}, error) {
+			return set(nsKey(key), value, expire)
+		},
+		"cacheGet": func(key string) interface{} {
+			v, _ := currentTemplateCache().Get(nsKey(key))
+			return v
+		},
+		"cacheHas": func(key string) bool {
+			_, err := currentTemplateCache().Get(nsKey(key))
+			return err == nil
+		},
+		"cacheDelete": func(key string) bool {
+			return del(nsKey(key))
+		},
+		"cacheIncr": func(key string, delta interface{}, expire interface{}) (int64, error) {
+			return incr(nsKey(key), delta, expire)
+		},
+		"cacheDecr": func(key string, delta interface{}, expire interface{}) (int64, error) {
+			return decr(nsKey(key), delta, expire)
+		},
+		"cacheGetOrSet": func(key string, expire interface{}, src string, data interface{}) (interface{}, error) {
+			var render = overlay
+			if render == nil {
+				render = newRenderOverlay(nil)
+			}
+			return cacheGetOrSetChain(chain, render, namespace, nsKey(key), expire, src, data)
+		},
+	}
+}
+
+// SetCacheNamespace isolates the cache template functions used by t, transparently prefixing keys with namespace
+// Templates in different namespaces (e.g. one per tenant) can use the same keys without colliding
+func (t *Template) SetCacheNamespace(namespace string) *Template {
+	t.cacheNamespace = namespace
+	t.Funcs(cacheNamespaceFuncs(namespace, nil, nil))
+	return t
+}
@@ -0,0 +1,405 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheGetOrSetConcurrent(t *testing.T) {
+	var calls int32
+	var wg sync.WaitGroup
+	var results = make([]interface{}, 20)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			v, err := CacheGetOrSet("getOrSetConcurrent", time.Minute, func() (interface{}, error) {
+				atomic.AddInt32(&calls, 1)
+				time.Sleep(50 * time.Millisecond)
+				return "computed", nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+			results[i] = v
+		}(i)
+	}
+	wg.Wait()
+	if calls != 1 {
+		t.Errorf(`Expected value to be computed once, computed %d times`, calls)
+	}
+	for _, v := range results {
+		if v != "computed" {
+			t.Errorf(`Unexpected result %v`, v)
+		}
+	}
+}
+
+func TestCacheGetOrSetErrorNotCached(t *testing.T) {
+	_, err := CacheGetOrSet("getOrSetError", time.Minute, func() (interface{}, error) {
+		return nil, errors.New("failed")
+	})
+	if err == nil {
+		t.Error("Expected error")
+		return
+	}
+	v, err := CacheGetOrSet("getOrSetError", time.Minute, func() (interface{}, error) {
+		return "recovered", nil
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if v != "recovered" {
+		t.Errorf(`Unexpected result %v`, v)
+	}
+}
+
+func TestCacheGetOrSetTemplate(t *testing.T) {
+	var err error
+	var jsondata = []byte(`"{{ cacheGetOrSet \"getOrSetTemplate\" \"1m\" \"{{ .value }}\" . }} {{ cacheGetOrSet \"getOrSetTemplate\" \"1m\" \"{{ .other }}\" . }}"`)
+	var tmpl *Template
+	err = json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{
+		"value": "first",
+		"other": "second",
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != "first first" {
+		t.Errorf(`Unexpected result %q`, buf.String())
+	}
+}
+
+// jsonCache is an in-memory Cache that round-trips values through JSON like a shared backend would
+type jsonCache struct {
+	sync.Mutex
+	values map[string][]byte
+}
+
+func (c *jsonCache) Get(key string) (interface{}, error) {
+	c.Lock()
+	defer c.Unlock()
+	b, ok := c.values[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	return v, dec.Decode(&v)
+}
+
+func (c *jsonCache) SetEx(key string, value interface{}, ttl time.Duration) error {
+	b, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	c.Lock()
+	defer c.Unlock()
+	c.values[key] = b
+	return nil
+}
+
+func (c *jsonCache) Delete(key string) error {
+	c.Lock()
+	defer c.Unlock()
+	if _, ok := c.values[key]; !ok {
+		return ErrCacheMiss
+	}
+	delete(c.values, key)
+	return nil
+}
+
+func TestSetCacheBackend(t *testing.T) {
+	var err error
+	var backend = &jsonCache{values: map[string][]byte{}}
+	SetCacheBackend(backend)
+	defer SetCacheBackend(nil)
+
+	var jsondata = []byte(`"{{ $_ := cacheSet \"backend\" 5 \"1m\" }}{{ $v := cacheGet \"backend\" }}{{ printf \"%T\" $v }} {{ cacheHas \"backend\" }} {{ cacheDelete \"backend\" }} {{ cacheHas \"backend\" }}"`)
+	var tmpl *Template
+	err = json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != "json.Number true true false" {
+		t.Errorf(`Unexpected result %q`, buf.String())
+	}
+
+	_, err = CacheGetOrSet("backend", time.Minute, func() (interface{}, error) {
+		return "fetched", nil
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(backend.values["backend"]) != `"fetched"` {
+		t.Errorf(`Expected value to be stored in backend, got %q`, backend.values["backend"])
+	}
+}
+
+func TestCacheIncrConcurrent(t *testing.T) {
+	var err error
+	var jsondata = []byte(`"{{ cacheIncr \"incrConcurrent\" 1 \"1m\" }}"`)
+	var tmpl *Template
+	err = json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, map[string]interface{}{}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	v, err := currentTemplateCache().Get("incrConcurrent")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if v != int64(100) {
+		t.Errorf(`Unexpected result %v`, v)
+	}
+}
+
+func TestCacheIncrFixedWindow(t *testing.T) {
+	SetCacheBackend(newTTLCache(time.Minute))
+	defer SetCacheBackend(nil)
+	var window = int64(150 * time.Millisecond)
+	if n, err := cacheIncr("window", 1, window); err != nil || n != 1 {
+		t.Errorf(`Unexpected result %d: %v`, n, err)
+		return
+	}
+	time.Sleep(100 * time.Millisecond)
+	if n, err := cacheIncr("window", 1, window); err != nil || n != 2 {
+		t.Errorf(`Unexpected result %d: %v`, n, err)
+		return
+	}
+	// The window started with the first increment, so it has ended despite the second
+	time.Sleep(100 * time.Millisecond)
+	if cacheHas("window") {
+		t.Error(`Expected the counter to expire a window after it was created`)
+	}
+	if n, err := cacheIncr("window", 1, window); err != nil || n != 1 {
+		t.Errorf(`Unexpected result %d: %v`, n, err)
+	}
+}
+
+func TestCacheDecr(t *testing.T) {
+	var err error
+	SetCacheBackend(&jsonCache{values: map[string][]byte{}})
+	defer SetCacheBackend(nil)
+
+	var jsondata = []byte(`"{{ cacheIncr \"decr\" 5 \"1m\" }} {{ cacheDecr \"decr\" .n \"1m\" }} {{ cacheDecr \"decr\" 1 \"1m\" }}"`)
+	var tmpl *Template
+	err = json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{
+		"n": json.Number("2"),
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != "5 3 2" {
+		t.Errorf(`Unexpected result %q`, buf.String())
+	}
+}
+
+func TestSetCacheNamespace(t *testing.T) {
+	var setTmpl = `{{ $_ := cacheSet "token" .token "1m" }}`
+	var getTmpl = `{{ cacheGet "token" }}`
+	for _, tenant := range []string{"a", "b"} {
+		tmpl, err := Parse(setTmpl)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var buf bytes.Buffer
+		err = tmpl.SetCacheNamespace(tenant).Execute(&buf, map[string]interface{}{
+			"token": "token-" + tenant,
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	var results []string
+	for _, tenant := range []string{"a", "b", ""} {
+		tmpl, err := Parse(getTmpl)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if tenant != "" {
+			tmpl.SetCacheNamespace(tenant)
+		}
+		str, err := tmpl.ExecuteToString(nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		results = append(results, str)
+	}
+	if results[0] != "token-a" || results[1] != "token-b" || results[2] != "<no value>" {
+		t.Errorf(`Unexpected results %q`, results)
+	}
+}
+
+func TestCacheStatsAndFlush(t *testing.T) {
+	var err error
+	err = FlushCaches()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var jsondata = []byte(`"{{ $_ := cacheSet \"stats\" 1 \"1m\" }}{{ $_ := cacheSet \"expires\" 1 1000000 }}{{ cacheGet \"stats\" }}{{ cacheGet \"missing\" }}"`)
+	var tmpl *Template
+	err = json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	before := CacheStats()["template"]
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	time.Sleep(20 * time.Millisecond)
+	stats := CacheStats()["template"]
+	if stats.Entries != 1 {
+		t.Errorf(`Expected 1 entry, got %d`, stats.Entries)
+	}
+	if stats.Hits-before.Hits != 1 || stats.Misses-before.Misses != 1 || stats.Evictions-before.Evictions != 1 {
+		t.Errorf(`Unexpected stats %+v`, stats)
+	}
+	if _, ok := CacheStats()["authx"]; !ok {
+		t.Error("Expected authx cache stats")
+	}
+
+	err = FlushCaches()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if CacheStats()["template"].Entries != 0 || cacheHas("stats") {
+		t.Error("Expected cache to be empty after flush")
+	}
+}
+
+func TestTTLCachePrunesUnreadKeys(t *testing.T) {
+	var c = newTTLCache(time.Minute)
+	for i := 0; i < 100; i++ {
+		c.SetEx(fmt.Sprintf("short-%d", i), i, 10*time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	for i := 0; i < 200; i++ {
+		c.SetEx(fmt.Sprintf("long-%d", i), i, time.Minute)
+	}
+	c.mu.Lock()
+	var entries = len(c.expires)
+	c.mu.Unlock()
+	if entries != 200 {
+		t.Errorf(`Expected expired keys to be pruned without a Get, got %d entries`, entries)
+	}
+	if stats := c.Stats(); stats.Entries != 200 || stats.Evictions != 100 {
+		t.Errorf(`Unexpected stats %+v`, stats)
+	}
+}
+
+func TestCacheGetOrSetRendersInCallersRender(t *testing.T) {
+	SetContextValueKeys(map[string]interface{}{"traceID": traceIDKey{}})
+	defer SetContextValueKeys(nil)
+	defer cacheDelete("get-or-set-ctx")
+
+	tmpl, err := Parse(`{{ cacheGetOrSet "get-or-set-ctx" "1m" "{{ ctxValue \"traceID\" }}" . }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	var ctx = context.WithValue(context.Background(), traceIDKey{}, "trace-1")
+	err = tmpl.ExecuteContext(ctx, &buf, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != "trace-1" {
+		t.Errorf(`Unexpected result %q`, buf.String())
+	}
+}
+
+func TestCacheNamespaceKeysNotSpoofable(t *testing.T) {
+	defer cacheDelete("spoof")
+	tmpl, err := Parse(`{{ $_ := cacheSet "spoof" "tenant" "1m" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = tmpl.SetCacheNamespace("ns").Execute(&bytes.Buffer{}, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer currentTemplateCache().Delete("ns" + cacheNamespaceSep + "spoof")
+
+	if cacheHas("ns"+cacheNamespaceSep+"spoof") || cacheGet("ns"+cacheNamespaceSep+"spoof") != nil {
+		t.Error("Expected a global key not to read a namespaced key")
+	}
+	if _, err := cacheSet("ns"+cacheNamespaceSep+"spoof", "global", "1m"); err == nil {
+		t.Error("Expected a global key containing the namespace separator to be rejected")
+	}
+	if cacheHas("ns::spoof") {
+		t.Error("Expected the old namespace prefix not to refer to a namespaced key")
+	}
+
+	tmpl, err = Parse(`{{ cacheGetOrSet "sub" "1m" "{{ cacheGet \"spoof\" }}" . }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer currentTemplateCache().Delete("ns" + cacheNamespaceSep + "sub")
+	res, err := tmpl.SetCacheNamespace("ns").ExecuteToString(nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "tenant" {
+		t.Errorf(`Expected the sub-template to use the namespace, got %q`, res)
+	}
+}
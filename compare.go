@@ -0,0 +1,80 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"text/template/parse"
+)
+
+// TemplateDiff lists what a new version of a template references that the old one didn't, and the reverse
+type TemplateDiff struct {
+	// Data paths, as reported by ListVariables
+	AddedVariables   []string `json:"addedVariables"`
+	RemovedVariables []string `json:"removedVariables"`
+	// Names of the functions called
+	AddedFunctions   []string `json:"addedFunctions"`
+	RemovedFunctions []string `json:"removedFunctions"`
+}
+
+// Changed reports whether the templates differ in the variables or functions they reference
+func (d *TemplateDiff) Changed() bool {
+	return len(d.AddedVariables)+len(d.RemovedVariables)+len(d.AddedFunctions)+len(d.RemovedFunctions) > 0
+}
+
+// CompareTemplates compares the data paths and functions referenced by two versions of a template source
+// New variables may be absent from data the old version was executed with, such as historical events.
+// When either source fails to parse the parse errors are returned, labeled old and new, instead of a diff.
+func CompareTemplates(oldSrc, newSrc string) (*TemplateDiff, error) {
+	oldVars, oldFuncs, oldErr := templateReferences(oldSrc)
+	newVars, newFuncs, newErr := templateReferences(newSrc)
+	if oldErr != nil || newErr != nil {
+		var errs []error
+		if oldErr != nil {
+			errs = append(errs, fmt.Errorf("old template: %w", oldErr))
+		}
+		if newErr != nil {
+			errs = append(errs, fmt.Errorf("new template: %w", newErr))
+		}
+		return nil, errors.Join(errs...)
+	}
+	return &TemplateDiff{
+		AddedVariables:   setDifference(newVars, oldVars),
+		RemovedVariables: setDifference(oldVars, newVars),
+		AddedFunctions:   setDifference(newFuncs, oldFuncs),
+		RemovedFunctions: setDifference(oldFuncs, newFuncs),
+	}, nil
+}
+
+// templateReferences parses src and returns the data paths and the names of the functions it references
+// Functions called within templates it defines are included
+func templateReferences(src string) (vars, funcs map[string]bool, err error) {
+	var tree = parse.New(RootTemplate.Name())
+	tree.Mode = parse.SkipFuncCheck
+	var trees = map[string]*parse.Tree{}
+	_, err = tree.Parse(src, leftDelim, rightDelim, trees)
+	if err != nil {
+		return nil, nil, err
+	}
+	funcs = map[string]bool{}
+	for _, t := range trees {
+		walkNodes(t.Root, func(node parse.Node) {
+			if ident, ok := node.(*parse.IdentifierNode); ok {
+				funcs[ident.Ident] = true
+			}
+		})
+	}
+	return collectTreeVariables(tree, trees), funcs, nil
+}
+
+// setDifference returns the sorted keys of a that are not in b
+func setDifference(a, b map[string]bool) []string {
+	var diff = make([]string, 0)
+	for k := range a {
+		if _, ok := b[k]; !ok {
+			diff = append(diff, k)
+		}
+	}
+	sort.Strings(diff)
+	return diff
+}
@@ -0,0 +1,54 @@
+package template
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCompareTemplates(t *testing.T) {
+	diff, err := CompareTemplates(
+		`{{ .event.id }} {{ upper .user.name }}{{ define "x" }}{{ lower . }}{{ end }}`,
+		`{{ .event.id }} {{ title .user.name }} {{ range .items }}{{ .sku }}{{ end }}`,
+	)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var expected = &TemplateDiff{
+		AddedVariables:   []string{".items", ".items[].sku"},
+		RemovedVariables: []string{},
+		AddedFunctions:   []string{"title"},
+		RemovedFunctions: []string{"lower", "upper"},
+	}
+	if !reflect.DeepEqual(diff, expected) {
+		t.Errorf(`Unexpected diff %+v`, diff)
+	}
+	if !diff.Changed() {
+		t.Errorf(`Expected the diff to be changed`)
+	}
+
+	diff, err = CompareTemplates(`{{ .a }}`, `{{ .a }}!`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if diff.Changed() {
+		t.Errorf(`Unexpected diff %+v`, diff)
+	}
+}
+
+func TestCompareTemplatesParseErrors(t *testing.T) {
+	diff, err := CompareTemplates(`{{ .a `, `{{ if }}`)
+	if diff != nil || err == nil {
+		t.Errorf(`Expected parse errors, got %+v`, diff)
+		return
+	}
+	if !strings.Contains(err.Error(), "old template: ") || !strings.Contains(err.Error(), "new template: ") {
+		t.Errorf(`Expected both parse errors, got %v`, err)
+	}
+	_, err = CompareTemplates(`{{ .a }}`, `{{ .a `)
+	if err == nil || strings.Contains(err.Error(), "old template") {
+		t.Errorf(`Expected only the new parse error, got %v`, err)
+	}
+}
@@ -0,0 +1,112 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+)
+
+var contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// contextFuncs are the registered functions taking the execution context as their first argument
+var contextFuncs = map[string]interface{}{
+	"ctxValue": ctxValue,
+}
+
+// contextValueKeys maps the names usable with ctxValue to context keys
+var contextValueKeys = map[string]interface{}{}
+
+// SetContextValueKeys sets the context values templates may read with ctxValue, as a map of name to context key
+// Context values not in the map can't be read by templates
+func SetContextValueKeys(keys map[string]interface{}) {
+	var copied = make(map[string]interface{}, len(keys))
+	for name, key := range keys {
+		copied[name] = key
+	}
+	contextValueKeys = copied
+}
+
+// ctxValue returns the value from the execution context for a name set with SetContextValueKeys
+func ctxValue(ctx context.Context, name string) (interface{}, error) {
+	key, ok := contextValueKeys[name]
+	if !ok {
+		return nil, fmt.Errorf("ctxValue: %q is not an allowed context key", name)
+	}
+	return ctx.Value(key), nil
+}
+
+// RegisterContextFunc adds a function taking the execution context as its first argument, callable from templates without it
+// The context is the one passed to ExecuteContext or InterpolateContext, or context.Background otherwise.
+// fn must otherwise follow the same rules as for RegisterFunc.
+func RegisterContextFunc(name string, fn interface{}, opts ...RegisterOption) error {
+	var v = reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.Type().NumIn() == 0 || v.Type().In(0) != contextType {
+		return fmt.Errorf("template func %q must take a context.Context as its first argument", name)
+	}
+	if err := RegisterFunc(name, bindContextFunc(context.Background(), fn), opts...); err != nil {
+		return err
+	}
+	contextFuncs[name] = fn
+	return nil
+}
+
+// bindContextFunc returns fn with ctx bound to its first argument
+func bindContextFunc(ctx context.Context, fn interface{}) interface{} {
+	var v = reflect.ValueOf(fn)
+	var t = v.Type()
+	var in = make([]reflect.Type, t.NumIn()-1)
+	for i := range in {
+		in[i] = t.In(i + 1)
+	}
+	var out = make([]reflect.Type, t.NumOut())
+	for i := range out {
+		out[i] = t.Out(i)
+	}
+	var ctxValue = reflect.ValueOf(&ctx).Elem()
+	return reflect.MakeFunc(reflect.FuncOf(in, out, t.IsVariadic()), func(args []reflect.Value) []reflect.Value {
+		args = append([]reflect.Value{ctxValue}, args...)
+		if t.IsVariadic() {
+			return v.CallSlice(args)
+		}
+		return v.Call(args)
+	}).Interface()
+}
+
+// contextFuncMap binds every context function to ctx
+func contextFuncMap(ctx context.Context) map[string]interface{} {
+	var names = make([]string, 0, len(contextFuncs))
+	for name := range contextFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var funcs = make(map[string]interface{}, len(contextFuncs))
+	for _, name := range names {
+		funcs[name] = bindContextFunc(ctx, contextFuncs[name])
+	}
+	return funcs
+}
+
+// ExecuteContext executes the template with ctx passed to context functions such as ctxValue, including in the partials it includes
+// Requests made by http, http_data, graphql, ipLookup, binLookup and the authx funcs are made with ctx, which the
+// HTTPLogger receives, and they and sleep return early with an error when ctx is done
+// The template is cloned so concurrent executions with different contexts don't interfere
+func (t *Template) ExecuteContext(ctx context.Context, w io.Writer, data interface{}) error {
+	return t.execute(w, data, rejectNoValue, newContextRenderOverlay(ctx, contextFuncMap(ctx)))
+}
+
+// InterpolateContext is InterpolateStrict with ctx passed to context functions such as ctxValue
+func InterpolateContext(ctx context.Context, data interface{}, text string) (string, error) {
+	tmpl, err := Parse(text)
+	if err != nil {
+		return "", newExecError("", text, err)
+	}
+	var buf bytes.Buffer
+	err = tmpl.ExecuteContext(ctx, &buf, data)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,112 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type traceIDKey struct{}
+
+func TestExecuteContext(t *testing.T) {
+	restoreTemplateFuncs(t)
+	SetContextValueKeys(map[string]interface{}{"traceID": traceIDKey{}})
+	defer SetContextValueKeys(nil)
+
+	err := RegisterContextFunc("traced", func(ctx context.Context, format string, args ...interface{}) string {
+		return fmt.Sprintf("[%v] ", ctx.Value(traceIDKey{})) + fmt.Sprintf(format, args...)
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tmpl, err := Parse(`{{ traced "order %s" .id }}|{{ ctxValue "traceID" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	var ctx = context.WithValue(context.Background(), traceIDKey{}, "trace-1")
+	err = tmpl.ExecuteContext(ctx, &buf, map[string]interface{}{"id": "42"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != "[trace-1] order 42|trace-1" {
+		t.Errorf(`Unexpected result %q`, buf.String())
+	}
+
+	res, err := tmpl.ExecuteToString(map[string]interface{}{"id": "42"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "[<nil>] order 42|<no value>" {
+		t.Errorf(`Unexpected result without context %q`, res)
+	}
+
+	res, err = InterpolateContext(context.WithValue(context.Background(), traceIDKey{}, "trace-2"), nil, `{{ ctxValue "traceID" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "trace-2" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestCtxValueNotAllowed(t *testing.T) {
+	var ctx = context.WithValue(context.Background(), traceIDKey{}, "trace-1")
+	_, err := InterpolateContext(ctx, nil, `{{ ctxValue "traceID" }}`)
+	if err == nil || !strings.Contains(err.Error(), "not an allowed context key") {
+		t.Errorf(`Expected disallowed key error, got %v`, err)
+	}
+}
+
+func TestRegisterContextFuncInvalid(t *testing.T) {
+	restoreTemplateFuncs(t)
+	err := RegisterContextFunc("noContext", strings.ToUpper)
+	if err == nil {
+		t.Errorf(`Expected func without context argument to be rejected`)
+	}
+}
+
+func TestExecuteContextHTTPLogger(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	var traceIDs []interface{}
+	SetHTTPLogger(func(ctx context.Context, method, url string, header http.Header, status int, duration time.Duration, err error) {
+		traceIDs = append(traceIDs, ctx.Value(traceIDKey{}))
+	}, nil)
+	defer SetHTTPLogger(nil, nil)
+
+	tmpl, err := Parse(`{{ (http "GET" .url (dict)).StatusCode }}{{ (graphql .url (dict) "{ ok }" nil) }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var ctx = context.WithValue(context.Background(), traceIDKey{}, "trace-1")
+	// The graphql response isn't JSON, only the request matters
+	tmpl.ExecuteContext(ctx, &bytes.Buffer{}, map[string]interface{}{"url": srv.URL})
+	if len(traceIDs) != 2 || traceIDs[0] != "trace-1" || traceIDs[1] != "trace-1" {
+		t.Errorf(`Unexpected trace IDs %v`, traceIDs)
+	}
+
+	_, err = InterpolateContext(ctx, map[string]interface{}{"url": srv.URL}, `{{ (http "GET" .url (dict)).StatusCode }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(traceIDs) != 3 || traceIDs[2] != "trace-1" {
+		t.Errorf(`Unexpected trace IDs %v`, traceIDs)
+	}
+}
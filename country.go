@@ -0,0 +1,127 @@
+package template
+
+import (
+	"strconv"
+	"strings"
+)
+
+//go:generate go run gen_countries.go
+
+// country is an ISO 3166-1 entry
+type country struct {
+	alpha2, alpha3, numeric, name string
+}
+
+// countryAliases are common names and codes for countries other than their ISO codes and short name
+var countryAliases = map[string]string{
+	"uk":                       "GB",
+	"great britain":            "GB",
+	"britain":                  "GB",
+	"england":                  "GB",
+	"scotland":                 "GB",
+	"wales":                    "GB",
+	"northern ireland":         "GB",
+	"united states of america": "US",
+	"america":                  "US",
+	"korea":                    "KR",
+	"republic of korea":        "KR",
+	"russian federation":       "RU",
+	"czech republic":           "CZ",
+	"holland":                  "NL",
+	"the netherlands":          "NL",
+	"ivory coast":              "CI",
+	"burma":                    "MM",
+	"swaziland":                "SZ",
+	"macedonia":                "MK",
+	"turkiye":                  "TR",
+	"viet nam":                 "VN",
+	"cabo verde":               "CV",
+	"holy see":                 "VA",
+	"east timor":               "TL",
+	"uae":                      "AE",
+}
+
+// countryIndex maps the normalized codes, names and aliases of every country to its entry
+var countryIndex = func() map[string]*country {
+	var index = make(map[string]*country, len(countries)*4+len(countryAliases))
+	var byAlpha2 = make(map[string]*country, len(countries))
+	for i := range countries {
+		var c = &countries[i]
+		byAlpha2[c.alpha2] = c
+		for _, key := range []string{c.alpha2, c.alpha3, c.numeric, c.name} {
+			index[normalizeCountryKey(key)] = c
+		}
+	}
+	for alias, alpha2 := range countryAliases {
+		index[alias] = byAlpha2[alpha2]
+	}
+	return index
+}()
+
+// normalizeCountryKey lowercases and transliterates s and removes punctuation, so "U.S.A." matches "usa"
+func normalizeCountryKey(s string) string {
+	s = strings.ToLower(transliterate(strings.TrimSpace(s)))
+	s = strings.Map(func(r rune) rune {
+		switch r {
+		case '.', ',', '\'', '’', '(', ')':
+			return -1
+		case '-', '_':
+			return ' '
+		}
+		return r
+	}, s)
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) > 0 && len(s) < 3 && strings.Trim(s, "0123456789") == "" {
+		s = strings.Repeat("0", 3-len(s)) + s
+	}
+	return s
+}
+
+// findCountry looks up a country by alpha-2, alpha-3 or numeric code, name or common alias
+func findCountry(input interface{}) *country {
+	var s string
+	switch v := input.(type) {
+	case string:
+		s = v
+	case nil:
+		return nil
+	default:
+		n, err := interfaceToWholeInt64(v)
+		if err != nil {
+			return nil
+		}
+		s = strconv.FormatInt(n, 10)
+	}
+	return countryIndex[normalizeCountryKey(s)]
+}
+
+// countryLookup returns the alpha2, alpha3, numeric and name of a country given any of them or a common alias
+// such as "UK", case insensitively. Unknown input returns nil so coalesce can supply a fallback.
+func countryLookup(input interface{}) interface{} {
+	var c = findCountry(input)
+	if c == nil {
+		return nil
+	}
+	return map[string]interface{}{
+		"alpha2":  c.alpha2,
+		"alpha3":  c.alpha3,
+		"numeric": c.numeric,
+		"name":    c.name,
+	}
+}
+
+// countryAlpha2 returns the ISO 3166-1 alpha-2 code of a country, or an empty string when unknown
+func countryAlpha2(input interface{}) string {
+	if c := findCountry(input); c != nil {
+		return c.alpha2
+	}
+	return ""
+}
+
+// countryName returns the English short name of a country, or an empty string when unknown
+func countryName(input interface{}) string {
+	if c := findCountry(input); c != nil {
+		return c.name
+	}
+	return ""
+}
@@ -0,0 +1,53 @@
+package template
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCountryLookup(t *testing.T) {
+	var us = map[string]interface{}{"alpha2": "US", "alpha3": "USA", "numeric": "840", "name": "United States"}
+	for _, input := range []interface{}{"US", "us", "USA", "840", 840, json.Number("840"), float64(840), "United States", "united states of america", "U.S.A.", " U.S. "} {
+		if res := countryLookup(input); !reflect.DeepEqual(res, us) {
+			t.Errorf(`Unexpected result %v for %#v`, res, input)
+		}
+	}
+	for input, alpha2 := range map[string]string{
+		"UK":                     "GB",
+		"Great Britain":          "GB",
+		"GBR":                    "GB",
+		"826":                    "GB",
+		"36":                     "AU",
+		"036":                    "AU",
+		"Cote d'Ivoire":          "CI",
+		"Côte d’Ivoire":          "CI",
+		"ivory coast":            "CI",
+		"Åland Islands":          "AX",
+		"bosnia and herzegovina": "BA",
+		"Guinea-Bissau":          "GW",
+		"nowhere":                "",
+		"":                       "",
+		"ZZ":                     "",
+		"999":                    "",
+	} {
+		if res := countryAlpha2(input); res != alpha2 {
+			t.Errorf(`Unexpected result %q for %q`, res, input)
+		}
+	}
+	if res := countryName("DE"); res != "Germany" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	if res := countryLookup(nil); res != nil {
+		t.Errorf(`Unexpected result %v`, res)
+	}
+
+	res, err := InterpolateStrict(map[string]interface{}{"country": "Atlantis"}, `{{ coalesce (countryLookup .country) "unknown" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "unknown" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
@@ -0,0 +1,218 @@
+package template
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed 5 field cron expression, each field a bit set of the values it matches
+type cronSchedule struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny record a * day of month or day of week, when only the other day field restricts the day
+	domAny, dowAny bool
+}
+
+// cronField describes the values a field of a cron expression accepts
+type cronField struct {
+	name     string
+	min, max int
+	names    []string
+}
+
+var cronFields = [5]cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"", "jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	// 7 is also accepted for Sunday and folded into 0
+	{name: "day of week", min: 0, max: 7, names: []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+var cronAliases = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// parseCron parses a standard 5 field cron expression (minute hour day-of-month month day-of-week) or an alias such as @daily
+// Fields accept *, values, ranges (1-5), steps (*/15, 1-30/2), comma separated lists, and month and weekday names
+func parseCron(expr string) (*cronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "@") {
+		alias, ok := cronAliases[strings.ToLower(expr)]
+		if !ok {
+			return nil, fmt.Errorf("cron: unknown alias %q", expr)
+		}
+		expr = alias
+	}
+	var fields = strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron: expected 5 fields, got %d in %q", len(fields), expr)
+	}
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := cronFields[i].parse(field)
+		if err != nil {
+			return nil, fmt.Errorf("cron: invalid %s field %q: %w", cronFields[i].name, field, err)
+		}
+		sets[i] = set
+	}
+	// Sunday may be written 7
+	if sets[4]&(1<<7) != 0 {
+		sets[4] = sets[4]&^(1<<7) | 1
+	}
+	return &cronSchedule{
+		minute: sets[0], hour: sets[1], dom: sets[2], month: sets[3], dow: sets[4],
+		domAny: fields[2] == "*", dowAny: fields[4] == "*",
+	}, nil
+}
+
+// parse returns the bit set of the values matched by a field
+func (f cronField) parse(field string) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		var rangePart, stepPart, hasStep = strings.Cut(part, "/")
+		var step = 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+		var lo, hi int
+		if rangePart == "*" {
+			lo, hi = f.min, f.max
+		} else {
+			var loPart, hiPart, isRange = strings.Cut(rangePart, "-")
+			var err error
+			lo, err = f.value(loPart)
+			if err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				hi, err = f.value(hiPart)
+				if err != nil {
+					return 0, err
+				}
+				if hi < lo {
+					return 0, fmt.Errorf("range %q ends before it starts", rangePart)
+				}
+			} else if hasStep {
+				// A step from a single value runs to the end of the field, as in 5/15
+				hi = f.max
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// value parses a single number or name of a field
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if name != "" && strings.EqualFold(s, name) {
+			return i, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if v < f.min || v > f.max {
+		return 0, fmt.Errorf("value %d is out of range %d to %d", v, f.min, f.max)
+	}
+	return v, nil
+}
+
+// dayMatches reports whether the day of t matches, either day field matches when both are restricted
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	var dom = s.dom&(1<<uint(t.Day())) != 0
+	var dow = s.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case s.domAny && s.dowAny:
+		return true
+	case s.domAny:
+		return dow
+	case s.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// matches reports whether the minute of t matches the schedule
+func (s *cronSchedule) matches(t time.Time) bool {
+	return s.month&(1<<uint(t.Month())) != 0 && s.dayMatches(t) &&
+		s.hour&(1<<uint(t.Hour())) != 0 && s.minute&(1<<uint(t.Minute())) != 0
+}
+
+// cronSearchYears bounds the search for the next match of expressions that rarely or never match, such as 0 0 30 2 *
+const cronSearchYears = 5
+
+// next returns the first minute after t that matches the schedule, in the location of t
+func (s *cronSchedule) next(t time.Time) (time.Time, error) {
+	var loc = t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	var limit = t.AddDate(cronSearchYears, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = cronAdvance(t, time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc))
+		case !s.dayMatches(t):
+			t = cronAdvance(t, time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc))
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			// Add elapsed minutes, as the next wall clock hour may be skipped by a daylight saving transition
+			t = t.Add(time.Duration(60-t.Minute()) * time.Minute)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("cron: no time within %d years matches", cronSearchYears)
+}
+
+// cronAdvance returns next, or the next hour when a daylight saving transition skipping midnight made time.Date
+// resolve next to a time that isn't after t
+func cronAdvance(t, next time.Time) time.Time {
+	if next.After(t) {
+		return next
+	}
+	return t.Add(time.Duration(60-t.Minute()) * time.Minute)
+}
+
+// cronNext returns the first time after t, to the minute, matching a 5 field cron expression, in the location of t
+func cronNext(expr string, t interface{}) (time.Time, error) {
+	s, err := parseCron(expr)
+	if err != nil {
+		return time.Time{}, err
+	}
+	tm, err := interfaceToTime(t)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return s.next(tm)
+}
+
+// cronMatches reports whether the minute of t matches a 5 field cron expression, in the location of t
+func cronMatches(expr string, t interface{}) (bool, error) {
+	s, err := parseCron(expr)
+	if err != nil {
+		return false, err
+	}
+	tm, err := interfaceToTime(t)
+	if err != nil {
+		return false, err
+	}
+	return s.matches(tm), nil
+}
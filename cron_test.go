@@ -0,0 +1,101 @@
+package template
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	var ny, err = time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var cases = []struct {
+		expr     string
+		t        interface{}
+		expected string
+	}{
+		{"*/15 * * * *", "2024-05-01T10:07:30Z", "2024-05-01T10:15:00Z"},
+		{"*/15 * * * *", "2024-05-01T10:45:00Z", "2024-05-01T11:00:00Z"},
+		{"0 9 * * mon-fri", "2024-05-03T09:00:00Z", "2024-05-06T09:00:00Z"},
+		{"30 8 1,15 * *", "2024-05-15T09:00:00Z", "2024-06-01T08:30:00Z"},
+		{"0 0 29 2 *", "2024-03-01T00:00:00Z", "2028-02-29T00:00:00Z"},
+		{"0 12 * * 7", "2024-05-01T00:00:00Z", "2024-05-05T12:00:00Z"},
+		{"0 0 13 * 5", "2024-05-01T00:00:00Z", "2024-05-03T00:00:00Z"},
+		{"5/20 1-3 * JAN *", "2024-05-01T00:00:00Z", "2025-01-01T01:05:00Z"},
+		{"@daily", "2024-12-31T23:59:00Z", "2025-01-01T00:00:00Z"},
+		{"@hourly", "2024-05-01T10:00:00Z", "2024-05-01T11:00:00Z"},
+		{"@weekly", "2024-05-01T10:00:00Z", "2024-05-05T00:00:00Z"},
+		// 2:30 doesn't exist in New York on 2024-03-10, so the first match is the next day
+		{"30 2 * * *", time.Date(2024, time.March, 10, 0, 0, 0, 0, ny), "2024-03-11T02:30:00-04:00"},
+		{"0 9 * * *", time.Date(2024, time.March, 9, 12, 0, 0, 0, ny), "2024-03-10T09:00:00-04:00"},
+	}
+	for _, c := range cases {
+		res, err := cronNext(c.expr, c.t)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if res.Format(time.RFC3339) != c.expected {
+			t.Errorf(`Unexpected next %s for %q after %v`, res.Format(time.RFC3339), c.expr, c.t)
+		}
+	}
+}
+
+func TestCronMatches(t *testing.T) {
+	var cases = []struct {
+		expr     string
+		t        string
+		expected bool
+	}{
+		{"0 9 * * mon-fri", "2024-05-03T09:00:59Z", true},
+		{"0 9 * * mon-fri", "2024-05-04T09:00:00Z", false},
+		{"0 9 * * mon-fri", "2024-05-03T09:01:00Z", false},
+		{"* * * * *", "2024-05-03T09:01:00Z", true},
+		{"@monthly", "2024-06-01T00:00:00Z", true},
+		{"0 0 13 * 5", "2024-05-03T00:00:00Z", true},
+		{"0 0 13 * 5", "2024-05-04T00:00:00Z", false},
+	}
+	for _, c := range cases {
+		res, err := cronMatches(c.expr, c.t)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if res != c.expected {
+			t.Errorf(`Unexpected match %v for %q at %s`, res, c.expr, c.t)
+		}
+	}
+
+	res, err := InterpolateStrict(map[string]interface{}{"at": "2024-05-03T08:00:00Z"}, `{{ if cronMatches "0 9 * * *" .at }}now{{ else }}{{ (cronNext "0 9 * * *" .at).Format "15:04" }}{{ end }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "09:00" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestCronInvalid(t *testing.T) {
+	var cases = map[string]string{
+		"* * * *":      "expected 5 fields",
+		"60 * * * *":   `invalid minute field "60"`,
+		"* 24 * * *":   `invalid hour field "24"`,
+		"* * 0 * *":    `invalid day of month field "0"`,
+		"* * * foo *":  `invalid month field "foo"`,
+		"* * * * 1-8":  `invalid day of week field "1-8"`,
+		"*/0 * * * *":  `invalid minute field "*/0"`,
+		"5-1 * * * *":  "ends before it starts",
+		"@fortnightly": "unknown alias",
+		"0 0 30 2 *":   "no time within 5 years matches",
+	}
+	for expr, expected := range cases {
+		_, err := cronNext(expr, "2024-05-01T00:00:00Z")
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf(`Expected error %q for %q, got %v`, expected, expr, err)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package template
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// stringifyField converts a value to a string for a delimited or fixed width field
+// Floats keep every significant digit, other numbers and strings are converted with interfaceToString,
+// nil is empty and other types are formatted with fmt
+func stringifyField(v interface{}) string {
+	switch f := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(f), 'f', -1, 32)
+	}
+	if s, err := interfaceToString(v); err == nil {
+		return s
+	}
+	return fmt.Sprint(v)
+}
+
+// fieldValues returns the values of a variadic field list, expanding a single slice argument
+func fieldValues(values []interface{}) []interface{} {
+	if len(values) == 1 {
+		if expanded := interfaceSlice(values[0]); expanded != nil {
+			return expanded
+		}
+	}
+	return values
+}
+
+// csvField converts v to a string and quotes it as in RFC 4180 when it contains a comma, quote or line break
+func csvField(v interface{}) string {
+	var s = stringifyField(v)
+	if !strings.ContainsAny(s, ",\"\r\n") {
+		return s
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// csvLine joins values, or the elements of a single list argument, into one CSV line without a line terminator
+func csvLine(values ...interface{}) string {
+	values = fieldValues(values)
+	var fields = make([]string, len(values))
+	for i, v := range values {
+		fields[i] = csvField(v)
+	}
+	return strings.Join(fields, ",")
+}
+
+// tsvEscaper escapes the characters that would break a tab separated field, using the backslash escapes
+// understood by most TSV readers including PostgreSQL COPY and MySQL LOAD DATA
+var tsvEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
+
+// tsvLine joins values, or the elements of a single list argument, into one tab separated line
+// Embedded backslashes, tabs and line breaks are escaped as \\, \t, \n and \r
+func tsvLine(values ...interface{}) string {
+	values = fieldValues(values)
+	var fields = make([]string, len(values))
+	for i, v := range values {
+		fields[i] = tsvEscaper.Replace(stringifyField(v))
+	}
+	return strings.Join(fields, "\t")
+}
+
+// fixedWidthLine pads or truncates each value to the column width at the same index, counting runes rather than bytes
+// Options are the pad character, a space by default, and the alignment: "left" (the default) or "right" for every
+// column, or a comma separated alignment per column such as "left,right,right".
+func fixedWidthLine(widths interface{}, values interface{}, options ...string) (string, error) {
+	var widthList, valueList = interfaceSlice(widths), interfaceSlice(values)
+	if len(widthList) != len(valueList) {
+		return "", fmt.Errorf("fixedWidthLine: %d widths for %d values", len(widthList), len(valueList))
+	}
+	if len(options) > 2 {
+		return "", fmt.Errorf("fixedWidthLine: expected pad character and alignment options, got %d options", len(options))
+	}
+	var pad = " "
+	if len(options) > 0 {
+		if utf8.RuneCountInString(options[0]) != 1 {
+			return "", fmt.Errorf("fixedWidthLine: pad must be a single character, got %q", options[0])
+		}
+		pad = options[0]
+	}
+	var alignments = make([]string, len(valueList))
+	for i := range alignments {
+		alignments[i] = "left"
+	}
+	if len(options) > 1 {
+		var parts = strings.Split(options[1], ",")
+		if len(parts) != 1 && len(parts) != len(valueList) {
+			return "", fmt.Errorf("fixedWidthLine: %d alignments for %d values", len(parts), len(valueList))
+		}
+		for i := range alignments {
+			alignments[i] = strings.TrimSpace(parts[min(i, len(parts)-1)])
+		}
+	}
+	var b strings.Builder
+	for i, v := range valueList {
+		width, err := interfaceToWholeInt64(widthList[i])
+		if err != nil || width < 0 {
+			return "", fmt.Errorf("fixedWidthLine: invalid width %v for column %d", widthList[i], i+1)
+		}
+		var s = stringifyField(v)
+		var n = utf8.RuneCountInString(s)
+		if n > int(width) {
+			s = string([]rune(s)[:width])
+			n = int(width)
+		}
+		var padding = strings.Repeat(pad, int(width)-n)
+		switch alignments[i] {
+		case "left":
+			b.WriteString(s + padding)
+		case "right":
+			b.WriteString(padding + s)
+		default:
+			return "", fmt.Errorf("fixedWidthLine: invalid alignment %q, expected left or right", alignments[i])
+		}
+	}
+	return b.String(), nil
+}
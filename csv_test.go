@@ -0,0 +1,122 @@
+package template
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCSVField(t *testing.T) {
+	for _, tc := range []struct {
+		v        interface{}
+		expected string
+	}{
+		{"plain", `plain`},
+		{"Smith, John", `"Smith, John"`},
+		{`say "hi"`, `"say ""hi"""`},
+		{"two\nlines", "\"two\nlines\""},
+		{"Zoë, Łódź", `"Zoë, Łódź"`},
+		{"日本", `日本`},
+		{12.5, `12.5`},
+		{0.125, `0.125`},
+		{1234567.891, `1234567.891`},
+		{float32(0.1), `0.1`},
+		{42, `42`},
+		{json.Number("1e3"), `1e3`},
+		{true, `true`},
+		{nil, ``},
+	} {
+		if res := csvField(tc.v); res != tc.expected {
+			t.Errorf(`Unexpected result %q for %#v`, res, tc.v)
+		}
+	}
+}
+
+func TestCSVLine(t *testing.T) {
+	var values = []interface{}{"1", "Smith, \"JJ\"", "multi\r\nline", "Zoë", nil}
+	for _, line := range []string{csvLine(values...), csvLine(values)} {
+		records, err := csv.NewReader(strings.NewReader(line)).ReadAll()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !reflect.DeepEqual(records, [][]string{{"1", "Smith, \"JJ\"", "multi\nline", "Zoë", ""}}) {
+			t.Errorf(`Unexpected result %q from %q`, records, line)
+		}
+	}
+
+	res, err := InterpolateStrict(map[string]interface{}{"rows": []interface{}{
+		map[string]interface{}{"id": 1, "name": "Ada, Countess"},
+		map[string]interface{}{"id": 2, "name": `Grace "Amazing" Hopper`},
+	}}, `{{ range .rows }}{{ csvLine .id .name }};{{ end }}{{ csvLine (list "a" "b,c") }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `1,"Ada, Countess";2,"Grace ""Amazing"" Hopper";a,"b,c"` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestTSVLine(t *testing.T) {
+	if res := tsvLine("a", "tab\there", "new\nline", `back\slash`, 3); res != "a\ttab\\there\tnew\\nline\tback\\\\slash\t3" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	if res := tsvLine([]string{"Zoë", "日本"}); res != "Zoë\t日本" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	if res := tsvLine(0.125, 1234567.891); res != "0.125\t1234567.891" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestFixedWidthLine(t *testing.T) {
+	for _, tc := range []struct {
+		widths, values interface{}
+		options        []string
+		expected       string
+	}{
+		{[]interface{}{5, 3}, []interface{}{"ab", 7}, nil, "ab   7  "},
+		{[]interface{}{4, 6}, []interface{}{"Zoë", "Łódź"}, nil, "Zoë Łódź  "},
+		{[]interface{}{3}, []interface{}{"Müllerstraße"}, nil, "Mül"},
+		{[]int{6, 6}, []interface{}{"id", json.Number("42")}, []string{"0", "right"}, "0000id000042"},
+		{[]interface{}{float64(4), 5}, []interface{}{"ab", "cd"}, []string{".", "left,right"}, "ab.....cd"},
+		{[]interface{}{0, 2}, []interface{}{"gone", "日本語"}, []string{"·"}, "日本"},
+		{[]interface{}{6, 12}, []interface{}{0.125, 1234567.891}, []string{" ", "right"}, " 0.125 1234567.891"},
+	} {
+		res, err := fixedWidthLine(tc.widths, tc.values, tc.options...)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if res != tc.expected {
+			t.Errorf(`Unexpected result %q for %v`, res, tc.values)
+		}
+	}
+	for _, tc := range []struct {
+		widths, values interface{}
+		options        []string
+	}{
+		{[]interface{}{5}, []interface{}{"a", "b"}, nil},
+		{[]interface{}{-1}, []interface{}{"a"}, nil},
+		{[]interface{}{"wide"}, []interface{}{"a"}, nil},
+		{[]interface{}{5}, []interface{}{"a"}, []string{"ab"}},
+		{[]interface{}{5}, []interface{}{"a"}, []string{" ", "center"}},
+		{[]interface{}{5, 5}, []interface{}{"a", "b"}, []string{" ", "left,right,left"}},
+	} {
+		if _, err := fixedWidthLine(tc.widths, tc.values, tc.options...); err == nil {
+			t.Errorf(`Expected error for %v %v %v`, tc.widths, tc.values, tc.options)
+		}
+	}
+
+	res, err := InterpolateStrict(map[string]interface{}{"name": "Renée", "total": 12.5}, `[{{ fixedWidthLine (list 8 8) (list .name .total) " " "left,right" }}]`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "[Renée       12.5]" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
@@ -0,0 +1,110 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// isoCurrency is an ISO 4217 entry
+type isoCurrency struct {
+	code, numeric string
+	minorUnits    int
+	name          string
+}
+
+// findCurrency looks up a currency by alphabetic code, case insensitively
+func findCurrency(code string) (isoCurrency, error) {
+	c, ok := currencies[strings.ToUpper(strings.TrimSpace(code))]
+	if !ok {
+		return isoCurrency{}, fmt.Errorf("unknown currency %q", code)
+	}
+	return c, nil
+}
+
+// currencyInfo returns the code, numeric code, minor units and name of an ISO 4217 currency
+func currencyInfo(code string) (map[string]interface{}, error) {
+	c, err := findCurrency(code)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"code":       c.code,
+		"numeric":    c.numeric,
+		"minorUnits": c.minorUnits,
+		"name":       c.name,
+	}, nil
+}
+
+// interfaceToRat converts a number or decimal string to an exact rational, using the shortest decimal for floats
+func interfaceToRat(i interface{}) (*big.Rat, error) {
+	var s string
+	switch v := i.(type) {
+	case string:
+		s = strings.TrimSpace(v)
+	case json.Number:
+		s = v.String()
+	case float64:
+		s = strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		s = strconv.Itoa(v)
+	case int64:
+		s = strconv.FormatInt(v, 10)
+	default:
+		return nil, fmt.Errorf("unable to convert type %T to a decimal", i)
+	}
+	r, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return nil, fmt.Errorf("invalid amount %q", s)
+	}
+	return r, nil
+}
+
+// toMinorUnits converts an amount in major units to an integer count of the currency's minor units, e.g. 12.34 USD to 1234
+// Amounts more precise than the currency's minor units are an error rather than being rounded
+func toMinorUnits(code string, amount interface{}) (int64, error) {
+	c, err := findCurrency(code)
+	if err != nil {
+		return 0, err
+	}
+	r, err := interfaceToRat(amount)
+	if err != nil {
+		return 0, err
+	}
+	var scale = new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(c.minorUnits)), nil)
+	r.Mul(r, new(big.Rat).SetInt(scale))
+	if !r.IsInt() {
+		return 0, fmt.Errorf("amount %v has more than %d decimal places for %s", amount, c.minorUnits, c.code)
+	}
+	if !r.Num().IsInt64() {
+		return 0, fmt.Errorf("amount %v overflows int64 minor units", amount)
+	}
+	return r.Num().Int64(), nil
+}
+
+// fromMinorUnits converts an integer count of the currency's minor units to a decimal amount in major units, e.g. 1234 USD to 12.34
+func fromMinorUnits(code string, amount interface{}) (json.Number, error) {
+	c, err := findCurrency(code)
+	if err != nil {
+		return "", err
+	}
+	n, err := interfaceToWholeInt64(amount)
+	if err != nil {
+		return "", err
+	}
+	if c.minorUnits == 0 {
+		return json.Number(strconv.FormatInt(n, 10)), nil
+	}
+	var sign string
+	var digits = strconv.FormatInt(n, 10)
+	if n < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	if len(digits) <= c.minorUnits {
+		digits = strings.Repeat("0", c.minorUnits-len(digits)+1) + digits
+	}
+	var point = len(digits) - c.minorUnits
+	return json.Number(sign + digits[:point] + "." + digits[point:]), nil
+}
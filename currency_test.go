@@ -0,0 +1,105 @@
+package template
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestCurrencyInfo(t *testing.T) {
+	res, err := currencyInfo("jpy")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var expected = map[string]interface{}{"code": "JPY", "numeric": "392", "minorUnits": 0, "name": "Yen"}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf(`Unexpected result %v`, res)
+	}
+	for code, minorUnits := range map[string]int{"USD": 2, "EUR": 2, "KWD": 3, "BHD": 3, "CLP": 0, "KRW": 0, "CLF": 4} {
+		if c, err := findCurrency(code); err != nil || c.minorUnits != minorUnits {
+			t.Errorf(`Unexpected minor units %d for %s: %v`, c.minorUnits, code, err)
+		}
+	}
+	if _, err := currencyInfo("XYZ"); err == nil {
+		t.Error("Expected error for unknown currency")
+	}
+}
+
+func TestToMinorUnits(t *testing.T) {
+	for _, tc := range []struct {
+		code   string
+		amount interface{}
+		minor  int64
+	}{
+		{"USD", 12.34, 1234},
+		{"USD", "12.34", 1234},
+		{"USD", json.Number("0.1"), 10},
+		{"USD", 19.99, 1999},
+		{"USD", "-5.5", -550},
+		{"USD", 3, 300},
+		{"JPY", "1500", 1500},
+		{"KWD", "1.234", 1234},
+		{"EUR", "12.340", 1234},
+	} {
+		res, err := toMinorUnits(tc.code, tc.amount)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if res != tc.minor {
+			t.Errorf(`Unexpected result %d for %v %s`, res, tc.amount, tc.code)
+		}
+	}
+	for _, tc := range []struct {
+		code   string
+		amount interface{}
+	}{
+		{"JPY", 12.5},
+		{"USD", "1.001"},
+		{"USD", "abc"},
+		{"XYZ", 1},
+		{"USD", "1e30"},
+	} {
+		if _, err := toMinorUnits(tc.code, tc.amount); err == nil {
+			t.Errorf(`Expected error for %v %s`, tc.amount, tc.code)
+		}
+	}
+}
+
+func TestFromMinorUnits(t *testing.T) {
+	for _, tc := range []struct {
+		code   string
+		amount interface{}
+		major  json.Number
+	}{
+		{"USD", 1234, "12.34"},
+		{"USD", 5, "0.05"},
+		{"USD", int64(-5), "-0.05"},
+		{"USD", json.Number("-1234"), "-12.34"},
+		{"JPY", 1500, "1500"},
+		{"KWD", float64(1234), "1.234"},
+		{"CLF", 1, "0.0001"},
+	} {
+		res, err := fromMinorUnits(tc.code, tc.amount)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if res != tc.major {
+			t.Errorf(`Unexpected result %q for %v %s`, res, tc.amount, tc.code)
+		}
+	}
+	if _, err := fromMinorUnits("USD", 12.5); err == nil {
+		t.Error("Expected error for fractional minor units")
+	}
+
+	res, err := InterpolateStrict(map[string]interface{}{"currency": "usd", "total": json.Number("42.5")}, `{{ $m := toMinorUnits .currency .total }}{{ $m }} {{ fromMinorUnits .currency $m }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "4250 42.50" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
@@ -0,0 +1,85 @@
+package template
+
+import (
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// clock is the source of the current time for now and timestamp
+var clock = time.Now
+
+// SetClock makes now and timestamp read the time from now instead of the wall clock, nil restores the wall clock
+// Together with SetRandSource it makes renders reproducible for golden file tests and previews
+func SetClock(now func() time.Time) {
+	if now == nil {
+		now = time.Now
+	}
+	clock = now
+}
+
+// FixedClock returns a clock for SetClock that always returns t
+func FixedClock(t time.Time) func() time.Time {
+	return func() time.Time {
+		return t
+	}
+}
+
+var seededRandMu sync.Mutex
+var seededRand *rand.Rand
+
+// SetRandSource makes uuid, randomInt and randomFloat64 draw from src instead of crypto/rand and the
+// math/rand global source, so renders with the same seed produce the same output. nil restores the defaults.
+// It is intended for tests and previews, never for ids that must be unpredictable.
+func SetRandSource(src rand.Source) {
+	seededRandMu.Lock()
+	defer seededRandMu.Unlock()
+	if src == nil {
+		seededRand = nil
+		return
+	}
+	seededRand = rand.New(src)
+}
+
+func randomFloat64() float64 {
+	seededRandMu.Lock()
+	defer seededRandMu.Unlock()
+	if seededRand == nil {
+		return rand.Float64()
+	}
+	return seededRand.Float64()
+}
+
+func randomIntn(n int) int {
+	seededRandMu.Lock()
+	defer seededRandMu.Unlock()
+	if seededRand == nil {
+		return rand.Intn(n)
+	}
+	return seededRand.Intn(n)
+}
+
+// seededReader reads from the seeded source set with SetRandSource
+type seededReader struct{}
+
+func (seededReader) Read(p []byte) (int, error) {
+	seededRandMu.Lock()
+	defer seededRandMu.Unlock()
+	if seededRand == nil {
+		return 0, io.ErrUnexpectedEOF
+	}
+	return seededRand.Read(p)
+}
+
+func newUUID() (uuid.UUID, error) {
+	seededRandMu.Lock()
+	var seeded = seededRand != nil
+	seededRandMu.Unlock()
+	if seeded {
+		return uuid.NewRandomFromReader(seededReader{})
+	}
+	return uuid.NewRandom()
+}
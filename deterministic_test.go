@@ -0,0 +1,57 @@
+package template
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestDeterministicRender(t *testing.T) {
+	defer SetClock(nil)
+	defer SetRandSource(nil)
+	SetClock(FixedClock(time.Date(2024, 2, 29, 12, 0, 0, 0, time.UTC)))
+
+	var src = `{{ uuid }} {{ randomInt 1 1000000 }} {{ randomFloat64 }} {{ now "2006-01-02T15:04:05Z07:00" }} {{ timestamp }}`
+	var render = func() string {
+		SetRandSource(rand.NewSource(42))
+		res, err := InterpolateStrict(nil, src)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return res
+	}
+	var first = render()
+	if second := render(); second != first {
+		t.Errorf(`Expected identical renders, got %q and %q`, first, second)
+	}
+
+	SetRandSource(rand.NewSource(43))
+	if other, _ := InterpolateStrict(nil, src); other == first {
+		t.Errorf(`Expected a different seed to render differently`)
+	}
+
+	res, err := InterpolateStrict(nil, `{{ now "2006-01-02" }} {{ timestamp }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "2024-02-29 1709208000" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestDeterministicDefaults(t *testing.T) {
+	res, err := InterpolateStrict(nil, `{{ uuid }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	other, err := InterpolateStrict(nil, `{{ uuid }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res == other {
+		t.Errorf(`Expected random uuids, got %q twice`, res)
+	}
+}
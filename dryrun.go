@@ -0,0 +1,184 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DryRunPlaceholderToken is returned by getAuthXBearerToken and getAuthXBearerTokenFresh during a dry run
+const DryRunPlaceholderToken = "Bearer dry-run.placeholder.token"
+
+// DryRunResponse is a canned response returned to the http template functions during a dry run
+type DryRunResponse struct {
+	// Status code, defaults to 200
+	Status int
+	Header http.Header
+	Body   string
+}
+
+// DryRunCall is a network call a template would have made
+type DryRunCall struct {
+	Method string
+	URL    string
+	// Header with credentials redacted as for SetHTTPLogger
+	Header http.Header
+}
+
+// DryRun previews a template without side effects, see ExecuteDryRun
+// The http, http_data and graphql functions, binLookup and the ipLookup of an HTTPIPLookupProvider return the
+// canned response for the first matching URL pattern, or an error when none matches, without using their caches.
+// cacheSet and the other cache writes are no-ops, and authx tokens are replaced by DryRunPlaceholderToken.
+// A DryRun may be reused; calls accumulate.
+type DryRun struct {
+	// Responses keyed by URL pattern using path.Match syntax (e.g. "https://api.example.com/users/*"), matched against the URL without its query
+	// Exact patterns take precedence over wildcard patterns, which are tried in lexical order
+	Responses map[string]DryRunResponse
+
+	mu    sync.Mutex
+	calls []DryRunCall
+}
+
+// Calls returns the network calls the template would have made, in order
+func (d *DryRun) Calls() []DryRunCall {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]DryRunCall(nil), d.calls...)
+}
+
+func (d *DryRun) record(method, url string, header http.Header) {
+	d.mu.Lock()
+	d.calls = append(d.calls, DryRunCall{Method: method, URL: url, Header: redactHeaders(header, noRedact)})
+	d.mu.Unlock()
+}
+
+// response returns the canned response for the first pattern matching url
+func (d *DryRun) response(url string) (DryRunResponse, bool) {
+	if i := strings.IndexByte(url, '?'); i >= 0 {
+		url = url[:i]
+	}
+	if resp, ok := d.Responses[url]; ok {
+		return resp, true
+	}
+	var patterns = make([]string, 0, len(d.Responses))
+	for pattern := range d.Responses {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, url); ok {
+			return d.Responses[pattern], true
+		}
+	}
+	return DryRunResponse{}, false
+}
+
+// do records req and returns the canned response in place of sending it
+func (d *DryRun) do(req *http.Request) (*http.Response, error) {
+	var url = req.URL.Redacted()
+	d.record(req.Method, url, req.Header)
+	resp, ok := d.response(req.URL.String())
+	if !ok {
+		return nil, fmt.Errorf("dry-run: network disabled, no response for %s %s", req.Method, url)
+	}
+	var status = resp.Status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	var header = resp.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		StatusCode:    status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(resp.Body)),
+		ContentLength: int64(len(resp.Body)),
+		Request:       req,
+	}, nil
+}
+
+// authxToken records the token request and returns the placeholder token
+func (d *DryRun) authxToken(authxURL, authxToken, authorizationId string) (string, error) {
+	d.record("POST", authxURL, http.Header{"Authorization": {authxToken}})
+	return DryRunPlaceholderToken, nil
+}
+
+// stubs are the dry run replacements of the template functions with side effects
+func (d *DryRun) stubs() map[string]interface{} {
+	return map[string]interface{}{
+		"http":      httpFunc(d.do),
+		"http_data": httpDataFunc(d.do),
+		"graphql": func(url string, headers map[interface{}]interface{}, query string, variables interface{}) (interface{}, error) {
+			return graphqlDo(d.do, url, headers, query, variables)
+		},
+		"ipLookup":                 ipLookupFunc(d.do, false),
+		"binLookup":                binLookupFunc(d.do, false),
+		"getAuthXBearerToken":      d.authxToken,
+		"getAuthXBearerTokenFresh": d.authxToken,
+		"cacheSet": func(key string, value interface{}, expire interface{}) (interface{}, error) {
+			return value, nil
+		},
+		"cacheIncr": func(key string, delta interface{}, expire interface{}) (int64, error) {
+			return d.cacheAdd(key, delta, 1)
+		},
+		"cacheDecr": func(key string, delta interface{}, expire interface{}) (int64, error) {
+			return d.cacheAdd(key, delta, -1)
+		},
+		"cacheDelete": func(key string) bool {
+			_, err := currentTemplateCache().Get(key)
+			return err == nil
+		},
+	}
+}
+
+// cacheAdd returns the value cacheIncr or cacheDecr would have stored without storing it
+func (d *DryRun) cacheAdd(key string, delta interface{}, sign int64) (int64, error) {
+	n, err := interfaceToInt64(delta)
+	if err != nil {
+		return 0, err
+	}
+	var current int64
+	if v, err := currentTemplateCache().Get(key); err == nil {
+		current, err = interfaceToInt64(v)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return current + sign*n, nil
+}
+
+func (d *DryRun) interpolate(data interface{}, text string) (string, error) {
+	tmpl, err := Parse(text)
+	if err != nil {
+		return "", newExecError("", text, err)
+	}
+	var buf bytes.Buffer
+	err = tmpl.ExecuteDryRun(&buf, data, d)
+	if err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// ExecuteDryRun executes the template with network calls and cache writes stubbed by dryRun
+// The calls the template would have made are recorded on dryRun
+func (t *Template) ExecuteDryRun(w io.Writer, data interface{}, dryRun *DryRun) error {
+	var overlay = newRenderOverlay(dryRun.stubs())
+	renderStateOf(overlay).dryRun = true
+	return t.execute(w, data, rejectNoValue, overlay)
+}
+
+// InterpolateDryRun is InterpolateStrict with network calls and cache writes stubbed by dryRun
+func InterpolateDryRun(dryRun *DryRun, data interface{}, text string) (string, error) {
+	return dryRun.interpolate(data, text)
+}
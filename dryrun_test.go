@@ -0,0 +1,114 @@
+package template
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExecuteDryRun(t *testing.T) {
+	restoreRootTemplate(t)
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	err := LoadPartialNamed("lookup.tmpl", `{{ (parseJSON (http "GET" (print .url "/bins/411111") (dict)).Body).brand }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var dryRun = &DryRun{Responses: map[string]DryRunResponse{
+		server.URL + "/users/*": {Body: `{"name":"Ada"}`},
+		server.URL + "/bins/*":  {Status: 200, Body: `{"brand":"visa"}`},
+	}}
+	tmpl, err := Parse(`{{ $user := parseJSON (http "GET" (print .url "/users/1?expand=all") (dict "Authorization" "Bearer secret" "Accept" "application/json")).Body }}` +
+		`{{ $user.name }} {{ include "lookup.tmpl" . }} {{ cacheSet "dry-run-key" "x" "1h" }}{{ cacheHas "dry-run-key" }} ` +
+		`{{ getAuthXBearerToken (print .url "/authx") "authx-secret" "auth-1" }} ` +
+		`{{ tryRender "{{ (http \"POST\" (print .url \"/unknown\") (dict)).Status }}" . "fallback" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf strings.Builder
+	err = tmpl.ExecuteDryRun(&buf, map[string]interface{}{"url": server.URL}, dryRun)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != "Ada visa xfalse "+DryRunPlaceholderToken+" fallback" {
+		t.Errorf(`Unexpected result %q`, buf.String())
+	}
+	if hits != 0 {
+		t.Errorf(`Expected no requests to be sent, got %d`, hits)
+	}
+
+	var calls = dryRun.Calls()
+	var expected = []string{
+		"GET " + server.URL + "/users/1?expand=all",
+		"GET " + server.URL + "/bins/411111",
+		"POST " + server.URL + "/authx",
+		"POST " + server.URL + "/unknown",
+	}
+	if len(calls) != len(expected) {
+		t.Errorf(`Unexpected calls %+v`, calls)
+		return
+	}
+	for i, call := range calls {
+		if call.Method+" "+call.URL != expected[i] {
+			t.Errorf(`Unexpected call %d %s %s`, i, call.Method, call.URL)
+		}
+	}
+	if calls[0].Header.Get("Authorization") != "REDACTED" || calls[0].Header.Get("Accept") != "application/json" {
+		t.Errorf(`Unexpected headers %v`, calls[0].Header)
+	}
+	if calls[2].Header.Get("Authorization") != "REDACTED" {
+		t.Errorf(`Expected the authx token to be redacted, got %v`, calls[2].Header)
+	}
+}
+
+func TestInterpolateDryRunNetworkDisabled(t *testing.T) {
+	var dryRun = &DryRun{}
+	_, err := InterpolateDryRun(dryRun, nil, `{{ http "GET" "https://example.com/users/1" (dict) }}`)
+	if err == nil || !strings.Contains(err.Error(), "dry-run: network disabled") {
+		t.Errorf(`Expected a network disabled error, got %v`, err)
+	}
+	if len(dryRun.Calls()) != 1 {
+		t.Errorf(`Expected the call to be recorded, got %+v`, dryRun.Calls())
+	}
+}
+
+func TestCacheGetOrSetDryRun(t *testing.T) {
+	var hits int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer server.Close()
+
+	tmpl, err := Parse(`{{ cacheGetOrSet "dry-run-get-or-set" "1h" "{{ (parseJSON (http \"GET\" .url (dict)).Body).name }}" . }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var dryRun = &DryRun{Responses: map[string]DryRunResponse{
+		server.URL: {Body: `{"name":"stubbed"}`},
+	}}
+	var buf strings.Builder
+	err = tmpl.ExecuteDryRun(&buf, map[string]interface{}{"url": server.URL}, dryRun)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != "stubbed" {
+		t.Errorf(`Unexpected result %q`, buf.String())
+	}
+	if hits != 0 || len(dryRun.Calls()) != 1 {
+		t.Errorf(`Expected the request to be stubbed, got %d hits and calls %+v`, hits, dryRun.Calls())
+	}
+	if cacheHas("dry-run-get-or-set") {
+		t.Error("Expected a dry run not to cache the rendered source")
+	}
+}
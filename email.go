@@ -0,0 +1,65 @@
+package template
+
+import (
+	"regexp"
+	"strings"
+)
+
+var reEmailDigit = regexp.MustCompile("[0-9]")
+
+// normalizeEmail reduces an email address to its lowercase local part without dots, digits or a + suffix
+func normalizeEmail(email string) string {
+	// get everything before the first instance of '+'
+	email = strings.Split(email, "+")[0]
+	// get everything before the first instance of '@'
+	email = strings.Split(email, "@")[0]
+	email = strings.ReplaceAll(email, ".", "")
+	email = reEmailDigit.ReplaceAllString(email, "")
+	email = strings.TrimSpace(email)
+	email = strings.ToLower(email)
+	return email
+}
+
+var reEmailLocal = regexp.MustCompile("^[A-Za-z0-9!#$%&'*+/=?^_`{|}~-]+(\\.[A-Za-z0-9!#$%&'*+/=?^_`{|}~-]+)*$")
+var reDomainLabel = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?$`)
+var reTopLevelDomain = regexp.MustCompile(`^([A-Za-z]{2,}|xn--[A-Za-z0-9-]+)$`)
+
+// splitEmail splits a practically valid email address into its local part and domain
+// The address must have a single @, a dot-atom local part of at most 64 characters and a domain of at least
+// two valid labels with an alphabetic top level domain. Quoted local parts and address literals aren't accepted.
+func splitEmail(s string) (local, domain string, ok bool) {
+	local, domain, found := strings.Cut(s, "@")
+	if !found || strings.Contains(domain, "@") || len(local) > 64 || len(domain) > 253 || !reEmailLocal.MatchString(local) {
+		return "", "", false
+	}
+	var labels = strings.Split(domain, ".")
+	if len(labels) < 2 || !reTopLevelDomain.MatchString(labels[len(labels)-1]) {
+		return "", "", false
+	}
+	for _, label := range labels {
+		if len(label) > 63 || !reDomainLabel.MatchString(label) {
+			return "", "", false
+		}
+	}
+	return local, domain, true
+}
+
+// emailValid reports whether s is a practically valid email address, see splitEmail
+func emailValid(s string) bool {
+	_, _, ok := splitEmail(s)
+	return ok
+}
+
+// emailParts returns the local part, lowercase domain and normalize_email form of an email address
+// Invalid addresses return nil so the result can be tested with if
+func emailParts(s string) map[string]interface{} {
+	local, domain, ok := splitEmail(s)
+	if !ok {
+		return nil
+	}
+	return map[string]interface{}{
+		"local":      local,
+		"domain":     strings.ToLower(domain),
+		"normalized": normalizeEmail(s),
+	}
+}
@@ -0,0 +1,59 @@
+package template
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEmailValid(t *testing.T) {
+	for s, valid := range map[string]bool{
+		"ada@example.com":                      true,
+		"Ada.Lovelace+news@Mail.Example.co.uk": true,
+		"o'brien@example.ie":                   true,
+		"user@xn--bcher-kva.example":           true,
+		"":                                     false,
+		"ada":                                  false,
+		"ada@":                                 false,
+		"@example.com":                         false,
+		"ada@@example.com":                     false,
+		"ada@b@example.com":                    false,
+		"ada lovelace@example.com":             false,
+		"ada@example .com":                     false,
+		".ada@example.com":                     false,
+		"ada..l@example.com":                   false,
+		"ada@localhost":                        false,
+		"ada@-example.com":                     false,
+		"ada@example-.com":                     false,
+		"ada@example.c0m":                      false,
+		"ada@example..com":                     false,
+	} {
+		if emailValid(s) != valid {
+			t.Errorf(`Expected emailValid(%q) to be %v`, s, valid)
+		}
+	}
+}
+
+func TestEmailParts(t *testing.T) {
+	res, err := InterpolateStrict(map[string]interface{}{"email": "Ada.Lovelace9+news@Mail.Example.com"},
+		`{{ with emailParts .email }}{{ .local }} {{ .domain }} {{ .normalized }}{{ end }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "Ada.Lovelace9+news mail.example.com adalovelace" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+
+	res, err = InterpolateStrict(map[string]interface{}{"email": "not an email"},
+		`{{ with emailParts .email }}{{ .domain }}{{ else }}invalid{{ end }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "invalid" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	if parts := emailParts("a@b"); !reflect.DeepEqual(parts, map[string]interface{}(nil)) {
+		t.Errorf(`Unexpected result %v`, parts)
+	}
+}
@@ -0,0 +1,58 @@
+package template
+
+import (
+	"database/sql/driver"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// UnmarshalText implements encoding.TextUnmarshaler, parsing text as template source like UnmarshalJSON
+func (t *Template) UnmarshalText(text []byte) error {
+	return t.parse(string(text))
+}
+
+// MarshalText implements encoding.TextMarshaler, returning the template source
+func (t Template) MarshalText() ([]byte, error) {
+	return []byte(t.Source()), nil
+}
+
+// UnmarshalYAML implementation for Template, the node must be a scalar holding the template source
+func (t *Template) UnmarshalYAML(value *yaml.Node) error {
+	var src string
+	err := value.Decode(&src)
+	if err != nil {
+		return err
+	}
+	return t.parse(src)
+}
+
+// MarshalYAML implementation for Template, emitting the template source as a string
+func (t Template) MarshalYAML() (interface{}, error) {
+	return t.Source(), nil
+}
+
+// Scan implements sql.Scanner, parsing a string or []byte column as template source
+// A NULL column leaves t without a parsed template
+func (t *Template) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		t.Template = nil
+		t.source = ""
+		return nil
+	case string:
+		return t.parse(v)
+	case []byte:
+		return t.parse(string(v))
+	default:
+		return fmt.Errorf("cannot scan %T into Template", src)
+	}
+}
+
+// Value implements driver.Valuer, returning the template source or NULL when no template is parsed
+func (t Template) Value() (driver.Value, error) {
+	if t.Template == nil {
+		return nil, nil
+	}
+	return t.Source(), nil
+}
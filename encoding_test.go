@@ -0,0 +1,228 @@
+package template
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+type yamlConfig struct {
+	Subject *Template `yaml:"subject"`
+	Body    Template  `yaml:"body"`
+}
+
+func TestTemplateYAML(t *testing.T) {
+	var cfg yamlConfig
+	err := yaml.Unmarshal([]byte("subject: \"Order {{ .id }}\"\nbody: |\n  Hi {{ .name }},\n  thanks\n"), &cfg)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := cfg.Subject.ExecuteToString(map[string]interface{}{"id": 1})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "Order 1" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	res, err = cfg.Body.ExecuteToString(map[string]interface{}{"name": "x"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "Hi x,\nthanks\n" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(out) != "subject: Order {{ .id }}\nbody: |\n    Hi {{ .name }},\n    thanks\n" {
+		t.Errorf(`Unexpected YAML %q`, out)
+	}
+
+	err = yaml.Unmarshal([]byte(`subject: "{{ .id"`), &cfg)
+	if err == nil {
+		t.Errorf(`Expected invalid template source to fail`)
+	}
+}
+
+func TestTemplateText(t *testing.T) {
+	var tmpl Template
+	var _ encoding.TextUnmarshaler = &tmpl
+	err := tmpl.UnmarshalText([]byte(`[[ {{- .x -}} ]]`))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := tmpl.ExecuteToString(map[string]interface{}{"x": 1})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "[[1]]" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	text, err := tmpl.MarshalText()
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(text) != `[[ {{- .x -}} ]]` {
+		t.Errorf(`Unexpected text %q`, text)
+	}
+}
+
+func TestTemplateJSON(t *testing.T) {
+	SetDelims("[[", "]]")
+	defer SetDelims("", "")
+	var src = []byte(`"[[ define \"greet\" ]]Hi [[ . ]][[ end ]][[ template \"greet\" .name ]] {{ .name }}"`)
+	var tmpl Template
+	err := json.Unmarshal(src, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	out, err := json.Marshal(tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(out) != string(src) {
+		t.Errorf(`Unexpected JSON %s`, out)
+	}
+	var again Template
+	err = json.Unmarshal(out, &again)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := again.ExecuteToString(map[string]interface{}{"name": "x"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "Hi x {{ .name }}" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+
+	out, err = json.Marshal(Template{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(out) != `""` {
+		t.Errorf(`Unexpected JSON for zero Template %s`, out)
+	}
+}
+
+// memDriver is a database/sql driver storing a single value, returned by every query
+type memDriver struct {
+	value driver.Value
+}
+
+func (d *memDriver) Open(name string) (driver.Conn, error) { return memConn{d}, nil }
+
+type memConn struct{ d *memDriver }
+
+func (c memConn) Prepare(query string) (driver.Stmt, error) { return memStmt{c.d, query}, nil }
+func (c memConn) Close() error                              { return nil }
+func (c memConn) Begin() (driver.Tx, error)                 { return nil, errors.New("not supported") }
+
+type memStmt struct {
+	d     *memDriver
+	query string
+}
+
+func (s memStmt) Close() error  { return nil }
+func (s memStmt) NumInput() int { return -1 }
+func (s memStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.value = args[0]
+	return driver.RowsAffected(1), nil
+}
+func (s memStmt) Query(args []driver.Value) (driver.Rows, error) {
+	return &memRows{value: s.d.value}, nil
+}
+
+type memRows struct {
+	value driver.Value
+	done  bool
+}
+
+func (r *memRows) Columns() []string { return []string{"template"} }
+func (r *memRows) Close() error      { return nil }
+func (r *memRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+	return nil
+}
+
+func init() {
+	sql.Register("templatemem", &memDriver{})
+}
+
+func TestTemplateSQL(t *testing.T) {
+	db, err := sql.Open("templatemem", "")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer db.Close()
+
+	tmpl, err := Parse(`Hi {{ .name }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = db.Exec(`INSERT INTO templates (template) VALUES (?)`, tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var scanned Template
+	err = db.QueryRow(`SELECT template FROM templates`).Scan(&scanned)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := scanned.ExecuteToString(map[string]interface{}{"name": "x"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "Hi x" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+
+	_, err = db.Exec(`INSERT INTO templates (template) VALUES (?)`, Template{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = db.QueryRow(`SELECT template FROM templates`).Scan(&scanned)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if scanned.Template != nil {
+		t.Errorf(`Expected NULL to scan to an empty template`)
+	}
+
+	err = scanned.Scan([]byte(`{{ .name `))
+	if err == nil {
+		t.Errorf(`Expected invalid template source to fail`)
+	}
+}
@@ -0,0 +1,126 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"runtime/debug"
+	"time"
+)
+
+// execErrorSourceLen is the number of characters of template source kept in an ExecError
+const execErrorSourceLen = 120
+
+// ExecError wraps an error from parsing or executing a template with the template it came from
+type ExecError struct {
+	// Key path of the template within a map passed to InterpolateMap, empty otherwise
+	Key string
+	// Name of the template, when parsed with ParseNamed or unmarshaled into NamedTemplates
+	Name string
+	// Source is the template source, truncated to its first 120 characters
+	Source string
+	// Err is the underlying text/template or function error
+	Err error
+}
+
+// Error implementation for ExecError
+func (e *ExecError) Error() string {
+	if e.Key != "" {
+		return fmt.Sprintf("%s: %v (template %q)", e.Key, e.Err, e.Source)
+	}
+	return fmt.Sprintf("%v (template %q)", e.Err, e.Source)
+}
+
+// Unwrap returns the underlying error
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
+
+// newExecError wraps err with an excerpt of the template source
+func newExecError(key, src string, err error) *ExecError {
+	var runes = []rune(src)
+	if len(runes) > execErrorSourceLen {
+		src = string(runes[:execErrorSourceLen]) + "..."
+	}
+	return &ExecError{Key: key, Source: src, Err: err}
+}
+
+// panicStackLen is the number of bytes of stack trace kept in a PanicError
+const panicStackLen = 4096
+
+// PanicError is returned in place of a panic raised while executing a template
+type PanicError struct {
+	// Value passed to panic
+	Value interface{}
+	// Stack of the panicking goroutine, truncated
+	Stack string
+}
+
+// Error implementation for PanicError
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// recoverPanic converts a panic into a PanicError assigned to err
+// It must be deferred directly
+func recoverPanic(err *error) {
+	if r := recover(); r != nil {
+		var stack = debug.Stack()
+		if len(stack) > panicStackLen {
+			stack = stack[:panicStackLen]
+		}
+		*err = &PanicError{Value: r, Stack: string(stack)}
+	}
+}
+
+// Execute applies the template to data, wrapping errors in an ExecError
+// Panics are recovered and returned as a PanicError
+func (t *Template) Execute(w io.Writer, data interface{}) error {
+	return t.execute(w, data, rejectNoValue, nil)
+}
+
+// execute applies the template to data as a render with the overlay funcs, see newRenderOverlay
+// A nil overlay starts a new render when the template uses render scoped functions
+func (t *Template) execute(w io.Writer, data interface{}, strict bool, overlay map[string]interface{}) (err error) {
+	if observer != nil {
+		defer func(start time.Time) {
+			observeExecute(t.Name(), t.Source(), start, err)
+		}(time.Now())
+	}
+	defer recoverPanic(&err)
+	var tmpl = t.Template
+	if overlay == nil && t.usesRenderState() {
+		overlay = newRenderOverlay(nil)
+	}
+	if overlay != nil {
+		// Clone so concurrent renders of the template don't share funcs
+		if tmpl, err = tmpl.Clone(); err != nil {
+			return err
+		}
+		tmpl.Funcs(renderChainFuncs(nil, overlay))
+		if t.cacheNamespace != "" {
+			tmpl.Funcs(cacheNamespaceFuncs(t.cacheNamespace, nil, overlay))
+		}
+	}
+	if strict {
+		var buf bytes.Buffer
+		err = tmpl.Execute(limitOutput(&buf), data)
+		if err == nil {
+			err = checkNoValue(buf.Bytes())
+		}
+		if err == nil {
+			_, err = buf.WriteTo(w)
+			return err
+		}
+	} else {
+		err = tmpl.Execute(limitOutput(w), data)
+	}
+	if err != nil {
+		var execErr = newExecError("", t.Source(), redactRenderError(overlay, err))
+		if name := t.Name(); name != RootTemplate.Name() {
+			execErr.Name = name
+		}
+		return execErr
+	}
+	return nil
+}
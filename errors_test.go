@@ -0,0 +1,198 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestExecErrorInterpolate(t *testing.T) {
+	var src = `{{ .foo.bar | nonexistentFunc }}`
+	_, err := InterpolateStrict(nil, src)
+	var execErr *ExecError
+	if !errors.As(err, &execErr) {
+		t.Errorf(`Expected ExecError, got %v`, err)
+		return
+	}
+	if execErr.Source != src || execErr.Key != "" {
+		t.Errorf(`Unexpected ExecError %+v`, execErr)
+	}
+}
+
+func TestExecErrorInterpolateMap(t *testing.T) {
+	_, err := InterpolateMap(map[string]interface{}{}, map[string]interface{}{
+		"ok": "{{ 1 }}",
+		"nested": map[string]interface{}{
+			"bad": `{{ parseJSON "{" }}` + strings.Repeat("x", 200),
+		},
+	})
+	var execErr *ExecError
+	if !errors.As(err, &execErr) {
+		t.Errorf(`Expected ExecError, got %v`, err)
+		return
+	}
+	if execErr.Key != "nested.bad" {
+		t.Errorf(`Unexpected key %q`, execErr.Key)
+	}
+	if len(execErr.Source) != execErrorSourceLen+3 {
+		t.Errorf(`Expected truncated source, got %q`, execErr.Source)
+	}
+	var tmplErr template.ExecError
+	if !errors.As(err, &tmplErr) {
+		t.Errorf(`Expected the underlying template.ExecError to be unwrapped, got %T`, execErr.Err)
+	}
+}
+
+func TestExecErrorTemplateExecute(t *testing.T) {
+	var tmpl *Template
+	err := json.Unmarshal([]byte(`"{{ parseJSON .body }}"`), &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{"body": "{"})
+	var execErr *ExecError
+	if !errors.As(err, &execErr) {
+		t.Errorf(`Expected ExecError, got %v`, err)
+		return
+	}
+	if execErr.Source != `{{ parseJSON .body }}` {
+		t.Errorf(`Unexpected source %q`, execErr.Source)
+	}
+}
+
+type panicWriter struct{}
+
+func (panicWriter) Write(p []byte) (int, error) {
+	panic("write failed")
+}
+
+func TestPanicRecoveryExecute(t *testing.T) {
+	var tmpl *Template
+	err := json.Unmarshal([]byte(`"{{ .name }}"`), &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = tmpl.Execute(panicWriter{}, map[string]interface{}{"name": "x"})
+	var panicErr *PanicError
+	if !errors.As(err, &panicErr) {
+		t.Errorf(`Expected PanicError, got %v`, err)
+		return
+	}
+	if panicErr.Value != "write failed" || !strings.Contains(panicErr.Stack, "panicWriter") {
+		t.Errorf(`Unexpected PanicError %v %s`, panicErr.Value, panicErr.Stack)
+	}
+}
+
+func TestPanicRecoveryInterpolate(t *testing.T) {
+	restoreTemplateFuncs(t)
+	err := RegisterFunc("explode", func() (string, error) {
+		var m map[string]int
+		m["x"] = 1
+		return "", nil
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = InterpolateMap(nil, map[string]interface{}{"a": "{{ explode }}"})
+	if err == nil {
+		t.Errorf(`Expected panicking func to return an error`)
+	}
+}
+
+func TestKnownPanicSites(t *testing.T) {
+	var cases = map[string]string{
+		`{{ http "GET" .url (dict 1 "x") }}`:          "header name must be a string",
+		`{{ http "GET" .url (dict "X-Id" 1) }}`:       "value must be a string",
+		`{{ http_data "POST" .url (dict 1 "x") "" }}`: "header name must be a string",
+		`{{ (toAmount "not an amount").ToString }}`:   "toAmount",
+		`{{ getAuthXBearerToken .url "token" "id" }}`: "authx",
+	}
+	for src, expected := range cases {
+		_, err := InterpolateStrict(map[string]interface{}{"url": "http://127.0.0.1:0"}, src)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf(`Expected error containing %q for %s, got %v`, expected, src, err)
+		}
+	}
+}
+
+func TestParseNamed(t *testing.T) {
+	restoreRootTemplate(t)
+	err := LoadPartial("greeting", `hello {{ . }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	tmpl, err := ParseNamed("tenant-42/welcome", `{{ template "greeting" .name }}{{ parseJSON .body }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if tmpl.Name() != "tenant-42/welcome" {
+		t.Errorf(`Unexpected name %q`, tmpl.Name())
+	}
+
+	var o CountingObserver
+	SetObserver(&o)
+	defer SetObserver(nil)
+
+	res, err := tmpl.ExecuteToString(map[string]interface{}{"name": "world", "body": "{}"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "hello worldmap[]" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	_, err = tmpl.ExecuteToString(map[string]interface{}{"name": "world", "body": "{"})
+	var execErr *ExecError
+	if !errors.As(err, &execErr) {
+		t.Errorf(`Expected ExecError, got %v`, err)
+		return
+	}
+	if execErr.Name != "tenant-42/welcome" || !strings.Contains(err.Error(), "tenant-42/welcome") {
+		t.Errorf(`Expected the error to name the template, got %v`, err)
+	}
+	if o.Executions["tenant-42/welcome"] != 2 || o.Errors["tenant-42/welcome"] != 1 {
+		t.Errorf(`Unexpected executions %v errors %v`, o.Executions, o.Errors)
+	}
+
+	_, err = ParseNamed("greeting", `{{ . }}`)
+	if err == nil {
+		t.Errorf(`Expected the name of a partial to be rejected`)
+	}
+	_, err = ParseNamed("broken", `{{ .x `)
+	if err == nil || !strings.Contains(err.Error(), "broken") {
+		t.Errorf(`Expected the parse error to name the template, got %v`, err)
+	}
+}
+
+func TestNamedTemplatesUnmarshalJSON(t *testing.T) {
+	var cfg struct {
+		Templates NamedTemplates `json:"templates"`
+	}
+	err := json.Unmarshal([]byte(`{"templates": {"subject": "Hi {{ .name }}", "body": "{{ index .items 3 }}"}}`), &cfg)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if cfg.Templates["subject"].Name() != "subject" || cfg.Templates["body"].Source() != "{{ index .items 3 }}" {
+		t.Errorf(`Unexpected templates %v`, cfg.Templates)
+	}
+	_, err = cfg.Templates["body"].ExecuteToString(map[string]interface{}{"items": []int{}})
+	var execErr *ExecError
+	if !errors.As(err, &execErr) || execErr.Name != "body" {
+		t.Errorf(`Expected an ExecError naming body, got %v`, err)
+	}
+
+	err = json.Unmarshal([]byte(`{"templates": {"bad": "{{ .x "}}`), &cfg)
+	if err == nil || !strings.Contains(err.Error(), `template "bad"`) {
+		t.Errorf(`Expected the parse error to name the template, got %v`, err)
+	}
+}
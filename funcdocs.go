@@ -0,0 +1,218 @@
+package template
+
+import (
+	"sort"
+)
+
+// FuncDoc documents a template function for listing to template authors
+type FuncDoc struct {
+	// Signature in template call order, e.g. "add(a, b number) int"
+	Signature string `json:"signature"`
+	// Description is a one line summary
+	Description string `json:"description"`
+	// Example template using the function
+	Example string `json:"example"`
+}
+
+// FuncNames returns the sorted names of the functions available to templates, documented or not
+func FuncNames() []string {
+	var names = make([]string, 0, len(TemplateFuncs))
+	for name := range TemplateFuncs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FuncDocs returns the documentation of the available template functions that have it, keyed by name
+// Functions registered without WithDoc are listed by FuncNames but not included here
+func FuncDocs() map[string]FuncDoc {
+	var docs = make(map[string]FuncDoc, len(TemplateFuncs))
+	for name := range TemplateFuncs {
+		if doc, ok := funcDocs[name]; ok {
+			docs[name] = doc
+		}
+	}
+	return docs
+}
+
+// funcDocs documents the built in template functions and those registered with WithDoc
+var funcDocs = map[string]FuncDoc{
+	"randomFloat64":            {`randomFloat64() float64`, `Returns a pseudo-random number in [0.0, 1.0)`, `{{ randomFloat64 }}`},
+	"randomInt":                {`randomInt(min, max int) int`, `Returns a pseudo-random integer between min and max inclusive`, `{{ randomInt 1 6 }}`},
+	"uuid":                     {`uuid() string`, `Returns a new random UUID`, `{{ uuid }}`},
+	"toJSON":                   {`toJSON(value any) string`, `Encodes a value as JSON`, `{{ toJSON .event }}`},
+	"toJSONStable":             {`toJSONStable(value any) string`, `Encodes a value as JSON with object keys sorted at every level`, `{{ toJSONStable .event }}`},
+	"toJSONOmitEmpty":          {`toJSONOmitEmpty(value any) string`, `Encodes a value as JSON leaving out null, empty string, empty array and empty object members`, `{{ toJSONOmitEmpty (dict "a" 1 "b" "") }}`},
+	"now":                      {`now(layout string) string`, `Formats the current time with a Go time layout`, `{{ now "2006-01-02" }}`},
+	"timestamp":                {`timestamp() int64`, `Returns the current unix time in seconds`, `{{ timestamp }}`},
+	"env":                      {`env(key string) string`, `Returns the value of an environment variable`, `{{ env "REGION" }}`},
+	"trim":                     {`trim(s, cutset string) string`, `Removes leading and trailing characters contained in cutset`, `{{ trim .code "-" }}`},
+	"multiply":                 {`multiply(x, y number) float64`, `Multiplies two numbers, treating invalid values as zero`, `{{ multiply .amount 100 }}`},
+	"ge":                       {`ge(x, y number) bool`, `Reports whether x is greater than or equal to y, comparing numbers of any type`, `{{ if ge .amount 100 }}large{{ end }}`},
+	"normalize_email":          {`normalize_email(email string) string`, `Reduces an email address to its lowercase local part without dots, digits or + suffix`, `{{ normalize_email .email }}`},
+	"emailValid":               {`emailValid(s string) bool`, `Reports whether s is a practically valid email address`, `{{ if emailValid .email }}email{{ end }}`},
+	"emailParts":               {`emailParts(s string) map`, `Returns the local, domain and normalized parts of an email address, nil when invalid`, `{{ with emailParts .email }}{{ .domain }}{{ end }}`},
+	"phoneValid":               {`phoneValid(s, defaultRegion string) bool`, `Reports whether s is a plausible phone number, international or national to defaultRegion`, `{{ if phoneValid .phone "US" }}sms{{ end }}`},
+	"phoneCountry":             {`phoneCountry(s string) string`, `Returns the ISO 3166-1 alpha-2 region of an international phone number, empty when unknown`, `{{ phoneCountry "+44 20 7946 0000" }}`},
+	"countryLookup":            {`countryLookup(input any) map`, `Returns the alpha2, alpha3, numeric and name of a country given any of them or an alias, nil when unknown`, `{{ (countryLookup "United Kingdom").alpha3 }}`},
+	"countryAlpha2":            {`countryAlpha2(input any) string`, `Returns the ISO 3166-1 alpha-2 code of a country, empty when unknown`, `{{ countryAlpha2 "USA" }}`},
+	"countryName":              {`countryName(input any) string`, `Returns the English short name of a country, empty when unknown`, `{{ countryName 840 }}`},
+	"regionCode":               {`regionCode(country any, input string) string`, `Returns the two letter code of a US or Canadian state, province or territory given its code, name or abbreviation, empty when unknown`, `{{ regionCode "US" "Calif." }}`},
+	"regionName":               {`regionName(country any, code string) string`, `Returns the English name of a US or Canadian state, province or territory, empty when unknown`, `{{ regionName "CA" "QC" }}`},
+	"parseAcceptLanguage":      {`parseAcceptLanguage(header string) []string`, `Returns the language tags of an Accept-Language header ordered by preference, skipping malformed entries`, `{{ index (parseAcceptLanguage .headers.accept_language) 0 }}`},
+	"matchLanguage":            {`matchLanguage(supported []string, header string) string`, `Returns the supported language tag best matching an Accept-Language header, or the first supported tag`, `{{ matchLanguage (list "en" "es" "fr") .accept_language }}`},
+	"stripTags":                {`stripTags(html string) string`, `Removes the tags, comments and script and style contents of HTML and decodes entities`, `{{ stripTags .ticket.body }}`},
+	"htmlToText":               {`htmlToText(html string) string`, `Converts HTML to plain text with collapsed whitespace, line breaks for blocks and bulleted list items`, `{{ htmlToText .ticket.body }}`},
+	"htmlEscape":               {`htmlEscape(s string) string`, `Escapes <, >, &, ' and " for HTML text`, `<p>{{ htmlEscape .name }}</p>`},
+	"htmlUnescape":             {`htmlUnescape(s string) string`, `Decodes HTML entities such as &amp; and &#39;`, `{{ htmlUnescape .title }}`},
+	"attrEscape":               {`attrEscape(s string) string`, `Escapes s for an HTML attribute value, including quotes, backticks, = and whitespace`, `<a title="{{ attrEscape .title }}">`},
+	"markdownToHTML":           {`markdownToHTML(md string) string`, `Renders a CommonMark subset (headings, emphasis, links, lists, code, quotes) as HTML, escaping raw HTML`, `{{ markdownToHTML .body }}`},
+	"markdownStrip":            {`markdownStrip(md string) string`, `Renders markdown as plain text, keeping list bullets and following links with their URL`, `{{ markdownStrip .body }}`},
+	"xmlEscape":                {`xmlEscape(s string) string`, `Escapes &, <, >, ' and " for XML element content or attribute values`, `<Name>{{ xmlEscape .name }}</Name>`},
+	"cdata":                    {`cdata(s string) string`, `Wraps s in a CDATA section, splitting any embedded ]]>`, `<Notes>{{ cdata .notes }}</Notes>`},
+	"csvField":                 {`csvField(v any) string`, `Converts v to a string, quoting it as in RFC 4180 when it contains a comma, quote or line break`, `{{ csvField .name }}`},
+	"csvLine":                  {`csvLine(values ...any) string`, `Joins values, or the elements of a single list, into one escaped CSV line`, `{{ range .rows }}{{ csvLine .id .name .total }}{{ "\n" }}{{ end }}`},
+	"tsvLine":                  {`tsvLine(values ...any) string`, `Joins values, or the elements of a single list, into one tab separated line with tabs and line breaks escaped`, `{{ tsvLine .id .name .total }}`},
+	"fixedWidthLine":           {`fixedWidthLine(widths, values list, options ...string) string`, `Pads or truncates each value to its column width in runes, with optional pad character and left or right alignment`, `{{ fixedWidthLine (list 10 8) (list .name .total) "0" "left,right" }}`},
+	"b32enc":                   {`b32enc(v any, alphabet ...string) string`, `Encodes as padded RFC 4648 base32, or unpadded Crockford base32 with the "crockford" alphabet`, `{{ b32enc .id "crockford" }}`},
+	"b32dec":                   {`b32dec(s any, alphabet ...string) string`, `Decodes base32 in the standard or "crockford" alphabet, failing on invalid input`, `{{ b32dec .code "crockford" }}`},
+	"base58enc":                {`base58enc(v any) string`, `Encodes with the Bitcoin base58 alphabet`, `{{ base58enc .walletId }}`},
+	"base58dec":                {`base58dec(s any) string`, `Decodes Bitcoin base58, failing on invalid input`, `{{ base58dec .address }}`},
+	"bitAnd":                   {`bitAnd(a, b any, more ...any) int64`, `Returns the bitwise AND of integers, failing on fractional values`, `{{ bitAnd .flags 6 }}`},
+	"bitOr":                    {`bitOr(a, b any, more ...any) int64`, `Returns the bitwise OR of integers, failing on fractional values`, `{{ bitOr .flags 1 4 }}`},
+	"bitXor":                   {`bitXor(a, b any, more ...any) int64`, `Returns the bitwise XOR of integers, failing on fractional values`, `{{ bitXor .flags 2 }}`},
+	"bitShiftLeft":             {`bitShiftLeft(n, count any) int64`, `Shifts an integer left by 0 to 63 bits`, `{{ bitShiftLeft 1 .bit }}`},
+	"bitShiftRight":            {`bitShiftRight(n, count any) int64`, `Shifts an integer right by 0 to 63 bits, preserving its sign`, `{{ bitShiftRight .flags 4 }}`},
+	"hasBit":                   {`hasBit(mask, flag any) bool`, `Reports whether every bit set in flag is set in mask`, `{{ if hasBit .flags 4 }}priority{{ end }}`},
+	"toOrdinal":                {`toOrdinal(n any) string`, `Formats an integer with its English ordinal suffix, negative numbers keep their sign`, `{{ toOrdinal .attempt }} attempt`},
+	"spellNumber":              {`spellNumber(n any) string`, `Spells an integer in English words, negative numbers are prefixed with "minus"`, `{{ spellNumber .count }} items`},
+	"plural":                   {`plural(n any, singular, plural string) string`, `Returns singular when the count is 1 and plural otherwise`, `{{ .count }} {{ plural .count "item" "items" }}`},
+	"pluralWithCount":          {`pluralWithCount(n any, singular, plural string) string`, `Returns the count followed by the singular or plural word`, `{{ pluralWithCount .count "item" "items" }}`},
+	"pluralize":                {`pluralize(word string) string`, `Returns the English plural of a noun, handling common irregular nouns`, `{{ pluralize .unit }}`},
+	"singularize":              {`singularize(word string) string`, `Returns the English singular of a plural noun, the inverse of pluralize`, `{{ singularize .collection }}`},
+	"initials":                 {`initials(name string, max any) string`, `Returns the upper cased first letters of the first max words of a name, all words when max is 0`, `{{ initials .user.name 2 }}`},
+	"nameParts":                {`nameParts(name string) map`, `Splits a full name into first, middle and last, also accepting "Last, First Middle"`, `Hi {{ (nameParts .user.name).first }}`},
+	"levenshtein":              {`levenshtein(a, b string) int`, `Returns the edit distance in runes between two strings of up to 1024 runes`, `{{ if le (levenshtein .descriptor "ACME CORP") 2 }}acme{{ end }}`},
+	"similarity":               {`similarity(a, b string) float64`, `Returns 1 minus the edit distance divided by the longer length, from 0 to 1`, `{{ if ge (similarity .descriptor "ACME CORP") 0.8 }}acme{{ end }}`},
+	"jaroWinkler":              {`jaroWinkler(a, b string) float64`, `Returns the Jaro-Winkler similarity from 0 to 1, favoring strings sharing a prefix`, `{{ if ge (jaroWinkler .descriptor "ACME CORP") 0.9 }}acme{{ end }}`},
+	"soundex":                  {`soundex(s string) string`, `Returns the American soundex code, empty when s has no latin letters`, `{{ soundex .last_name }}`},
+	"metaphone":                {`metaphone(s string) string`, `Returns the Metaphone phonetic key, empty when s has no latin letters`, `{{ fingerprint (metaphone .first) (metaphone .last) }}`},
+	"tzOffset":                 {`tzOffset(location string, t any) map`, `Returns the offset ("-05:00"), seconds east of UTC and abbreviation in effect in an IANA time zone at t`, `{{ (tzOffset .customer.timezone .event.time).offset }}`},
+	"isDST":                    {`isDST(location string, t any) bool`, `Reports whether daylight saving time is in effect in an IANA time zone at t`, `{{ if isDST "America/New_York" .event.time }}EDT{{ end }}`},
+	"cronNext":                 {`cronNext(expr string, t any) time.Time`, `Returns the first minute after t matching a 5 field cron expression or alias such as @daily`, `{{ (cronNext .schedule .event.time).Format "2006-01-02T15:04:05Z07:00" }}`},
+	"cronMatches":              {`cronMatches(expr string, t any) bool`, `Reports whether the minute of t matches a 5 field cron expression or alias such as @hourly`, `{{ if cronMatches "0 9 * * mon-fri" .event.time }}business hours{{ end }}`},
+	"semverParse":              {`semverParse(v string) map`, `Returns the major, minor, patch, prerelease, metadata and normalized version of a semantic version`, `{{ (semverParse .app_version).major }}`},
+	"semverCompare":            {`semverCompare(a, b string) int`, `Returns -1, 0 or 1 as semantic version a is lower than, equal to or higher than b`, `{{ if ge (semverCompare .app_version "2.3.0") 0 }}new{{ end }}`},
+	"semverSatisfies":          {`semverSatisfies(constraint, v string) bool`, `Reports whether a semantic version satisfies a constraint using =, !=, >, >=, <, <=, ~ and ^`, `{{ if semverSatisfies ">= 2.3.0" .app_version }}new{{ end }}`},
+	"matchGlob":                {`matchGlob(pattern, s string) bool`, `Reports whether a dot or slash separated string matches a glob, * within a segment and ** across segments`, `{{ if matchGlob "order.*" .event.type }}orders{{ end }}`},
+	"matchAnyGlob":             {`matchAnyGlob(patterns []string, s string) bool`, `Reports whether a string matches any of a list of globs, see matchGlob`, `{{ if matchAnyGlob (list "order.*" "refund.**") .event.type }}billing{{ end }}`},
+	"ipLookup":                 {`ipLookup(ip string) map[string]any`, `Returns the country, asn and org of an IP address from the provider set with SetIPLookupProvider, failing when none is set`, `{{ (ipLookup .request.remoteAddr).country }}`},
+	"binLookup":                {`binLookup(bin string) map[string]any`, `Returns the scheme, type, brand and country of a 6 to 8 digit card BIN, or nil when it is unknown, cached for 24 hours`, `{{ (binLookup (substr 0 6 .card.number)).scheme }}`},
+	"getSecret":                {`getSecret(name string) string`, `Returns a secret from the provider set with SetSecretProvider, redacted from the logged requests of the render`, `{{ $key := getSecret "PARTNER_API_KEY" }}{{ (http "GET" "https://api.example.com/v1/orders" (dict "X-Api-Key" $key)).StatusCode }}`},
+	"sleep":                    {`sleep(d any) string`, `Pauses the execution for a duration, at most 5s per execution by default, returning an empty string`, `{{ sleep "500ms" }}`},
+	"retry":                    {`retry(attempts any, delay any, src string, data any) string`, `Renders a template until its output is neither empty nor "false", waiting between attempts within the sleep limit`, `{{ retry 3 "1s" "{{ with (http \"GET\" .url (dict)).Body | parseJSON }}{{ if eq .status \"done\" }}{{ .id }}{{ end }}{{ end }}" . }}`},
+	"fail":                     {`fail(message any) string`, `Aborts the execution with an AssertionError carrying the message`, `{{ if not (or (eq .currency "USD") (eq .currency "EUR")) }}{{ fail "currency must be USD or EUR" }}{{ end }}`},
+	"assert":                   {`assert(condition any, message any) string`, `Aborts the execution with an AssertionError carrying the message when the condition is false or empty`, `{{ assert (gt (float64 .amount) 0.0) "amount must be positive" }}`},
+	"warn":                     {`warn(message any, value any) any`, `Records a warning of the execution without failing it and returns the value unchanged`, `{{ with .brand }}{{ . }}{{ else }}{{ warn "fallback brand used" "generic" }}{{ end }}`},
+	"currencyInfo":             {`currencyInfo(code string) map`, `Returns the code, numeric code, minor units and name of an ISO 4217 currency, failing when unknown`, `{{ (currencyInfo "JPY").minorUnits }}`},
+	"toMinorUnits":             {`toMinorUnits(code string, amount any) int64`, `Converts an amount in major units to minor units of the currency, failing when it is too precise`, `{{ toMinorUnits .currency .total }}`},
+	"fromMinorUnits":           {`fromMinorUnits(code string, amount any) json.Number`, `Converts an integer amount of minor units of the currency to an exact decimal in major units`, `{{ fromMinorUnits "USD" .amount_cents }}`},
+	"toLower":                  {`toLower(s string) string`, `Converts a string to lower case`, `{{ toLower .name }}`},
+	"fingerprint":              {`fingerprint(parts ...string) string`, `Joins parts with underscores, lowercased with other non letter or digit characters replaced`, `{{ fingerprint .first .last }}`},
+	"fingerprintSep":           {`fingerprintSep(sep string, parts ...string) string`, `fingerprint joining and replacing with sep instead of an underscore`, `{{ fingerprintSep "-" .first .last }}`},
+	"fingerprintTranslit":      {`fingerprintTranslit(parts ...string) string`, `fingerprint with accented latin letters transliterated first`, `{{ fingerprintTranslit .street .city }}`},
+	"transliterate":            {`transliterate(s string) string`, `Replaces accented latin letters with their unaccented equivalents`, `{{ transliterate "Müller" }}`},
+	"normalizeAddress":         {`normalizeAddress(line string) string`, `Puts an address line in canonical form, abbreviating suffixes and directionals`, `{{ normalizeAddress .address1 }}`},
+	"fingerprint_address":      {`fingerprint_address(address, city, state, zip, plus4 any) string`, `Fingerprints an address from its normalized parts`, `{{ fingerprint_address .address1 .city .state .zip .plus4 }}`},
+	"fingerprint_address_map":  {`fingerprint_address_map(address map) string`, `fingerprint_address taking the parts from an address map with conventional keys`, `{{ fingerprint_address_map .billing_address }}`},
+	"dict":                     {`dict(key, value ...any) map`, `Builds a map from alternating keys and values`, `{{ dict "id" .id "name" .name }}`},
+	"dictStr":                  {`dictStr(key, value ...any) map`, `Builds a string keyed map from alternating keys and values`, `{{ toJSON (dictStr "id" .id) }}`},
+	"dictFrom":                 {`dictFrom(m map, key, value ...any) map`, `Copies a map and sets the additional keys and values on the copy`, `{{ dictFrom . "page" 2 }}`},
+	"list":                     {`list(items ...any) list`, `Builds a list from its arguments`, `{{ list 1 2 3 }}`},
+	"http":                     {`http(method, url string, headers map) response`, `Makes an HTTP request and returns the response`, `{{ (http "GET" "https://example.com/users/1" (dict)).Body | parseJSON }}`},
+	"http_data":                {`http_data(method, url string, headers map, body string) response`, `Makes an HTTP request with a body and returns the response`, `{{ http_data "POST" "https://example.com/users" (dict "Content-Type" "application/json") (toJSON .user) }}`},
+	"graphql":                  {`graphql(url string, headers map, query string, variables any) any`, `Posts a GraphQL query and returns the data of the response`, `{{ (graphql "https://example.com/graphql" (dict) "query { viewer { id } }" nil).viewer.id }}`},
+	"basicAuth":                {`basicAuth(user, pass string) string`, `Returns a Basic Authorization header value`, `{{ basicAuth .user .pass }}`},
+	"bearerAuth":               {`bearerAuth(token string) string`, `Returns a Bearer Authorization header value`, `{{ bearerAuth .token }}`},
+	"authHeaders":              {`authHeaders(headers map, authorization string) map`, `Copies headers and sets the Authorization header on the copy`, `{{ authHeaders (dict "Accept" "application/json") (bearerAuth .token) }}`},
+	"parseJSON":                {`parseJSON(data string) any`, `Decodes JSON from a string, bytes or reader`, `{{ (parseJSON .payload).id }}`},
+	"jsonMerge":                {`jsonMerge(base, overlay any) any`, `Applies overlay to base as an RFC 7386 JSON merge patch`, `{{ jsonMerge .defaults .overrides | toJSON }}`},
+	"jsonPatch":                {`jsonPatch(doc, patch any) any`, `Applies an RFC 6902 JSON patch to doc`, `{{ jsonPatch .doc .patch | toJSON }}`},
+	"formatTime":               {`formatTime(srcLayout, targetLayout, input string) string`, `Parses a time with one Go time layout and formats it with another`, `{{ formatTime "01/02/2006" "2006-01-02" .date }}`},
+	"formatUnix":               {`formatUnix(targetLayout string, seconds number) string`, `Formats a unix time in the local time zone, deprecated in favor of formatUnixTZ`, `{{ formatUnix "2006-01-02" .created }}`},
+	"formatUnixFull":           {`formatUnixFull(targetLayout string, seconds, nanoseconds number) string`, `Formats a unix time with nanoseconds in the local time zone, deprecated in favor of formatUnixFullTZ`, `{{ formatUnixFull "15:04:05.000" .seconds .nanos }}`},
+	"formatUnixTZ":             {`formatUnixTZ(targetLayout, timezone string, seconds number) string`, `Formats a unix time in the given time zone`, `{{ formatUnixTZ "2006-01-02 15:04" "America/New_York" .created }}`},
+	"formatUnixFullTZ":         {`formatUnixFullTZ(targetLayout, timezone string, seconds, nanoseconds number) string`, `Formats a unix time with nanoseconds in the given time zone`, `{{ formatUnixFullTZ "15:04:05.000" "UTC" .seconds .nanos }}`},
+	"split":                    {`split(sep, s string) list`, `Splits a string on a separator`, `{{ first (split "," .tags) }}`},
+	"first":                    {`first(list any) any`, `Returns the first element of a list, or nil when empty`, `{{ first .items }}`},
+	"last":                     {`last(list any) any`, `Returns the last element of a list, or nil when empty`, `{{ last .items }}`},
+	"coalesce":                 {`coalesce(values ...any) any`, `Returns the first value that isn't nil`, `{{ coalesce .nickname .name }}`},
+	"firstNonEmpty":            {`firstNonEmpty(values ...any) any`, `Returns the first value that isn't nil, empty or zero`, `{{ firstNonEmpty .nickname .name "guest" }}`},
+	"sortMap":                  {`sortMap(list list, key, dir string) list`, `Sorts a list of objects by a key, "asc" or "desc"`, `{{ range sortMap .items "name" "asc" }}{{ .name }}{{ end }}`},
+	"add":                      {`add(a, b number) int`, `Adds two whole numbers`, `{{ add .count 1 }}`},
+	"addInt64":                 {`addInt64(a, b number) int64`, `Adds two whole numbers as 64 bit integers`, `{{ addInt64 .total .amount }}`},
+	"addFloat":                 {`addFloat(a, b number) float64`, `Adds two numbers as floating point`, `{{ addFloat .subtotal .tax }}`},
+	"unquote":                  {`unquote(s string) string`, `Strips a leading and trailing double quote`, `{{ unquote .value }}`},
+	"unquoteJSON":              {`unquoteJSON(s string) string`, `Decodes a JSON quoted string, processing escapes`, `{{ unquoteJSON .value }}`},
+	"escapeJSON":               {`escapeJSON(s string) string`, `Escapes a string for use between the quotes of a JSON string`, `{"note":"{{ escapeJSON .note }}"}`},
+	"escapeJSONQuoted":         {`escapeJSONQuoted(s string) string`, `Returns a string as a quoted JSON string`, `{"note":{{ escapeJSONQuoted .note }}}`},
+	"getAuthXBearerToken":      {`getAuthXBearerToken(authxURL, authxToken, authorizationID string) string`, `Fetches a bearer token from authx, cached until shortly before it expires`, `{{ getAuthXBearerToken .authx_url .authx_token .authorization_id }}`},
+	"getAuthXBearerTokenFresh": {`getAuthXBearerTokenFresh(authxURL, authxToken, authorizationID string) string`, `Fetches a bearer token from authx, replacing any cached token`, `{{ getAuthXBearerTokenFresh .authx_url .authx_token .authorization_id }}`},
+	"cacheSet":                 {`cacheSet(key string, value any, expire duration) any`, `Caches a value for a duration and returns it`, `{{ cacheSet "token" .token "1h" }}`},
+	"cacheGet":                 {`cacheGet(key string) any`, `Returns a cached value, or nil when absent`, `{{ cacheGet "token" }}`},
+	"cacheIncr":                {`cacheIncr(key string, delta number, expire duration) int64`, `Adds delta to a cached counter and returns the new value, expire is set when the counter is created`, `{{ cacheIncr "attempts" 1 "1h" }}`},
+	"cacheDecr":                {`cacheDecr(key string, delta number, expire duration) int64`, `Subtracts delta from a cached counter and returns the new value`, `{{ cacheDecr "remaining" 1 "1h" }}`},
+	"cacheHas":                 {`cacheHas(key string) bool`, `Reports whether a key is cached`, `{{ if cacheHas "token" }}cached{{ end }}`},
+	"cacheDelete":              {`cacheDelete(key string) bool`, `Removes a cached key and reports whether it was present`, `{{ cacheDelete "token" }}`},
+	"cacheGetOrSet":            {`cacheGetOrSet(key string, expire duration, src string, data any) any`, `Returns a cached value, otherwise renders src with data and caches the output`, `{{ cacheGetOrSet "token" "1h" "{{ getAuthXBearerToken .url .token .id }}" . }}`},
+	"parseCIDR":                {`parseCIDR(cidr string) network`, `Parses a CIDR network, which can test addresses with Contains`, `{{ if (parseCIDR "10.0.0.0/8").Contains .ip }}internal{{ end }}`},
+	"signPayload":              {`signPayload(secret, body string) string`, `Returns a "t=<unix>,v1=<hex hmac>" webhook signature header for the current time`, `{{ signPayload .secret (toJSON .event) }}`},
+	"signPayloadAt":            {`signPayloadAt(secret, body string, timestamp int) string`, `signPayload at the given unix timestamp`, `{{ signPayloadAt .secret .body 1700000000 }}`},
+	"verifyPayloadSignature":   {`verifyPayloadSignature(secret, body, header string, tolerance duration) bool`, `Reports whether a signPayload header is valid for body and recent enough`, `{{ if verifyPayloadSignature .secret .body .signature "5m" }}ok{{ end }}`},
+	"toApproxBigDuration":      {`toApproxBigDuration(value any) duration`, `Converts a number of seconds or a duration string such as "1d" to a duration`, `{{ (toApproxBigDuration "36h").Pretty }}`},
+	"int":                      {`int(value any) int`, `Converts a value to an int`, `{{ int "42" }}`},
+	"int64":                    {`int64(value any) int64`, `Converts a value to an int64`, `{{ int64 .id }}`},
+	"float64":                  {`float64(value any) float64`, `Converts a value to a float64`, `{{ float64 "1.5" }}`},
+	"atoi":                     {`atoi(s string) int`, `Parses a decimal integer string, zero when invalid`, `{{ atoi .count }}`},
+	"b64dec":                   {`b64dec(s string) string`, `Decodes standard base64`, `{{ b64dec .payload }}`},
+	"b64enc":                   {`b64enc(s string) string`, `Encodes a string as standard base64`, `{{ b64enc .payload }}`},
+	"ternary":                  {`ternary(ifTrue, ifFalse any, condition bool) any`, `Returns ifTrue when condition is true, otherwise ifFalse`, `{{ ternary "yes" "no" .enabled }}`},
+	"sha1sum":                  {`sha1sum(s string) string`, `Returns the hex encoded SHA-1 hash of a string`, `{{ sha1sum .email }}`},
+	"sha256sum":                {`sha256sum(s string) string`, `Returns the hex encoded SHA-256 hash of a string`, `{{ sha256sum .email }}`},
+	"encryptAES":               {`encryptAES(password, plaintext string) string`, `Encrypts with AES-256 CBC, returning base64`, `{{ encryptAES .key .card }}`},
+	"decryptAES":               {`decryptAES(password, ciphertext string) string`, `Decrypts base64 output of encryptAES`, `{{ decryptAES .key .encrypted }}`},
+	"nospace":                  {`nospace(s string) string`, `Removes all whitespace`, `{{ nospace .phone }}`},
+	"substr":                   {`substr(start, end int, s string) string`, `Returns the bytes of s from start up to end`, `{{ substr 0 4 .card }}`},
+	"regexMatch":               {`regexMatch(regex, s string) bool`, `Reports whether s matches a regular expression`, `{{ if regexMatch "^[0-9]+$" .zip }}numeric{{ end }}`},
+	"regexReplaceAll":          {`regexReplaceAll(regex, s, replacement string) string`, `Replaces matches of a regular expression`, `{{ regexReplaceAll "[^0-9]" .phone "" }}`},
+	"parseTime":                {`parseTime(s string) time`, `Parses a time in any of the common layouts`, `{{ (parseTime .created).Unix }}`},
+	"maybeParseTime":           {`maybeParseTime(s string) time`, `parseTime returning nil for unparseable input`, `{{ with maybeParseTime .created }}{{ .Unix }}{{ end }}`},
+	"formatAnyTime":            {`formatAnyTime(targetLayout string, input any) string`, `Formats a time string, unix timestamp in seconds or milliseconds or time`, `{{ formatAnyTime "2006-01-02" .created }}`},
+	"maybeFormatAnyTime":       {`maybeFormatAnyTime(targetLayout string, input any) string`, `formatAnyTime returning an empty string for empty or unparseable input`, `{{ maybeFormatAnyTime "2006-01-02" .created }}`},
+	"left":                     {`left(s string, n int) string`, `Returns the first n bytes of s`, `{{ left .card 6 }}`},
+	"right":                    {`right(s string, n int) string`, `Returns the last n bytes of s`, `{{ right .card 4 }}`},
+	"toAmount":                 {`toAmount(value any) amount`, `Parses a currency amount`, `{{ (toAmount .total).String }}`},
+	"onlyDigits":               {`onlyDigits(s string) string`, `Removes everything except ASCII digits`, `{{ onlyDigits .phone }}`},
+	"onlyAlpha":                {`onlyAlpha(s string) string`, `Removes everything except ASCII letters`, `{{ onlyAlpha .name }}`},
+	"onlyAlphaUnicode":         {`onlyAlphaUnicode(s string) string`, `Removes everything except letters in any script`, `{{ onlyAlphaUnicode .name }}`},
+	"onlyAlnum":                {`onlyAlnum(s string) string`, `Removes everything except ASCII letters and digits`, `{{ onlyAlnum .reference }}`},
+	"onlyAlnumUnicode":         {`onlyAlnumUnicode(s string) string`, `Removes everything except letters and digits in any script`, `{{ onlyAlnumUnicode .reference }}`},
+	"keepChars":                {`keepChars(allowed, s string) string`, `Removes every character not in allowed`, `{{ keepChars "0123456789+" .phone }}`},
+	"joseSign":                 {`joseSign(payload, jwk string, alg string) string`, `Signs a payload with a JSON web key, returning a compact JWS`, `{{ joseSign (toJSON .claims) .jwk "RS256" }}`},
+	"joseVerifySignature":      {`joseVerifySignature(jws, jwk string) string`, `Verifies a compact JWS with a JSON web key and returns its payload`, `{{ joseVerifySignature .token .jwk }}`},
+	"joseEncrypt":              {`joseEncrypt(payload, jwk string, enc, alg string) string`, `Encrypts a payload for a JSON web key, returning a compact JWE`, `{{ joseEncrypt .card .jwk "A256GCM" "RSA-OAEP-256" }}`},
+	"joseDecrypt":              {`joseDecrypt(jwe, jwk string) string`, `Decrypts a compact JWE with a JSON web key`, `{{ joseDecrypt .encrypted .jwk }}`},
+	"gcloud_storage_get":       {`gcloud_storage_get(bucket, object string) string`, `Reads an object from Google Cloud Storage`, `{{ gcloud_storage_get "config" "mapping.json" | parseJSON }}`},
+	"nilSafeIndex":             {`nilSafeIndex(m map, key string) any`, `Indexes a map, returning nil when the map is nil or not a map`, `{{ nilSafeIndex .metadata "source" }}`},
+	"UNSAFE_render":            {`UNSAFE_render(filename string, data any) string`, `Renders a template file, only when enabled with AllowUnsafeRender`, `{{ UNSAFE_render "templates/body.tmpl" . }}`},
+	"include":                  {`include(name string, data any) string`, `Renders a loaded partial chosen by name at runtime`, `{{ include (printf "%s.tmpl" .method) . }}`},
+	"tryRender":                {`tryRender(src string, data, fallback any) any`, `Renders src with data, returning fallback if it fails`, `{{ tryRender "{{ (parseJSON .raw).id }}" . "unknown" }}`},
+	"counterNext":              {`counterNext(name string) int64`, `Increments the named counter of the current render, starting at 1, and returns it`, `{{ range .rows }}{{ counterNext "line" }},{{ .id }}{{ end }}`},
+	"counterValue":             {`counterValue(name string) int64`, `Returns the named counter of the current render without incrementing it, 0 before counterNext`, `TRAILER,{{ counterValue "line" }}`},
+	"counterReset":             {`counterReset(name string) string`, `Resets the named counter of the current render so counterNext returns 1 again`, `{{ counterReset "line" }}`},
+	"accumAdd":                 {`accumAdd(name string, v any) json.Number`, `Adds a number exactly to the named accumulator of the current render and returns the running total`, `{{ range .items }}{{ $_ := accumAdd "total" .amount }}{{ end }}`},
+	"accumGet":                 {`accumGet(name string) json.Number`, `Returns the total of the named accumulator of the current render, 0 when empty`, `TRAILER,{{ accumGet "total" }}`},
+	"memo":                     {`memo(key, src string, data any) string`, `Renders src with data once per render, returning the first result for later calls with the same key`, `{{ memo "customer" "{{ (http \"GET\" .customer_url (dict)).Body }}" . }}`},
+	"ctxValue":                 {`ctxValue(name string) any`, `Returns a value from the execution context by its configured name`, `{{ ctxValue "requestID" }}`},
+}
@@ -0,0 +1,123 @@
+package template
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"unicode"
+)
+
+// RegisterOption modifies how RegisterFunc and RegisterFuncs treat the functions being registered
+type RegisterOption func(*registerOptions)
+
+type registerOptions struct {
+	override bool
+	doc      *FuncDoc
+}
+
+// Override allows replacing a function that is already registered, including the text/template builtins
+var Override RegisterOption = func(o *registerOptions) {
+	o.override = true
+}
+
+// WithDoc documents the registered function for FuncDocs
+// Passed to RegisterFuncs, every function registered gets the doc
+func WithDoc(doc FuncDoc) RegisterOption {
+	return func(o *registerOptions) {
+		o.doc = &doc
+	}
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// registeredFuncs are the names of the functions registered with RegisterFunc and RegisterFuncs
+// Renders leave them to the registered function rather than binding their own, as they do for http.
+var registeredFuncs = map[string]bool{}
+
+// RegisterFunc adds a function callable from templates as name
+// fn must be a func returning one value, or two values where the second is an error.
+// Registering a name that is already registered is an error unless the Override option is passed.
+// Templates parsed before registration keep the functions they were parsed with; only later calls to
+// Parse, Interpolate and the partial loaders see the new function.
+func RegisterFunc(name string, fn interface{}, opts ...RegisterOption) error {
+	return RegisterFuncs(map[string]interface{}{name: fn}, opts...)
+}
+
+// RegisterFuncs adds each of the functions in funcs as with RegisterFunc
+// Nothing is registered if any of the functions is invalid or collides with a registered function
+func RegisterFuncs(funcs map[string]interface{}, opts ...RegisterOption) error {
+	var options registerOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	var names = make([]string, 0, len(funcs))
+	for name := range funcs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := checkFunc(name, funcs[name]); err != nil {
+			return err
+		}
+		if _, ok := TemplateFuncs[name]; (ok || builtinFuncs[name]) && !options.override {
+			return fmt.Errorf("template func %q is already registered", name)
+		}
+	}
+	for name, fn := range funcs {
+		TemplateFuncs[name] = fn
+		registeredFuncs[name] = true
+		// A context function replaced by a plain one is no longer bound to the execution context
+		delete(contextFuncs, name)
+		if options.doc != nil {
+			funcDocs[name] = *options.doc
+		}
+	}
+	RootTemplate.Funcs(TemplateFuncs)
+	return nil
+}
+
+// checkFunc validates name and fn against the rules text/template applies to a FuncMap
+func checkFunc(name string, fn interface{}) error {
+	if name == "" {
+		return fmt.Errorf("template func name must not be empty")
+	}
+	for i, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return fmt.Errorf("template func name %q is not a valid identifier", name)
+		}
+	}
+	var v = reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return fmt.Errorf("template func %q is a %T, not a function", name, fn)
+	}
+	var t = v.Type()
+	switch {
+	case t.NumOut() == 1:
+	case t.NumOut() == 2 && t.Out(1) == errorType:
+	default:
+		return fmt.Errorf("template func %q must return one value, or a value and an error", name)
+	}
+	return nil
+}
+
+// EnableSprig adds the named sprig functions, or all of them when no names are given
+// Functions already registered, such as this package's own dict, ge and multiply, take precedence over sprig's
+func EnableSprig(names ...string) error {
+	if len(names) == 0 {
+		for name := range sprigFuncs {
+			names = append(names, name)
+		}
+	}
+	for _, name := range names {
+		if _, ok := sprigFuncs[name]; !ok {
+			return fmt.Errorf("unknown sprig func %q", name)
+		}
+	}
+	for _, name := range names {
+		if _, ok := TemplateFuncs[name]; !ok && !builtinFuncs[name] {
+			TemplateFuncs[name] = sprigFuncs[name]
+		}
+	}
+	RootTemplate.Funcs(TemplateFuncs)
+	return nil
+}
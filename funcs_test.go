@@ -0,0 +1,237 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func restoreTemplateFuncs(t *testing.T) {
+	restoreRootTemplate(t)
+	var funcs = make(map[string]interface{}, len(TemplateFuncs))
+	for k, v := range TemplateFuncs {
+		funcs[k] = v
+	}
+	var ctxFuncs = make(map[string]interface{}, len(contextFuncs))
+	for k, v := range contextFuncs {
+		ctxFuncs[k] = v
+	}
+	var registered = make(map[string]bool, len(registeredFuncs))
+	for k, v := range registeredFuncs {
+		registered[k] = v
+	}
+	var docs = make(map[string]FuncDoc, len(funcDocs))
+	for k, v := range funcDocs {
+		docs[k] = v
+	}
+	t.Cleanup(func() {
+		contextFuncs = ctxFuncs
+		registeredFuncs = registered
+		funcDocs = docs
+		for k := range TemplateFuncs {
+			if _, ok := funcs[k]; !ok {
+				delete(TemplateFuncs, k)
+			}
+		}
+		for k, v := range funcs {
+			TemplateFuncs[k] = v
+		}
+		RootTemplate.Funcs(TemplateFuncs)
+	})
+}
+
+func TestRegisterFunc(t *testing.T) {
+	restoreTemplateFuncs(t)
+	_, err := Parse(`{{ toUpper "x" }}`)
+	if err == nil {
+		t.Errorf(`Expected unregistered func to fail to parse`)
+		return
+	}
+
+	err = RegisterFunc("toUpper", strings.ToUpper)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := Interpolate(nil, `{{ toUpper "x" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "X" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestRegisterFuncParsedTemplate(t *testing.T) {
+	restoreTemplateFuncs(t)
+	tmpl, err := Parse(`{{ toLower "X" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = RegisterFunc("toLower", strings.ToUpper, Override)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	// Already parsed templates keep the funcs they were parsed with
+	res, err := tmpl.ExecuteToString(nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "x" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	res, err = Interpolate(nil, `{{ toLower "x" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "X" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestRegisterFuncErrors(t *testing.T) {
+	restoreTemplateFuncs(t)
+	var cases = map[string]map[string]interface{}{
+		"builtin collision": {"eq": strings.EqualFold},
+		"func collision":    {"dict": strings.ToUpper},
+		"invalid name":      {"to-upper": strings.ToUpper},
+		"not a func":        {"value": "x"},
+		"no results":        {"noop": func() {}},
+		"bad second result": {"pair": func() (string, string) { return "", "" }},
+	}
+	for name, funcs := range cases {
+		err := RegisterFuncs(funcs)
+		if err == nil {
+			t.Errorf(`Expected %s to be rejected`, name)
+		}
+	}
+
+	err := RegisterFuncs(map[string]interface{}{
+		"okFunc":  strings.ToUpper,
+		"badFunc": "x",
+	})
+	if err == nil {
+		t.Errorf(`Expected invalid func to be rejected`)
+	}
+	if _, ok := TemplateFuncs["okFunc"]; ok {
+		t.Errorf(`Expected no funcs to be registered when one is invalid`)
+	}
+}
+
+func TestEnableSprig(t *testing.T) {
+	restoreTemplateFuncs(t)
+	err := EnableSprig("until", "nope")
+	if err == nil {
+		t.Errorf(`Expected unknown sprig func to be rejected`)
+	}
+
+	err = Configure(Config{EnableSprigFull: true})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := Interpolate(map[string]interface{}{"m": map[string]interface{}{"a": 1}}, `{{ range until 3 }}{{ . }}{{ end }} {{ printf "%T" (dict "a" 1) }} {{ hasKey .m "a" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "012 map[interface {}]interface {} true" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestFuncDocsCoverBuiltins(t *testing.T) {
+	var docs = FuncDocs()
+	for _, name := range FuncNames() {
+		doc, ok := docs[name]
+		if !ok {
+			t.Errorf(`Missing FuncDoc for %s`, name)
+			continue
+		}
+		if !strings.HasPrefix(doc.Signature, name+"(") || doc.Description == "" || !strings.Contains(doc.Example, name) {
+			t.Errorf(`Incomplete FuncDoc for %s: %+v`, name, doc)
+		}
+	}
+	for name := range funcDocs {
+		if _, ok := TemplateFuncs[name]; !ok {
+			t.Errorf(`FuncDoc for %s documents a func that doesn't exist`, name)
+		}
+	}
+}
+
+func TestRegisterFuncWithDoc(t *testing.T) {
+	restoreTemplateFuncs(t)
+	var doc = FuncDoc{Signature: "toUpper(s string) string", Description: "Converts a string to upper case", Example: `{{ toUpper "x" }}`}
+	err := RegisterFunc("toUpper", strings.ToUpper, WithDoc(doc))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = RegisterFunc("toTitle", strings.ToTitle)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var names = FuncNames()
+	if !sort.StringsAreSorted(names) {
+		t.Errorf(`Expected sorted names`)
+	}
+	var found int
+	for _, name := range names {
+		if name == "toUpper" || name == "toTitle" {
+			found++
+		}
+	}
+	if found != 2 {
+		t.Errorf(`Expected custom funcs in FuncNames, got %v`, names)
+	}
+	var docs = FuncDocs()
+	if docs["toUpper"] != doc {
+		t.Errorf(`Unexpected doc %+v`, docs["toUpper"])
+	}
+	if _, ok := docs["toTitle"]; ok {
+		t.Errorf(`Expected no doc for toTitle`)
+	}
+}
+
+func TestRegisterFuncOverridesRenderFuncs(t *testing.T) {
+	restoreTemplateFuncs(t)
+	// The replacement shares its code with the builtin http func, only the doer differs
+	err := RegisterFunc("http", httpFunc(func(req *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusTeapot, Body: http.NoBody}, nil
+	}), Override)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = RegisterFunc("ctxValue", func(name string) string {
+		return "custom " + name
+	}, Override)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	tmpl, err := Parse(`{{ (http "GET" "http://example.invalid" (dict)).StatusCode }} {{ ctxValue "traceID" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	err = tmpl.ExecuteContext(context.Background(), &buf, nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != "418 custom traceID" {
+		t.Errorf(`Unexpected result %q`, buf.String())
+	}
+}
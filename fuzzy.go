@@ -0,0 +1,118 @@
+package template
+
+import (
+	"fmt"
+)
+
+// maxFuzzyRunes bounds the length of the strings compared by levenshtein, similarity and jaroWinkler,
+// whose cost grows with the product of the lengths
+const maxFuzzyRunes = 1024
+
+// fuzzyRunes returns the runes of a and b, failing when either is longer than maxFuzzyRunes
+func fuzzyRunes(name, a, b string) ([]rune, []rune, error) {
+	var ra, rb = []rune(a), []rune(b)
+	for _, r := range [][]rune{ra, rb} {
+		if len(r) > maxFuzzyRunes {
+			return nil, nil, fmt.Errorf("%s: input of %d runes exceeds the limit of %d", name, len(r), maxFuzzyRunes)
+		}
+	}
+	return ra, rb, nil
+}
+
+// levenshtein returns the number of single rune insertions, deletions and substitutions that turn a into b
+func levenshtein(a, b string) (int, error) {
+	ra, rb, err := fuzzyRunes("levenshtein", a, b)
+	if err != nil {
+		return 0, err
+	}
+	return levenshteinRunes(ra, rb), nil
+}
+
+func levenshteinRunes(a, b []rune) int {
+	// Distances from a[:i] to each prefix of b, for the previous and current i
+	var prev = make([]int, len(b)+1)
+	var cur = make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			var cost = 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+// similarity returns 1 minus the levenshtein distance divided by the length of the longer string, from 0 for
+// entirely different strings to 1 for equal strings
+func similarity(a, b string) (float64, error) {
+	ra, rb, err := fuzzyRunes("similarity", a, b)
+	if err != nil {
+		return 0, err
+	}
+	var longest = max(len(ra), len(rb))
+	if longest == 0 {
+		return 1, nil
+	}
+	return 1 - float64(levenshteinRunes(ra, rb))/float64(longest), nil
+}
+
+// jaroWinkler returns the Jaro-Winkler similarity of a and b from 0 to 1, which favors strings sharing a prefix
+func jaroWinkler(a, b string) (float64, error) {
+	ra, rb, err := fuzzyRunes("jaroWinkler", a, b)
+	if err != nil {
+		return 0, err
+	}
+	if len(ra) == 0 && len(rb) == 0 {
+		return 1, nil
+	}
+	if len(ra) == 0 || len(rb) == 0 {
+		return 0, nil
+	}
+
+	// Runes match when equal and no further apart than half the longer length, less one
+	var window = max(max(len(ra), len(rb))/2-1, 0)
+	var matchedA = make([]bool, len(ra))
+	var matchedB = make([]bool, len(rb))
+	var matches int
+	for i := range ra {
+		for j := max(0, i-window); j < min(len(rb), i+window+1); j++ {
+			if !matchedB[j] && ra[i] == rb[j] {
+				matchedA[i], matchedB[j] = true, true
+				matches++
+				break
+			}
+		}
+	}
+	if matches == 0 {
+		return 0, nil
+	}
+	// Transpositions are half the matched runes that are out of order
+	var transpositions, j int
+	for i := range ra {
+		if !matchedA[i] {
+			continue
+		}
+		for !matchedB[j] {
+			j++
+		}
+		if ra[i] != rb[j] {
+			transpositions++
+		}
+		j++
+	}
+	var m = float64(matches)
+	var jaro = (m/float64(len(ra)) + m/float64(len(rb)) + (m-float64(transpositions)/2)/m) / 3
+
+	var prefix int
+	for prefix < min(4, len(ra), len(rb)) && ra[prefix] == rb[prefix] {
+		prefix++
+	}
+	return jaro + float64(prefix)*0.1*(1-jaro), nil
+}
@@ -0,0 +1,82 @@
+package template
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestLevenshtein(t *testing.T) {
+	var cases = []struct {
+		a, b     string
+		distance int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"saturday", "sunday", 3},
+		{"ACME CORP", "ACME CORP", 0},
+		{"naïve", "naive", 1},
+		{"日本語", "日本", 1},
+	}
+	for _, c := range cases {
+		res, err := levenshtein(c.a, c.b)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if res != c.distance {
+			t.Errorf(`Unexpected distance %d between %q and %q`, res, c.a, c.b)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	var cases = []struct {
+		a, b        string
+		score, jaro float64
+	}{
+		{"", "", 1, 1},
+		{"abc", "", 0, 0},
+		{"abc", "abc", 1, 1},
+		{"kitten", "sitting", 1 - 3.0/7, 0.746},
+		{"MARTHA", "MARHTA", 1 - 2.0/6, 0.961},
+		{"DWAYNE", "DUANE", 1 - 2.0/6, 0.84},
+		{"DIXON", "DICKSONX", 1 - 4.0/8, 0.813},
+		{"abc", "xyz", 0, 0},
+	}
+	for _, c := range cases {
+		score, err := similarity(c.a, c.b)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if math.Abs(score-c.score) > 1e-9 {
+			t.Errorf(`Unexpected similarity %v between %q and %q`, score, c.a, c.b)
+		}
+		jaro, err := jaroWinkler(c.a, c.b)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if math.Abs(jaro-c.jaro) > 0.001 {
+			t.Errorf(`Unexpected Jaro-Winkler similarity %v between %q and %q`, jaro, c.a, c.b)
+		}
+	}
+}
+
+func TestFuzzyLengthLimit(t *testing.T) {
+	var long = strings.Repeat("é", maxFuzzyRunes+1)
+	for _, src := range []string{`{{ levenshtein .long "a" }}`, `{{ similarity "a" .long }}`, `{{ jaroWinkler .long .long }}`} {
+		_, err := InterpolateStrict(map[string]interface{}{"long": long}, src)
+		if err == nil || !strings.Contains(err.Error(), "exceeds the limit of 1024") {
+			t.Errorf(`Expected the length limit to be enforced for %s, got %v`, src, err)
+		}
+	}
+	_, err := levenshtein(strings.Repeat("é", maxFuzzyRunes), "a")
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,64 @@
+//go:build ignore
+
+// gen_countries generates countries_data.go, the ISO 3166-1 table used by countryLookup, from the CLDR data in golang.org/x/text
+// Run with go generate
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/language/display"
+)
+
+// nameOverrides replaces CLDR display names that aren't the names partners expect
+var nameOverrides = map[string]string{
+	"CD": "Democratic Republic of the Congo",
+	"CG": "Republic of the Congo",
+	"HK": "Hong Kong",
+	"MK": "North Macedonia",
+	"MM": "Myanmar",
+	"MO": "Macao",
+	"PS": "Palestine",
+	"SZ": "Eswatini",
+}
+
+func main() {
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by gen_countries.go; DO NOT EDIT.\n\npackage template\n\n")
+	buf.WriteString("// countries is the ISO 3166-1 table of alpha-2, alpha-3 and numeric codes with English short names\n")
+	buf.WriteString("var countries = []country{\n")
+	var names = display.English.Regions()
+	for a := 'A'; a <= 'Z'; a++ {
+		for b := 'A'; b <= 'Z'; b++ {
+			var code = string([]rune{a, b})
+			r, err := language.ParseRegion(code)
+			if err != nil || r.String() != code || !r.IsCountry() || r.IsPrivateUse() || r.Canonicalize() != r || r.ISO3() == "" || r.M49() == 0 {
+				continue
+			}
+			var name = names.Name(r)
+			if name == "" {
+				continue
+			}
+			if override, ok := nameOverrides[code]; ok {
+				name = override
+			}
+			name = strings.NewReplacer(" & ", " and ", "St. ", "Saint ", "’", "'").Replace(name)
+			fmt.Fprintf(&buf, "\t{%q, %q, \"%03d\", %q},\n", code, r.ISO3(), r.M49(), name)
+		}
+	}
+	buf.WriteString("}\n")
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		log.Fatal(err)
+	}
+	err = os.WriteFile("countries_data.go", src, 0644)
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,135 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// globSeparators are the segment separators * and ? don't match across
+const globSeparators = "./"
+
+// compileGlob translates a glob pattern into an anchored regular expression
+// * matches any run of characters within a segment, ? a single character within a segment, [...] a character
+// class as in path.Match, ** any run of characters across segments, and \ escapes the following character.
+// A **. or **/ also matches nothing, so "order.**.paid" matches "order.paid".
+func compileGlob(pattern string) (*regexp.Regexp, error) {
+	var re strings.Builder
+	re.WriteString(`^`)
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				i++
+				var atSegmentStart = i == 1 || strings.IndexByte(globSeparators, pattern[i-2]) >= 0
+				if atSegmentStart && i+1 < len(pattern) && strings.IndexByte(globSeparators, pattern[i+1]) >= 0 {
+					i++
+					re.WriteString(`(?:.*[./])?`)
+				} else {
+					re.WriteString(`.*`)
+				}
+			} else {
+				re.WriteString(`[^./]*`)
+			}
+		case '?':
+			re.WriteString(`[^./]`)
+		case '\\':
+			if i+1 == len(pattern) {
+				return nil, fmt.Errorf("invalid glob pattern %q: trailing backslash", pattern)
+			}
+			i++
+			var size = globRuneLen(pattern[i:])
+			re.WriteString(regexp.QuoteMeta(pattern[i : i+size]))
+			i += size - 1
+		case '[':
+			var end = i + 1
+			if end < len(pattern) && (pattern[end] == '^' || pattern[end] == '!') {
+				end++
+			}
+			// A ] first in the class is literal
+			if end < len(pattern) && pattern[end] == ']' {
+				end++
+			}
+			for end < len(pattern) && pattern[end] != ']' {
+				if pattern[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(pattern) {
+				return nil, fmt.Errorf("invalid glob pattern %q: unclosed [", pattern)
+			}
+			var class = pattern[i+1 : end]
+			re.WriteByte('[')
+			if class[0] == '^' || class[0] == '!' {
+				re.WriteByte('^')
+				class = class[1:]
+			}
+			for j := 0; j < len(class); j++ {
+				switch class[j] {
+				case '\\':
+					j++
+					var size = globRuneLen(class[j:])
+					re.WriteString(regexp.QuoteMeta(class[j : j+size]))
+					j += size - 1
+				case '-':
+					re.WriteByte('-')
+				default:
+					var size = globRuneLen(class[j:])
+					re.WriteString(regexp.QuoteMeta(class[j : j+size]))
+					j += size - 1
+				}
+			}
+			re.WriteByte(']')
+			i = end
+		default:
+			var size = globRuneLen(pattern[i:])
+			re.WriteString(regexp.QuoteMeta(pattern[i : i+size]))
+			i += size - 1
+		}
+	}
+	re.WriteString(`$`)
+	compiled, err := regexp.Compile(re.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+	}
+	return compiled, nil
+}
+
+// globRuneLen returns the length in bytes of the character at the start of s, so multi-byte characters are quoted whole
+func globRuneLen(s string) int {
+	_, size := utf8.DecodeRuneInString(s)
+	return size
+}
+
+// matchGlob reports whether s matches a glob pattern over dot or slash separated segments, such as "order.*" or "**.paid"
+func matchGlob(pattern, s string) (bool, error) {
+	re, err := compileGlob(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(s), nil
+}
+
+// matchAnyGlob reports whether s matches any of a list of glob patterns, see matchGlob
+// Every pattern is checked to be valid, even after a match
+func matchAnyGlob(patterns interface{}, s string) (bool, error) {
+	var list = interfaceSlice(patterns)
+	if list == nil {
+		return false, fmt.Errorf("matchAnyGlob: patterns must be a list, got %T", patterns)
+	}
+	var matched bool
+	for _, p := range list {
+		pattern, ok := p.(string)
+		if !ok {
+			return false, fmt.Errorf("matchAnyGlob: pattern must be a string, got %T", p)
+		}
+		re, err := compileGlob(pattern)
+		if err != nil {
+			return false, err
+		}
+		matched = matched || re.MatchString(s)
+	}
+	return matched, nil
+}
@@ -0,0 +1,80 @@
+package template
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	var cases = []struct {
+		pattern, s string
+		expected   bool
+	}{
+		{"order.*", "order.paid", true},
+		{"order.*", "order.paid.late", false},
+		{"order.*", "orders.paid", false},
+		{"order.**", "order.paid.late", true},
+		{"order.**", "order", false},
+		{"**.paid", "order.paid", true},
+		{"**.paid", "paid", true},
+		{"**.paid", "order.unpaid", false},
+		{"order.**.paid", "order.paid", true},
+		{"order.**.paid", "order.invoice.line.paid", true},
+		{"order.?aid", "order.paid", true},
+		{"order.?aid", "order..aid", false},
+		{"order.[pr]aid", "order.raid", true},
+		{"order.[^p]aid", "order.paid", false},
+		{"order.[a-c]*", "order.cancelled", true},
+		{"api/v?/users/*", "api/v2/users/42", true},
+		{"api/**", "api/v2/users/42", true},
+		{"api/*", "api/v2/users", false},
+		{`price\*`, "price*", true},
+		{`price\*`, "prices", false},
+		{"a+b(c)", "a+b(c)", true},
+		{"*", "", true},
+		{"café.*", "café.x", true},
+		{"café.?", "café.é", true},
+		{"caf?.x", "café.x", true},
+		{"caf[éè].x", "cafè.x", true},
+		{"caf[^é].x", "café.x", false},
+		{`caf\é.*`, "café.x", true},
+		{"日本/*", "日本/東京", true},
+	}
+	for _, c := range cases {
+		res, err := matchGlob(c.pattern, c.s)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if res != c.expected {
+			t.Errorf(`Unexpected match %v of %q against %q`, res, c.s, c.pattern)
+		}
+	}
+
+	for _, pattern := range []string{"order.[paid", `order\`, "[]"} {
+		_, err := InterpolateStrict(map[string]interface{}{"p": pattern}, `{{ matchGlob .p "order.paid" }}`)
+		if err == nil || !strings.Contains(err.Error(), pattern) {
+			t.Errorf(`Expected an error naming %q, got %v`, pattern, err)
+		}
+	}
+}
+
+func TestMatchAnyGlob(t *testing.T) {
+	var data = map[string]interface{}{"patterns": []string{"order.*", "refund.**"}, "type": "refund.issued.partial"}
+	res, err := InterpolateStrict(data, `{{ matchAnyGlob .patterns .type }} {{ matchAnyGlob (list "order.*") .type }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "true false" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	_, err = matchAnyGlob([]interface{}{"refund.**", "order.[x"}, "refund.issued")
+	if err == nil || !strings.Contains(err.Error(), "order.[x") {
+		t.Errorf(`Expected the invalid pattern to be reported, got %v`, err)
+	}
+	_, err = matchAnyGlob("order.*", "order.paid")
+	if err == nil {
+		t.Errorf(`Expected a non list to fail`)
+	}
+}
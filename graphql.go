@@ -0,0 +1,89 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// graphqlResponse is the standard GraphQL response envelope
+type graphqlResponse struct {
+	Data   interface{} `json:"data"`
+	Errors []struct {
+		Message string        `json:"message"`
+		Path    []interface{} `json:"path"`
+	} `json:"errors"`
+}
+
+// graphql posts a query to a GraphQL endpoint and returns the data of the response
+// Numbers in the response are decoded as json.Number. The first GraphQL error, if any, is returned as an error.
+func graphql(url string, headers map[interface{}]interface{}, query string, variables interface{}) (interface{}, error) {
+	return graphqlDo(doHTTP, url, headers, query, variables)
+}
+
+// graphqlDo is graphql sending the request with do
+func graphqlDo(do httpDoer, url string, headers map[interface{}]interface{}, query string, variables interface{}) (interface{}, error) {
+	vars, err := normalizeJSONValue(variables)
+	if err != nil {
+		return nil, fmt.Errorf("graphql %s: variables: %w", url, err)
+	}
+	requestBody, err := json.Marshal(map[string]interface{}{
+		"query":     query,
+		"variables": vars,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("graphql %s: %w", url, err)
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(requestBody))
+	if err != nil {
+		return nil, fmt.Errorf("graphql %s: %w", url, err)
+	}
+	for k, v := range headers {
+		key, ok := k.(string)
+		if !ok {
+			return nil, fmt.Errorf("graphql %s: header name must be a string, got %T", url, k)
+		}
+		value, err := interfaceToString(v)
+		if err != nil {
+			return nil, fmt.Errorf("graphql %s: header %q: %w", url, key, err)
+		}
+		req.Header.Set(key, value)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := do(req)
+	if err != nil {
+		return nil, fmt.Errorf("graphql %s: %w", url, err)
+	}
+	defer res.Body.Close()
+	body, err := readJSONLimited(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("graphql %s: %w", url, err)
+	}
+	var gqlResponse graphqlResponse
+	decodeErr := checkJSONLimits(body)
+	if decodeErr == nil {
+		dec := json.NewDecoder(bytes.NewReader(body))
+		dec.UseNumber()
+		decodeErr = dec.Decode(&gqlResponse)
+	}
+	if decodeErr == nil && len(gqlResponse.Errors) > 0 {
+		var gqlErr = gqlResponse.Errors[0]
+		if len(gqlErr.Path) > 0 {
+			var path = make([]string, len(gqlErr.Path))
+			for i, p := range gqlErr.Path {
+				path[i] = fmt.Sprint(p)
+			}
+			return nil, fmt.Errorf("graphql %s error at %s: %s", url, strings.Join(path, "."), gqlErr.Message)
+		}
+		return nil, fmt.Errorf("graphql %s error: %s", url, gqlErr.Message)
+	}
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("graphql %s: unexpected status %s", url, res.Status)
+	}
+	if decodeErr != nil {
+		return nil, fmt.Errorf("graphql %s: %w", url, decodeErr)
+	}
+	return gqlResponse.Data, nil
+}
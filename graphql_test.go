@@ -0,0 +1,101 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGraphQL(t *testing.T) {
+	var err error
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string
+			Variables map[string]interface{}
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if r.Header.Get("Authorization") != "Bearer abc" || req.Variables["id"] != "42" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"data":{"user":{"id":"42","balance":12345678901234567890}}}`))
+	}))
+	defer srv.Close()
+
+	var jsondata = []byte(`"{{ $res := graphql .url (dict \"Authorization\" \"Bearer abc\") \"query($id: ID!) { user(id: $id) { id balance } }\" (dict \"id\" \"42\") }}{{ $res.user.id }} {{ $res.user.balance }}"`)
+	var tmpl *Template
+	err = json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{
+		"url": srv.URL,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != "42 12345678901234567890" {
+		t.Errorf(`Unexpected result %q`, buf.String())
+	}
+}
+
+func TestGraphQLError(t *testing.T) {
+	var err error
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":null,"errors":[{"message":"user not found","path":["user",0,"name"]}]}`))
+	}))
+	defer srv.Close()
+
+	var jsondata = []byte(`"{{ graphql .url (dict) \"{ user { name } }\" nil }}"`)
+	var tmpl *Template
+	err = json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{
+		"url": srv.URL,
+	})
+	if err == nil {
+		t.Error("Expected graphql error")
+		return
+	}
+	if !strings.Contains(err.Error(), "user not found") || !strings.Contains(err.Error(), "user.0.name") {
+		t.Errorf(`Unexpected error %q`, err.Error())
+	}
+}
+
+func TestGraphQLHTTPError(t *testing.T) {
+	var err error
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(`bad gateway`))
+	}))
+	defer srv.Close()
+
+	var jsondata = []byte(`"{{ graphql .url (dict) \"{ user { name } }\" (dict) }}"`)
+	var tmpl *Template
+	err = json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{
+		"url": srv.URL,
+	})
+	if err == nil {
+		t.Error("Expected http error")
+		return
+	}
+	if !strings.Contains(err.Error(), "502") {
+		t.Errorf(`Unexpected error %q`, err.Error())
+	}
+}
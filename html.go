@@ -0,0 +1,147 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	htmltemplate "html/template"
+	"strings"
+	"time"
+)
+
+// HTMLTemplate is a wrapper around html/template that implements unmarshalJSON
+// Output is contextually escaped, so data such as user supplied names can't inject markup or script.
+// It has the same funcs and partials as Template, with partials escaped in the context they are called from.
+// Funcs return plain strings, which are always escaped: toJSON, b64dec and UNSAFE_render can't be used to output raw HTML,
+// and toJSON rendered inside a <script> element is escaped again as a JS value.
+// The html, js and urlquery builtins may only be used at the end of a pipeline.
+type HTMLTemplate struct {
+	*htmltemplate.Template
+	// source the template was parsed from, when parsed with ParseHTML or unmarshaled
+	source string
+}
+
+// newHTMLRootTemplate creates an html/template root with the template funcs and the loaded partials
+// html/template can't be cloned once executed, so a new root is built for each template
+func newHTMLRootTemplate() (*htmltemplate.Template, error) {
+	var root = htmltemplate.New(RootTemplate.Name()).Delims(leftDelim, rightDelim).Funcs(TemplateFuncs)
+	for _, ps := range partialSources {
+		if _, err := root.New(ps.name).Parse(ps.src); err != nil {
+			return nil, fmt.Errorf("partial %q: %w", ps.name, err)
+		}
+	}
+	return root, nil
+}
+
+// ParseHTML is a shorthand for html/template.Parse using templatefuncs and the loaded partials
+func ParseHTML(src string) (*HTMLTemplate, error) {
+	t, err := newHTMLRootTemplate()
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = t.Parse(src)
+	if err != nil {
+		return nil, err
+	}
+
+	return &HTMLTemplate{Template: t, source: src}, nil
+}
+
+// InterpolateHTML interpolates an HTML template string with data, escaping the output
+// Like InterpolateStrict it returns an empty string on error, wrapped in an ExecError
+func InterpolateHTML(data interface{}, text string) (res string, err error) {
+	if observer != nil {
+		defer func(start time.Time) {
+			observeExecute("", text, start, err)
+		}(time.Now())
+	}
+	defer recoverPanic(&err)
+
+	t, err := ParseHTML(text)
+	if err != nil {
+		return "", newExecError("", text, err)
+	}
+	var overlay = newRenderOverlay(nil)
+	t.Funcs(renderChainFuncs(nil, overlay))
+
+	var tBuf bytes.Buffer
+	err = t.Execute(limitOutput(&tBuf), data)
+
+	if err == nil && rejectNoValue {
+		err = checkNoValue(tBuf.Bytes())
+	}
+
+	if err != nil {
+		return "", newExecError("", text, redactRenderError(overlay, err))
+	}
+
+	return tBuf.String(), nil
+}
+
+// UnmarshalJSON implementation for HTMLTemplate
+func (t *HTMLTemplate) UnmarshalJSON(data []byte) (err error) {
+	var src string
+	err = json.Unmarshal(data, &src)
+	if err != nil {
+		return err
+	}
+
+	parsed, err := ParseHTML(src)
+	if err != nil {
+		return err
+	}
+
+	t.Template = parsed.Template
+	t.source = src
+	return nil
+}
+
+// ExecuteToString executes the template and returns the result as a string
+func (t *HTMLTemplate) ExecuteToString(data interface{}) (string, error) {
+	var tBuf bytes.Buffer
+	var err = t.Execute(&tBuf, data)
+
+	if err != nil {
+		return "", err
+	}
+
+	return tBuf.String(), nil
+}
+
+// Source returns the source the template was parsed from
+// Templates parsed through the embedded html/template methods return the source reconstructed from the parse tree,
+// which is rewritten with escapers once executed
+func (t *HTMLTemplate) Source() string {
+	if t.source != "" || t.Template == nil || t.Tree == nil {
+		return t.source
+	}
+	return t.Tree.Root.String()
+}
+
+// MarshalJSON implementation for HTMLTemplate, emitting the template source as a string
+func (t HTMLTemplate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Source())
+}
+
+// attrEscaper escapes the characters that can end or break out of an attribute value, quoted or not
+var attrEscaper = strings.NewReplacer(
+	"&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&#34;", "'", "&#39;", "`", "&#96;", "=", "&#61;",
+	" ", "&#32;", "\t", "&#9;", "\n", "&#10;", "\r", "&#13;", "\f", "&#12;",
+)
+
+// htmlEscape escapes <, >, &, ' and " so s can be placed in HTML text in a text/template
+func htmlEscape(s string) string {
+	return html.EscapeString(s)
+}
+
+// htmlUnescape decodes the HTML entities in s, such as &amp; and &#39;
+func htmlUnescape(s string) string {
+	return html.UnescapeString(s)
+}
+
+// attrEscape escapes s for an HTML attribute value, also escaping backticks, = and whitespace so the value is safe unquoted
+func attrEscape(s string) string {
+	return attrEscaper.Replace(s)
+}
@@ -0,0 +1,113 @@
+package template
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestHTMLEscaping(t *testing.T) {
+	var data = map[string]interface{}{
+		"userInput": `<script>alert("x")</script>`,
+	}
+	var src = `<p>{{ .userInput }}</p>`
+
+	res, err := InterpolateHTML(data, src)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `<p>&lt;script&gt;alert(&#34;x&#34;)&lt;/script&gt;</p>` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+
+	res, err = Interpolate(data, src)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `<p><script>alert("x")</script></p>` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestHTMLTemplateUnmarshalJSON(t *testing.T) {
+	restoreRootTemplate(t)
+	err := LoadPartialNamed("html_greeting", `Hi {{ . }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var tmpl *HTMLTemplate
+	err = json.Unmarshal([]byte(`"<a href=\"/u?name={{ .name }}\">{{ template \"html_greeting\" .name }}</a>"`), &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := tmpl.ExecuteToString(map[string]interface{}{"name": "a&b <c>"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `<a href="/u?name=a%26b%20%3cc%3e">Hi a&amp;b &lt;c&gt;</a>` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	// Marshaled after Execute, the source is the original rather than the tree rewritten with escapers
+	out, err := json.Marshal(tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(out) != `"\u003ca href=\"/u?name={{ .name }}\"\u003e{{ template \"html_greeting\" .name }}\u003c/a\u003e"` {
+		t.Errorf(`Unexpected JSON %s`, out)
+	}
+	var roundTrip *HTMLTemplate
+	err = json.Unmarshal(out, &roundTrip)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err = roundTrip.ExecuteToString(map[string]interface{}{"name": "a&b <c>"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `<a href="/u?name=a%26b%20%3cc%3e">Hi a&amp;b &lt;c&gt;</a>` {
+		t.Errorf(`Unexpected round trip result %q`, res)
+	}
+
+	out, err = json.Marshal(HTMLTemplate{})
+	if err != nil || string(out) != `""` {
+		t.Errorf(`Unexpected JSON for the zero value %s, %v`, out, err)
+	}
+}
+
+func TestHTMLEscapeFuncs(t *testing.T) {
+	for s, expected := range map[string]string{
+		`<b>Tom & Jerry's "show"</b>`: `&lt;b&gt;Tom &amp; Jerry&#39;s &#34;show&#34;&lt;/b&gt;`,
+		`Zoë 日本 ☺`:                    `Zoë 日本 ☺`,
+		``:                            ``,
+	} {
+		if res := htmlEscape(s); res != expected {
+			t.Errorf(`Unexpected result %q for %q`, res, s)
+		}
+		if res := htmlUnescape(htmlEscape(s)); res != s {
+			t.Errorf(`Unexpected round trip %q for %q`, res, s)
+		}
+	}
+	if res := htmlUnescape(`caf&eacute; &#39;x&#39; &amp;amp; &unknown;`); res != `café 'x' &amp; &unknown;` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	if res := attrEscape("a\"b' c=`d`<é>"); res != `a&#34;b&#39;&#32;c&#61;&#96;d&#96;&lt;é&gt;` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+
+	res, err := InterpolateStrict(map[string]interface{}{"name": `O'Brien <admin>`}, `<td title={{ attrEscape .name }}>{{ htmlEscape .name }}</td>`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `<td title=O&#39;Brien&#32;&lt;admin&gt;>O&#39;Brien &lt;admin&gt;</td>` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
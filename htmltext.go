@@ -0,0 +1,144 @@
+package template
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+)
+
+// htmlDroppedElements are elements whose contents are never text
+var htmlDroppedElements = map[string]bool{"script": true, "style": true, "template": true, "title": true}
+
+// htmlBlockBreaks is the number of line breaks htmlToText puts around block elements
+var htmlBlockBreaks = map[string]int{
+	"p": 2, "h1": 2, "h2": 2, "h3": 2, "h4": 2, "h5": 2, "h6": 2, "blockquote": 2, "pre": 2, "table": 2,
+	"div": 1, "ul": 1, "ol": 1, "li": 1, "tr": 1, "dl": 1, "dt": 1, "dd": 1, "hr": 1,
+	"section": 1, "article": 1, "header": 1, "footer": 1, "nav": 1, "aside": 1, "main": 1, "form": 1,
+}
+
+// htmlTokens calls fn with each tag and decoded text token of s outside the dropped elements
+// Malformed markup is handled as browsers would, e.g. unclosed tags are left open and stray < is text.
+func htmlTokens(s string, fn func(tt html.TokenType, tag string, text string)) {
+	var z = html.NewTokenizer(strings.NewReader(s))
+	var dropping string
+	for {
+		var tt = z.Next()
+		switch tt {
+		case html.ErrorToken:
+			return
+		case html.TextToken:
+			if dropping == "" {
+				fn(tt, "", string(z.Text()))
+			}
+		case html.StartTagToken, html.EndTagToken, html.SelfClosingTagToken:
+			name, _ := z.TagName()
+			var tag = string(name)
+			if dropping != "" {
+				if tt == html.EndTagToken && tag == dropping {
+					dropping = ""
+				}
+				continue
+			}
+			if tt == html.StartTagToken && htmlDroppedElements[tag] {
+				dropping = tag
+				continue
+			}
+			fn(tt, tag, "")
+		}
+	}
+}
+
+// stripTags removes the tags, comments and script and style contents of an HTML fragment and decodes entities
+func stripTags(s string) string {
+	var b strings.Builder
+	htmlTokens(s, func(tt html.TokenType, tag string, text string) {
+		b.WriteString(text)
+	})
+	return b.String()
+}
+
+// htmlText accumulates the plain text rendering of HTML, collapsing whitespace and line breaks
+type htmlText struct {
+	b      strings.Builder
+	breaks int
+	space  bool
+	prefix string
+}
+
+func (t *htmlText) lineBreak(n int) {
+	if t.breaks < n {
+		t.breaks = n
+	}
+}
+
+// write writes text preceded by any pending line breaks, list bullet or space
+func (t *htmlText) write(text string) {
+	if t.b.Len() > 0 {
+		if t.breaks > 0 {
+			t.b.WriteString(strings.Repeat("\n", min(t.breaks, 2)))
+		} else if t.space {
+			t.b.WriteByte(' ')
+		}
+	}
+	t.b.WriteString(t.prefix)
+	t.b.WriteString(text)
+	t.breaks, t.space, t.prefix = 0, false, ""
+}
+
+// htmlToText converts an HTML fragment to plain text, dropping tags and script and style contents and decoding entities
+// Whitespace is collapsed as in a browser, <br> and block elements such as <p> start new lines, and list items are
+// bulleted with "- ", indented by nesting level. The contents of <pre> are kept verbatim.
+func htmlToText(s string) string {
+	var t htmlText
+	var lists, pre int
+	htmlTokens(s, func(tt html.TokenType, tag string, text string) {
+		switch {
+		case tt == html.TextToken && pre > 0:
+			t.write(text)
+		case tt == html.TextToken:
+			var words = strings.Fields(text)
+			if len(words) == 0 {
+				t.space = t.space || text != ""
+				return
+			}
+			first, _ := utf8.DecodeRuneInString(text)
+			last, _ := utf8.DecodeLastRuneInString(text)
+			t.space = t.space || unicode.IsSpace(first)
+			t.write(strings.Join(words, " "))
+			t.space = unicode.IsSpace(last)
+		case tag == "br":
+			if t.b.Len() > 0 {
+				t.breaks++
+			}
+		case tag == "li" && tt == html.StartTagToken:
+			t.lineBreak(1)
+			t.prefix = strings.Repeat("  ", max(lists-1, 0)) + "- "
+		case tag == "td" || tag == "th":
+			t.space = true
+		default:
+			if tag == "ul" || tag == "ol" {
+				if tt == html.StartTagToken {
+					lists++
+				} else if tt == html.EndTagToken && lists > 0 {
+					lists--
+				}
+			}
+			if tag == "pre" {
+				if tt == html.StartTagToken {
+					pre++
+				} else if tt == html.EndTagToken && pre > 0 {
+					pre--
+				}
+			}
+			if n, ok := htmlBlockBreaks[tag]; ok {
+				if lists > 0 && n > 1 {
+					n = 1
+				}
+				t.lineBreak(n)
+			}
+		}
+	})
+	return t.b.String()
+}
@@ -0,0 +1,52 @@
+package template
+
+import (
+	"testing"
+)
+
+func TestStripTags(t *testing.T) {
+	for src, expected := range map[string]string{
+		`<p>Hello <b>world</b></p>`:                           `Hello world`,
+		`<div><span>Tom &amp; Jerry&#39;s</span> &lt;3</div>`: `Tom & Jerry's <3`,
+		`a<script>alert("x")</script>b<style>p{}</style>c`:    `abc`,
+		`<!-- note -->visible`:                                `visible`,
+		`<b>bold <i>italic</b> unclosed`:                      `bold italic unclosed`,
+		`1 < 2 and <a href="x">link`:                          `1 < 2 and link`,
+		`Caf&eacute; &#x263A;`:                                `Café ☺`,
+		`plain text`:                                          `plain text`,
+	} {
+		if res := stripTags(src); res != expected {
+			t.Errorf(`Unexpected result %q for %q`, res, src)
+		}
+	}
+}
+
+func TestHTMLToText(t *testing.T) {
+	for src, expected := range map[string]string{
+		`<p>Hello   <b>world</b></p><p>Second
+			paragraph</p>`: "Hello world\n\nSecond paragraph",
+		`Line one<br>Line two<br/><br/>Line four`:                                 "Line one\nLine two\n\nLine four",
+		`<p>Items:</p><ul><li>One</li><li>Two &amp; <i>three</i></li></ul>`:       "Items:\n\n- One\n- Two & three",
+		`<ol><li>Outer<ul><li>Inner</li></ul></li><li>Next</li></ol>`:             "- Outer\n  - Inner\n- Next",
+		`<div>Ticket <script>var x = "<p>";</script>#42</div><style>.a{}</style>`: "Ticket #42",
+		`<h1>Title</h1>Body text`:                                                 "Title\n\nBody text",
+		`<div><p>Unclosed paragraph<div>Next block`:                               "Unclosed paragraph\nNext block",
+		`<pre>  keep
+  spacing</pre>after`: "  keep\n  spacing\n\nafter",
+		`<table><tr><td>a</td><td>b</td></tr><tr><td>c</td></tr></table>`: "a b\nc",
+		`   `: "",
+	} {
+		if res := htmlToText(src); res != expected {
+			t.Errorf(`Unexpected result %q for %q`, res, src)
+		}
+	}
+
+	res, err := InterpolateStrict(map[string]interface{}{"body": `<p>Hi&nbsp;there,</p><p>Your order <b>shipped</b>.</p>`}, `{{ htmlToText .body }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "Hi there,\n\nYour order shipped." {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
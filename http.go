@@ -0,0 +1,234 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// hostLimiter pairs a host pattern with the token bucket enforcing its limit
+type hostLimiter struct {
+	pattern string
+	limiter *rate.Limiter
+}
+
+var httpRateLimitsMu sync.RWMutex
+var httpRateLimiters []hostLimiter
+
+// SetHTTPRateLimits limits the number of requests per second the http template functions may make to matching hosts
+// Keys are host patterns using path.Match syntax (e.g. "api.example.com" or "*.example.com") and are matched against the request hostname
+// Exact host patterns take precedence over wildcard patterns, which are tried in lexical order
+// Limits are shared by all template executions in the process. Passing nil or an empty map removes all limits.
+func SetHTTPRateLimits(limits map[string]float64) error {
+	var patterns = make([]string, 0, len(limits))
+	for pattern := range limits {
+		patterns = append(patterns, pattern)
+	}
+	sort.Slice(patterns, func(i, j int) bool {
+		iWild, jWild := strings.ContainsAny(patterns[i], `*?[\`), strings.ContainsAny(patterns[j], `*?[\`)
+		if iWild != jWild {
+			return jWild
+		}
+		return patterns[i] < patterns[j]
+	})
+	var limiters = make([]hostLimiter, 0, len(limits))
+	for _, pattern := range patterns {
+		var rps = limits[pattern]
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid http rate limit host pattern %q: %w", pattern, err)
+		}
+		if rps <= 0 {
+			return fmt.Errorf("invalid http rate limit for host pattern %q: must be greater than 0", pattern)
+		}
+		limiters = append(limiters, hostLimiter{
+			pattern: pattern,
+			limiter: rate.NewLimiter(rate.Limit(rps), 1),
+		})
+	}
+	httpRateLimitsMu.Lock()
+	httpRateLimiters = limiters
+	httpRateLimitsMu.Unlock()
+	return nil
+}
+
+// httpRateLimiterFor returns the limiter for the first pattern matching host, or nil if the host is not limited
+func httpRateLimiterFor(host string) *rate.Limiter {
+	httpRateLimitsMu.RLock()
+	defer httpRateLimitsMu.RUnlock()
+	for _, hl := range httpRateLimiters {
+		if ok, _ := path.Match(hl.pattern, host); ok {
+			return hl.limiter
+		}
+	}
+	return nil
+}
+
+var maxHTTPResponseBytes int64
+
+// SetMaxHTTPResponseBytes limits the size of response bodies read by the http template functions
+// Reading past the limit fails with an HTTPResponseLimitError. Zero, the default, is unlimited.
+func SetMaxHTTPResponseBytes(n int64) {
+	maxHTTPResponseBytes = n
+}
+
+// HTTPResponseLimitError is returned when reading a response body larger than the configured maximum
+type HTTPResponseLimitError struct {
+	URL   string
+	Limit int64
+}
+
+// Error implementation for HTTPResponseLimitError
+func (e *HTTPResponseLimitError) Error() string {
+	return fmt.Sprintf("http response from %s exceeds the limit of %d bytes", e.URL, e.Limit)
+}
+
+// limitedBody fails reads of a response body once more than its limit has been read
+type limitedBody struct {
+	io.ReadCloser
+	err       *HTTPResponseLimitError
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	if int64(n) > b.remaining {
+		n = int(b.remaining)
+		b.remaining = 0
+		return n, b.err
+	}
+	b.remaining -= int64(n)
+	return n, err
+}
+
+// HTTPLogFunc is called after each request made by a template function
+// header is a copy of the request headers with sensitive values redacted, status is zero when no response was received
+type HTTPLogFunc func(ctx context.Context, method, url string, header http.Header, status int, duration time.Duration, err error)
+
+var httpLogger HTTPLogFunc
+var httpLogRedact = DefaultHTTPLogRedact
+
+// SetHTTPLogger sets the function logging requests made by the http, http_data, graphql and authx template functions
+// redact replaces header values before they are logged, nil uses DefaultHTTPLogRedact. A nil logger disables logging.
+func SetHTTPLogger(logger HTTPLogFunc, redact func(name, value string) string) {
+	if redact == nil {
+		redact = DefaultHTTPLogRedact
+	}
+	httpLogger = logger
+	httpLogRedact = redact
+}
+
+// DefaultHTTPLogRedact redacts credential headers such as Authorization, Cookie and API keys
+func DefaultHTTPLogRedact(name, value string) string {
+	switch strings.ToLower(name) {
+	case "authorization", "proxy-authorization", "cookie", "x-api-key", "x-auth-token":
+		return "REDACTED"
+	}
+	return value
+}
+
+// redactHeaders copies header applying the log redaction and redact to every value
+func redactHeaders(header http.Header, redact func(string) string) http.Header {
+	var redacted = make(http.Header, len(header))
+	for name, values := range header {
+		var copied = make([]string, len(values))
+		for i, v := range values {
+			copied[i] = redact(httpLogRedact(name, v))
+		}
+		redacted[name] = copied
+	}
+	return redacted
+}
+
+// httpDoer sends a request made by a template function, doHTTP unless the execution stubs the network
+type httpDoer func(req *http.Request) (*http.Response, error)
+
+// httpFunc returns the http template function sending requests with do
+func httpFunc(do httpDoer) func(method, url string, headers map[interface{}]interface{}) (*http.Response, error) {
+	return func(method, url string, headers map[interface{}]interface{}) (*http.Response, error) {
+		var req *http.Request
+		var err error
+		req, err = http.NewRequest(method, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		err = setHeaders(req, headers)
+		if err != nil {
+			return nil, err
+		}
+		return do(req)
+	}
+}
+
+// httpDataFunc returns the http_data template function sending requests with do
+func httpDataFunc(do httpDoer) func(method, url string, headers map[interface{}]interface{}, data string) (*http.Response, error) {
+	return func(method, url string, headers map[interface{}]interface{}, data string) (*http.Response, error) {
+		var req *http.Request
+		var err error
+		req, err = http.NewRequest(method, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewBufferString(data))
+
+		err = setHeaders(req, headers)
+		if err != nil {
+			return nil, err
+		}
+
+		return do(req)
+	}
+}
+
+// doHTTP sends a request made by a template function
+// It blocks until the rate limit for the request host allows it, respecting the request context
+func doHTTP(req *http.Request) (*http.Response, error) {
+	if limiter := httpRateLimiterFor(req.URL.Hostname()); limiter != nil {
+		if err := limiter.Wait(req.Context()); err != nil {
+			return nil, fmt.Errorf("http rate limit for %s: %w", req.URL.Hostname(), err)
+		}
+	}
+	var start time.Time
+	var logger = httpLogger
+	if observer != nil || logger != nil {
+		start = time.Now()
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if observer != nil || logger != nil {
+		var status int
+		if resp != nil {
+			status = resp.StatusCode
+		}
+		var duration = time.Since(start)
+		var redact = redactorFrom(req.Context())
+		var url = redact(req.URL.Redacted())
+		if observer != nil {
+			observer.OnHTTPRequest(url, status, duration)
+		}
+		if logger != nil {
+			logger(req.Context(), req.Method, url, redactHeaders(req.Header, redact), status, duration, redactError(err, redact))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if maxHTTPResponseBytes > 0 {
+		resp.Body = &limitedBody{
+			ReadCloser: resp.Body,
+			err:        &HTTPResponseLimitError{URL: req.URL.Redacted(), Limit: maxHTTPResponseBytes},
+			remaining:  maxHTTPResponseBytes,
+		}
+	}
+	return resp, nil
+}
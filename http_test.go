@@ -0,0 +1,229 @@
+package template
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHTTPRateLimit(t *testing.T) {
+	var err error
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	err = SetHTTPRateLimits(map[string]float64{
+		"127.0.0.1": 5,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer SetHTTPRateLimits(nil)
+
+	var jsondata = []byte(`"{{ (http \"GET\" .url (dict)).StatusCode }} {{ (http \"GET\" .url (dict)).StatusCode }}"`)
+	var tmpl *Template
+	err = json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	start := time.Now()
+	err = tmpl.Execute(&buf, map[string]interface{}{
+		"url": srv.URL,
+	})
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != "200 200" {
+		t.Errorf(`Unexpected result %q`, buf.String())
+	}
+	// 5 requests per second spaces the calls by 200ms
+	if elapsed < 150*time.Millisecond {
+		t.Errorf(`Expected rate limited calls to be spaced out, took %s`, elapsed)
+	}
+}
+
+func TestHTTPRateLimitContext(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	err := SetHTTPRateLimits(map[string]float64{
+		"127.0.0.1": 0.5,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer SetHTTPRateLimits(nil)
+
+	tmpl, err := Parse(`{{ (http "GET" .url (dict)).StatusCode }} {{ (http "GET" .url (dict)).StatusCode }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = tmpl.ExecuteContext(ctx, &bytes.Buffer{}, map[string]interface{}{
+		"url": srv.URL,
+	})
+	elapsed := time.Since(start)
+	if err == nil || !strings.Contains(err.Error(), "http rate limit") {
+		t.Errorf(`Expected the rate limit wait to end with the context, got %v`, err)
+	}
+	// 0.5 requests per second would otherwise hold the second call for 2s
+	if elapsed > time.Second {
+		t.Errorf(`Expected the rate limit wait to end with the context, took %s`, elapsed)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	_, err = InterpolateContext(ctx, map[string]interface{}{"url": srv.URL}, `{{ (http "GET" .url (dict)).StatusCode }}`)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf(`Expected a cancelled context to stop the request, got %v`, err)
+	}
+}
+
+func TestHTTPRateLimitUnmatchedHost(t *testing.T) {
+	var err error
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	err = SetHTTPRateLimits(map[string]float64{
+		"*.example.com": 0.1,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer SetHTTPRateLimits(nil)
+
+	var jsondata = []byte(`"{{ (http \"GET\" .url (dict)).StatusCode }} {{ (http \"GET\" .url (dict)).StatusCode }}"`)
+	var tmpl *Template
+	err = json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	start := time.Now()
+	err = tmpl.Execute(&buf, map[string]interface{}{
+		"url": srv.URL,
+	})
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if elapsed > 5*time.Second {
+		t.Errorf(`Unlimited host should not be rate limited, took %s`, elapsed)
+	}
+}
+
+func TestHTTPRateLimitInvalidPattern(t *testing.T) {
+	err := SetHTTPRateLimits(map[string]float64{
+		"[": 1,
+	})
+	if err == nil {
+		t.Fail()
+	}
+	err = SetHTTPRateLimits(map[string]float64{
+		"example.com": 0,
+	})
+	if err == nil {
+		t.Fail()
+	}
+}
+
+func TestMaxHTTPResponseBytes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"data":"` + strings.Repeat("x", 100) + `"}`))
+	}))
+	defer srv.Close()
+
+	SetMaxHTTPResponseBytes(50)
+	defer SetMaxHTTPResponseBytes(0)
+
+	_, err := InterpolateStrict(map[string]interface{}{"url": srv.URL}, `{{ (http "GET" .url (dict)).Body | parseJSON }}`)
+	var limitErr *HTTPResponseLimitError
+	if !errors.As(err, &limitErr) {
+		t.Errorf(`Expected HTTPResponseLimitError, got %v`, err)
+	}
+
+	SetMaxHTTPResponseBytes(200)
+	res, err := InterpolateStrict(map[string]interface{}{"url": srv.URL}, `{{ len ((http "GET" .url (dict)).Body | parseJSON).data }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "100" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+type httpLogEntry struct {
+	method string
+	url    string
+	header http.Header
+	status int
+	err    error
+}
+
+func TestHTTPLogger(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	var entries []httpLogEntry
+	SetHTTPLogger(func(ctx context.Context, method, url string, header http.Header, status int, duration time.Duration, err error) {
+		entries = append(entries, httpLogEntry{method, url, header, status, err})
+	}, nil)
+	defer SetHTTPLogger(nil, nil)
+
+	res, err := InterpolateStrict(map[string]interface{}{"url": srv.URL}, `{{ (http_data "POST" .url (dict "Authorization" (bearerAuth "secret") "X-Id" "1") "{}").StatusCode }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "202" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	_, err = InterpolateStrict(nil, `{{ http "GET" "http://127.0.0.1:0" (dict) }}`)
+	if err == nil {
+		t.Errorf(`Expected request to fail`)
+	}
+
+	if len(entries) != 2 {
+		t.Errorf(`Unexpected log entries %v`, entries)
+		return
+	}
+	if entries[0].method != "POST" || entries[0].url != srv.URL || entries[0].status != 202 || entries[0].err != nil {
+		t.Errorf(`Unexpected log entry %+v`, entries[0])
+	}
+	if entries[0].header.Get("Authorization") != "REDACTED" || entries[0].header.Get("X-Id") != "1" {
+		t.Errorf(`Unexpected logged headers %v`, entries[0].header)
+	}
+	if entries[1].status != 0 || entries[1].err == nil {
+		t.Errorf(`Unexpected log entry %+v`, entries[1])
+	}
+}
@@ -0,0 +1,145 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// IPInfo is the network information ipLookup returns for an IP address
+type IPInfo struct {
+	// ISO 3166-1 alpha-2 code of the country the address is located in
+	Country string `json:"country"`
+	// Number of the autonomous system announcing the address
+	ASN int64 `json:"asn"`
+	// Organization the address is allocated to
+	Org string `json:"org"`
+}
+
+// IPLookupProvider resolves IP addresses for the ipLookup template function
+// Methods may be called concurrently. ExecuteDryRun calls providers other than HTTPIPLookupProvider as usual,
+// so they should not have side effects.
+type IPLookupProvider interface {
+	LookupIP(ip netip.Addr) (IPInfo, error)
+}
+
+var ipLookupProvider IPLookupProvider
+
+// SetIPLookupProvider sets the provider used by ipLookup, nil makes ipLookup fail, which is the default
+func SetIPLookupProvider(p IPLookupProvider) {
+	ipLookupProvider = p
+}
+
+// ipLookupFunc returns the ipLookup template function sending the requests of an HTTPIPLookupProvider with do
+// Dry runs don't use the provider's cache, so their canned answers never replace real ones.
+func ipLookupFunc(do httpDoer, cached bool) func(ip string) (map[string]interface{}, error) {
+	return func(ip string) (map[string]interface{}, error) {
+		return ipLookup(do, cached, ip)
+	}
+}
+
+// ipLookup returns the country, asn and org of an IP address from the configured IPLookupProvider
+func ipLookup(do httpDoer, cached bool, ip string) (map[string]interface{}, error) {
+	var provider = ipLookupProvider
+	if provider == nil {
+		return nil, fmt.Errorf("ipLookup: no IP lookup provider is configured, see SetIPLookupProvider")
+	}
+	addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+	if err != nil {
+		return nil, fmt.Errorf("ipLookup: %w", err)
+	}
+	var info IPInfo
+	if p, ok := provider.(*HTTPIPLookupProvider); ok {
+		info, err = p.lookup(do, cached, addr.Unmap())
+	} else {
+		info, err = provider.LookupIP(addr.Unmap())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ipLookup %s: %w", addr, err)
+	}
+	return map[string]interface{}{
+		"country": info.Country,
+		"asn":     info.ASN,
+		"org":     info.Org,
+	}, nil
+}
+
+// HTTPIPLookupProvider is an IPLookupProvider requesting an HTTP endpoint and caching its answers
+// The endpoint must respond to a GET with a JSON object holding country, asn and org, where asn may be a
+// number or a string such as "AS15169". Requests are subject to the http rate limits and logging.
+type HTTPIPLookupProvider struct {
+	url   string
+	ttl   time.Duration
+	cache *ttlCache
+}
+
+// NewHTTPIPLookupProvider returns a provider requesting url with {ip} replaced by the address,
+// e.g. "https://geo.example.com/v1/{ip}", caching answers for ttl, which defaults to 1 hour
+func NewHTTPIPLookupProvider(url string, ttl time.Duration) *HTTPIPLookupProvider {
+	if ttl <= 0 {
+		ttl = time.Hour
+	}
+	return &HTTPIPLookupProvider{url: url, ttl: ttl, cache: newTTLCache(ttl)}
+}
+
+// LookupIP implementation for HTTPIPLookupProvider
+func (p *HTTPIPLookupProvider) LookupIP(ip netip.Addr) (IPInfo, error) {
+	return p.lookup(doHTTP, true, ip)
+}
+
+// lookup is LookupIP sending the request with do, using the cache when cached is set
+func (p *HTTPIPLookupProvider) lookup(do httpDoer, cached bool, ip netip.Addr) (IPInfo, error) {
+	var key = ip.String()
+	if cached {
+		if v, err := p.cache.Get(key); err == nil {
+			return v.(IPInfo), nil
+		}
+	}
+	req, err := http.NewRequest("GET", strings.ReplaceAll(p.url, "{ip}", key), nil)
+	if err != nil {
+		return IPInfo{}, err
+	}
+	req.Header.Set("Accept", "application/json")
+	res, err := do(req)
+	if err != nil {
+		return IPInfo{}, err
+	}
+	defer res.Body.Close()
+	body, err := readJSONLimited(res.Body)
+	if err != nil {
+		return IPInfo{}, err
+	}
+	if res.StatusCode != http.StatusOK {
+		return IPInfo{}, fmt.Errorf("%s responded %s", req.URL.Redacted(), res.Status)
+	}
+	var answer struct {
+		Country string          `json:"country"`
+		ASN     json.RawMessage `json:"asn"`
+		Org     string          `json:"org"`
+	}
+	err = unmarshalJSONLimited(body, &answer)
+	if err != nil {
+		return IPInfo{}, fmt.Errorf("%s: %w", req.URL.Redacted(), err)
+	}
+	var info = IPInfo{Country: strings.ToUpper(answer.Country), Org: answer.Org}
+	var asn = strings.Trim(string(answer.ASN), `"`)
+	if asn != "" && asn != "null" {
+		info.ASN, err = strconv.ParseInt(strings.TrimPrefix(strings.ToUpper(asn), "AS"), 10, 64)
+		if err != nil {
+			return IPInfo{}, fmt.Errorf("%s: invalid asn %s", req.URL.Redacted(), answer.ASN)
+		}
+	}
+	if cached {
+		p.cache.SetEx(key, info, p.ttl)
+	}
+	return info, nil
+}
+
+// Stats returns the usage statistics of the provider's cache
+func (p *HTTPIPLookupProvider) Stats() CacheStat {
+	return p.cache.Stats()
+}
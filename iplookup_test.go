@@ -0,0 +1,157 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type fakeIPLookupProvider map[string]IPInfo
+
+func (p fakeIPLookupProvider) LookupIP(ip netip.Addr) (IPInfo, error) {
+	info, ok := p[ip.String()]
+	if !ok {
+		return IPInfo{}, errors.New("not found")
+	}
+	return info, nil
+}
+
+func restoreIPLookupProvider(t *testing.T) {
+	var p = ipLookupProvider
+	t.Cleanup(func() {
+		ipLookupProvider = p
+	})
+}
+
+func TestIPLookup(t *testing.T) {
+	restoreIPLookupProvider(t)
+	SetIPLookupProvider(nil)
+	_, err := ipLookup(doHTTP, true, "8.8.8.8")
+	if err == nil || !strings.Contains(err.Error(), "no IP lookup provider") {
+		t.Errorf(`Unexpected error %v`, err)
+	}
+
+	SetIPLookupProvider(fakeIPLookupProvider{
+		"8.8.8.8": {Country: "US", ASN: 15169, Org: "Google LLC"},
+	})
+	result, err := InterpolateStrict(map[string]interface{}{"ip": "::ffff:8.8.8.8"}, `{{ with ipLookup .ip }}{{ .country }} AS{{ .asn }} {{ .org }}{{ end }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if result != "US AS15169 Google LLC" {
+		t.Errorf(`Unexpected result %q`, result)
+	}
+
+	for _, ip := range []string{"", "8.8.8", "1.1.1.1"} {
+		_, err = ipLookup(doHTTP, true, ip)
+		if err == nil {
+			t.Errorf(`Expected error for %q`, ip)
+		}
+	}
+}
+
+func TestHTTPIPLookupProvider(t *testing.T) {
+	restoreIPLookupProvider(t)
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		switch r.URL.Path {
+		case "/v1/1.1.1.1":
+			fmt.Fprint(w, `{"country":"au","asn":"AS13335","org":"Cloudflare, Inc."}`)
+		case "/v1/2001:db8::1":
+			fmt.Fprint(w, `{"country":"","asn":64496,"org":"Documentation"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	var p = NewHTTPIPLookupProvider(srv.URL+"/v1/{ip}", 0)
+	SetIPLookupProvider(p)
+	for i := 0; i < 3; i++ {
+		info, err := ipLookup(doHTTP, true, "1.1.1.1")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if info["country"] != "AU" || info["asn"] != int64(13335) || info["org"] != "Cloudflare, Inc." {
+			t.Errorf(`Unexpected result %v`, info)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf(`Expected 1 request, got %d`, n)
+	}
+	if stat := p.Stats(); stat.Hits != 2 || stat.Entries != 1 {
+		t.Errorf(`Unexpected cache stats %+v`, stat)
+	}
+
+	info, err := ipLookup(doHTTP, true, "2001:db8::1")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if info["asn"] != int64(64496) {
+		t.Errorf(`Unexpected result %v`, info)
+	}
+
+	// Failures are not cached
+	for i := 0; i < 2; i++ {
+		_, err = ipLookup(doHTTP, true, "9.9.9.9")
+		if err == nil || !strings.Contains(err.Error(), "404") {
+			t.Errorf(`Unexpected error %v`, err)
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 4 {
+		t.Errorf(`Expected 4 requests, got %d`, n)
+	}
+}
+
+func TestIPLookupDryRun(t *testing.T) {
+	restoreIPLookupProvider(t)
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		fmt.Fprint(w, `{"country":"US","asn":1,"org":"Real"}`)
+	}))
+	defer srv.Close()
+	var p = NewHTTPIPLookupProvider(srv.URL+"/v1/{ip}", 0)
+	SetIPLookupProvider(p)
+
+	var dryRun = &DryRun{Responses: map[string]DryRunResponse{
+		srv.URL + "/v1/*": {Body: `{"country":"NZ","asn":2,"org":"Canned"}`},
+	}}
+	result, err := InterpolateDryRun(dryRun, nil, `{{ (ipLookup "1.1.1.1").org }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if result != "Canned" {
+		t.Errorf(`Unexpected result %q`, result)
+	}
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf(`Expected no requests to be sent, got %d`, n)
+	}
+	if calls := dryRun.Calls(); len(calls) != 1 || calls[0].URL != srv.URL+"/v1/1.1.1.1" {
+		t.Errorf(`Unexpected calls %+v`, calls)
+	}
+	if stat := p.Stats(); stat.Entries != 0 {
+		t.Errorf(`Expected the dry run not to be cached, got %+v`, stat)
+	}
+
+	// Other providers are called as usual
+	SetIPLookupProvider(fakeIPLookupProvider{"1.1.1.1": {Org: "Fake"}})
+	result, err = InterpolateDryRun(dryRun, nil, `{{ (ipLookup "1.1.1.1").org }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if result != "Fake" {
+		t.Errorf(`Unexpected result %q`, result)
+	}
+}
@@ -0,0 +1,151 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+var maxParseJSONBytes int64
+var maxParseJSONDepth int
+
+// SetParseJSONLimits limits the size and nesting depth of documents parsed by parseJSON, jsonMerge and jsonPatch,
+// and of the responses parsed by graphql, ipLookup, binLookup and the authx funcs
+// Zero leaves the limit unset, which is the default
+func SetParseJSONLimits(maxBytes int64, maxDepth int) {
+	maxParseJSONBytes = maxBytes
+	maxParseJSONDepth = maxDepth
+}
+
+// checkJSONLimits returns an error when data exceeds the size or depth limits for parsed JSON
+func checkJSONLimits(data []byte) error {
+	if maxParseJSONBytes > 0 && int64(len(data)) > maxParseJSONBytes {
+		return fmt.Errorf("json document of %d bytes exceeds the size limit of %d bytes", len(data), maxParseJSONBytes)
+	}
+	if maxParseJSONDepth <= 0 {
+		return nil
+	}
+	var depth int
+	var inString, escaped bool
+	for i, c := range data {
+		switch {
+		case escaped:
+			escaped = false
+		case inString && c == '\\':
+			escaped = true
+		case c == '"':
+			inString = !inString
+		case inString:
+		case c == '[' || c == '{':
+			depth++
+			if depth > maxParseJSONDepth {
+				return fmt.Errorf("json document exceeds the depth limit of %d at offset %d", maxParseJSONDepth, i)
+			}
+		case c == ']' || c == '}':
+			depth--
+		}
+	}
+	return nil
+}
+
+// unmarshalJSONLimited is json.Unmarshal enforcing the parsed JSON limits
+func unmarshalJSONLimited(data []byte, v interface{}) error {
+	if err := checkJSONLimits(data); err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// readJSONLimited reads r, stopping with an error once more than the parsed JSON size limit has been read
+func readJSONLimited(r io.Reader) ([]byte, error) {
+	if maxParseJSONBytes <= 0 {
+		return io.ReadAll(r)
+	}
+	b, err := io.ReadAll(io.LimitReader(r, maxParseJSONBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(b)) > maxParseJSONBytes {
+		return nil, fmt.Errorf("json document exceeds the size limit of %d bytes", maxParseJSONBytes)
+	}
+	return b, nil
+}
+
+// toJSONStable marshals v with interface keyed maps, such as dicts, converted to string keys
+// Map keys are always sorted, so the output is stable for signing
+func toJSONStable(v interface{}) (string, error) {
+	normalized, err := normalizeJSONValue(v)
+	if err != nil {
+		return "", fmt.Errorf("toJSONStable: %w", err)
+	}
+	b, err := json.Marshal(normalized)
+	if err != nil {
+		return "", fmt.Errorf("toJSONStable: %w", err)
+	}
+	return string(b), nil
+}
+
+// toJSONOmitEmpty is toJSONStable with nil values, empty strings and empty maps and slices removed from maps and slices
+// Maps and slices left empty once their empty members are removed are removed as well
+func toJSONOmitEmpty(v interface{}) (string, error) {
+	normalized, err := normalizeJSONValue(v)
+	if err != nil {
+		return "", fmt.Errorf("toJSONOmitEmpty: %w", err)
+	}
+	b, err := json.Marshal(omitEmptyJSON(normalized))
+	if err != nil {
+		return "", fmt.Errorf("toJSONOmitEmpty: %w", err)
+	}
+	return string(b), nil
+}
+
+// omitEmptyJSON removes empty members from normalized maps and slices
+func omitEmptyJSON(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		for k, child := range value {
+			child = omitEmptyJSON(child)
+			if isEmptyJSON(child) {
+				delete(value, k)
+			} else {
+				value[k] = child
+			}
+		}
+		return value
+	case []interface{}:
+		var kept = make([]interface{}, 0, len(value))
+		for _, child := range value {
+			child = omitEmptyJSON(child)
+			if !isEmptyJSON(child) {
+				kept = append(kept, child)
+			}
+		}
+		return kept
+	default:
+		return v
+	}
+}
+
+// isEmptyJSON reports whether v is nil, an empty string or an empty map or slice, zero numbers and false are not empty
+func isEmptyJSON(v interface{}) bool {
+	switch value := v.(type) {
+	case nil:
+		return true
+	case string:
+		return value == ""
+	case map[string]interface{}:
+		return len(value) == 0
+	case []interface{}:
+		return len(value) == 0
+	default:
+		var rv = reflect.ValueOf(v)
+		switch rv.Kind() {
+		case reflect.Ptr, reflect.Interface:
+			return rv.IsNil()
+		case reflect.Slice, reflect.Map, reflect.String:
+			return rv.Len() == 0
+		}
+		return false
+	}
+}
@@ -0,0 +1,137 @@
+package template
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+)
+
+type countingReader struct {
+	r    *strings.Reader
+	read int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.read += n
+	return n, err
+}
+
+func TestParseJSONLimits(t *testing.T) {
+	SetParseJSONLimits(64, 3)
+	defer SetParseJSONLimits(0, 0)
+
+	res, err := Interpolate(map[string]interface{}{"body": `{"a":[{"b":"[[[{{"}]}`}, `{{ (index (parseJSON .body).a 0).b }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "[[[{{" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+
+	var cases = map[string]string{
+		`{"a":[{"b":[1]}]}`:                  "depth limit of 3",
+		`"` + strings.Repeat("x", 100) + `"`: "size limit of 64",
+	}
+	for body, expected := range cases {
+		_, err = Interpolate(map[string]interface{}{"body": body}, `{{ parseJSON .body }}`)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf(`Expected error containing %q, got %v`, expected, err)
+		}
+		_, err = Interpolate(map[string]interface{}{"body": body}, `{{ jsonMerge .body "{}" }}`)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf(`Expected jsonMerge error containing %q, got %v`, expected, err)
+		}
+	}
+
+	var reader = &countingReader{r: strings.NewReader(`"` + strings.Repeat("x", 10000) + `"`)}
+	_, err = Interpolate(map[string]interface{}{"body": reader}, `{{ parseJSON .body }}`)
+	if err == nil || !strings.Contains(err.Error(), "size limit of 64") {
+		t.Errorf(`Expected size limit error, got %v`, err)
+	}
+	if reader.read > 1024 {
+		t.Errorf(`Expected reading to stop at the limit, read %d bytes`, reader.read)
+	}
+}
+
+func TestToJSONStable(t *testing.T) {
+	res, err := Interpolate(map[string]interface{}{"m": map[string]interface{}{"z": 1, "a": []interface{}{"x"}}}, `{{ dict "b" 1 "a" (dict "d" .m "c" nil) | toJSONStable }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `{"a":{"c":null,"d":{"a":["x"],"z":1}},"b":1}` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	_, err = InterpolateStrict(nil, `{{ dict 1 2 | toJSONStable }}`)
+	if err == nil {
+		t.Errorf(`Expected non-string keys to fail`)
+	}
+}
+
+func TestToJSONOmitEmpty(t *testing.T) {
+	var data = map[string]interface{}{
+		"event": map[string]interface{}{
+			"id":      "1",
+			"comment": "",
+			"count":   0,
+			"ok":      false,
+			"tags":    []interface{}{"", nil, "x", map[string]interface{}{}},
+			"meta":    map[string]interface{}{"a": nil, "b": []interface{}{}},
+			"notes":   []string{},
+		},
+	}
+	res, err := Interpolate(data, `{{ toJSONOmitEmpty .event }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `{"count":0,"id":"1","ok":false,"tags":["x"]}` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	if _, ok := data["event"].(map[string]interface{})["comment"]; !ok {
+		t.Errorf(`Expected data to be unchanged`)
+	}
+}
+
+func TestParseJSONLimitsHTTPResponses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data":{"a":{"b":{"c":1}}},"country":{"alpha2":"US"},"org":"`+strings.Repeat("x", 100)+`"}`)
+	}))
+	defer srv.Close()
+	SetParseJSONLimits(0, 3)
+	defer SetParseJSONLimits(0, 0)
+	SetBINLookupURL(srv.URL + "/{bin}")
+	t.Cleanup(func() {
+		SetBINLookupURL("")
+		binLookupCache.Flush()
+	})
+	binLookupCache.Flush()
+
+	_, err := graphqlDo(doHTTP, srv.URL, nil, "{ a { b { c } } }", nil)
+	if err == nil || !strings.Contains(err.Error(), "depth limit of 3") {
+		t.Errorf(`Expected graphql depth limit error, got %v`, err)
+	}
+	_, err = binLookup(doHTTP, false, "411111")
+	if err == nil || !strings.Contains(err.Error(), "depth limit of 3") {
+		t.Errorf(`Expected binLookup depth limit error, got %v`, err)
+	}
+	_, err = NewHTTPIPLookupProvider(srv.URL+"/{ip}", 0).LookupIP(netip.MustParseAddr("1.1.1.1"))
+	if err == nil || !strings.Contains(err.Error(), "depth limit of 3") {
+		t.Errorf(`Expected ipLookup depth limit error, got %v`, err)
+	}
+
+	SetParseJSONLimits(64, 0)
+	_, err = binLookup(doHTTP, false, "411111")
+	if err == nil || !strings.Contains(err.Error(), "size limit of 64") {
+		t.Errorf(`Expected binLookup size limit error, got %v`, err)
+	}
+	_, err = graphqlDo(doHTTP, srv.URL, nil, "{ a { b { c } } }", nil)
+	if err == nil || !strings.Contains(err.Error(), "size limit of 64") {
+		t.Errorf(`Expected graphql size limit error, got %v`, err)
+	}
+}
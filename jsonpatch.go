@@ -0,0 +1,341 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonDocument converts a JSON string or an already parsed value into a fresh JSON value
+// Maps and slices are always copied, so the result can be modified without changing the template data
+func jsonDocument(i interface{}) (interface{}, error) {
+	var data []byte
+	switch v := i.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	default:
+		return normalizeJSONValue(i)
+	}
+	if err := checkJSONLimits(data); err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	var dec = json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	err := dec.Decode(&doc)
+	if err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
+
+// jsonMerge applies overlay to base using RFC 7386 JSON merge patch semantics
+// Either argument may be a JSON string or a parsed value such as a map from the template data or dict
+func jsonMerge(base, overlay interface{}) (interface{}, error) {
+	target, err := jsonDocument(base)
+	if err != nil {
+		return nil, fmt.Errorf("jsonMerge base: %w", err)
+	}
+	patch, err := jsonDocument(overlay)
+	if err != nil {
+		return nil, fmt.Errorf("jsonMerge overlay: %w", err)
+	}
+	return mergePatch(target, patch), nil
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+		} else {
+			t[k] = mergePatch(t[k], v)
+		}
+	}
+	return t
+}
+
+// jsonPatch applies an RFC 6902 JSON patch, a list of operations, to doc
+// Either argument may be a JSON string or a parsed value. Errors identify the index of the failing operation.
+func jsonPatch(doc, patch interface{}) (interface{}, error) {
+	target, err := jsonDocument(doc)
+	if err != nil {
+		return nil, fmt.Errorf("jsonPatch document: %w", err)
+	}
+	p, err := jsonDocument(patch)
+	if err != nil {
+		return nil, fmt.Errorf("jsonPatch patch: %w", err)
+	}
+	ops, ok := p.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("jsonPatch patch must be an array of operations, got %T", p)
+	}
+	for i, o := range ops {
+		op, ok := o.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("jsonPatch operation %d: must be an object, got %T", i, o)
+		}
+		target, err = applyJSONPatchOp(target, op)
+		if err != nil {
+			return nil, fmt.Errorf("jsonPatch operation %d (%v %v): %w", i, op["op"], op["path"], err)
+		}
+	}
+	return target, nil
+}
+
+func applyJSONPatchOp(doc interface{}, op map[string]interface{}) (interface{}, error) {
+	path, err := jsonPatchPointer(op, "path")
+	if err != nil {
+		return nil, err
+	}
+	switch op["op"] {
+	case "add":
+		value, ok := op["value"]
+		if !ok {
+			return nil, fmt.Errorf("missing value")
+		}
+		return jsonPointerAdd(doc, path, value)
+	case "remove":
+		doc, _, err = jsonPointerRemove(doc, path)
+		return doc, err
+	case "replace":
+		value, ok := op["value"]
+		if !ok {
+			return nil, fmt.Errorf("missing value")
+		}
+		doc, _, err = jsonPointerRemove(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, path, value)
+	case "move":
+		from, err := jsonPatchPointer(op, "from")
+		if err != nil {
+			return nil, err
+		}
+		if len(path) > len(from) && strings.Join(path[:len(from)], "/") == strings.Join(from, "/") {
+			return nil, fmt.Errorf("cannot move a value into one of its children")
+		}
+		doc, value, err := jsonPointerRemove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, path, value)
+	case "copy":
+		from, err := jsonPatchPointer(op, "from")
+		if err != nil {
+			return nil, err
+		}
+		value, err := jsonPointerGet(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		value, err = normalizeJSONValue(value)
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerAdd(doc, path, value)
+	case "test":
+		value, err := jsonPointerGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(value, op["value"]) {
+			return nil, fmt.Errorf("test failed")
+		}
+		return doc, nil
+	default:
+		return nil, fmt.Errorf("unknown op %v", op["op"])
+	}
+}
+
+// jsonPatchPointer parses the JSON pointer in the member of op into its reference tokens
+func jsonPatchPointer(op map[string]interface{}, member string) ([]string, error) {
+	pointer, ok := op[member].(string)
+	if !ok {
+		return nil, fmt.Errorf("missing %s", member)
+	}
+	if pointer == "" {
+		return []string{}, nil
+	}
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("invalid %s %q", member, pointer)
+	}
+	var tokens = strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// jsonArrayIndex parses an array index token for an array of length n
+// The index n itself, or "-", is only valid when appending
+func jsonArrayIndex(token string, n int, appending bool) (int, error) {
+	if appending && token == "-" {
+		return n, nil
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i < 0 || (token != "0" && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if i > n || (i == n && !appending) {
+		return 0, fmt.Errorf("array index %d out of range", i)
+	}
+	return i, nil
+}
+
+func jsonPointerGet(doc interface{}, tokens []string) (interface{}, error) {
+	for _, token := range tokens {
+		switch v := doc.(type) {
+		case map[string]interface{}:
+			child, ok := v[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q not found", token)
+			}
+			doc = child
+		case []interface{}:
+			i, err := jsonArrayIndex(token, len(v), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = v[i]
+		default:
+			return nil, fmt.Errorf("cannot index %T with %q", doc, token)
+		}
+	}
+	return doc, nil
+}
+
+// jsonPointerUpdate calls update with the container holding the last token, returning the document with the updated container
+func jsonPointerUpdate(doc interface{}, tokens []string, update func(container interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return update(doc, tokens[0])
+	}
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		child, ok := v[tokens[0]]
+		if !ok {
+			return nil, fmt.Errorf("member %q not found", tokens[0])
+		}
+		child, err := jsonPointerUpdate(child, tokens[1:], update)
+		if err != nil {
+			return nil, err
+		}
+		v[tokens[0]] = child
+		return v, nil
+	case []interface{}:
+		i, err := jsonArrayIndex(tokens[0], len(v), false)
+		if err != nil {
+			return nil, err
+		}
+		child, err := jsonPointerUpdate(v[i], tokens[1:], update)
+		if err != nil {
+			return nil, err
+		}
+		v[i] = child
+		return v, nil
+	default:
+		return nil, fmt.Errorf("cannot index %T with %q", doc, tokens[0])
+	}
+}
+
+func jsonPointerAdd(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return jsonPointerUpdate(doc, tokens, func(container interface{}, token string) (interface{}, error) {
+		switch v := container.(type) {
+		case map[string]interface{}:
+			v[token] = value
+			return v, nil
+		case []interface{}:
+			i, err := jsonArrayIndex(token, len(v), true)
+			if err != nil {
+				return nil, err
+			}
+			v = append(v, nil)
+			copy(v[i+1:], v[i:])
+			v[i] = value
+			return v, nil
+		default:
+			return nil, fmt.Errorf("cannot add %q to %T", token, container)
+		}
+	})
+}
+
+func jsonPointerRemove(doc interface{}, tokens []string) (interface{}, interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, doc, nil
+	}
+	var removed interface{}
+	doc, err := jsonPointerUpdate(doc, tokens, func(container interface{}, token string) (interface{}, error) {
+		switch v := container.(type) {
+		case map[string]interface{}:
+			value, ok := v[token]
+			if !ok {
+				return nil, fmt.Errorf("member %q not found", token)
+			}
+			removed = value
+			delete(v, token)
+			return v, nil
+		case []interface{}:
+			i, err := jsonArrayIndex(token, len(v), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = v[i]
+			return append(v[:i], v[i+1:]...), nil
+		default:
+			return nil, fmt.Errorf("cannot remove %q from %T", token, container)
+		}
+	})
+	return doc, removed, err
+}
+
+// jsonEqual compares two JSON values, treating numbers of any representation as equal when their values are
+func jsonEqual(a, b interface{}) bool {
+	switch av := a.(type) {
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			if other, ok := bv[k]; !ok || !jsonEqual(v, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !jsonEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	case json.Number, float64, int, int64:
+		x, err := interfaceToFloat64(a)
+		if err != nil {
+			return false
+		}
+		y, err := interfaceToFloat64(b)
+		return err == nil && x == y
+	default:
+		return a == b
+	}
+}
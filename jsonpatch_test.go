@@ -0,0 +1,82 @@
+package template
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestJSONMerge(t *testing.T) {
+	var base = map[string]interface{}{
+		"name":    "base",
+		"options": map[string]interface{}{"a": 1, "b": 2},
+		"tags":    []interface{}{"x"},
+	}
+	var data = map[string]interface{}{
+		"base":    base,
+		"overlay": `{"options":{"b":null,"c":3},"tags":["y"],"id":12345678901234567890}`,
+	}
+	res, err := Interpolate(data, `{{ jsonMerge .base .overlay | toJSON }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `{"id":12345678901234567890,"name":"base","options":{"a":1,"c":3},"tags":["y"]}` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	if _, ok := base["options"].(map[string]interface{})["b"]; !ok {
+		t.Errorf(`Expected base map to be unchanged, got %v`, base)
+	}
+
+	res, err = Interpolate(nil, `{{ jsonMerge "{\"a\":1}" (dict "a" (dict "b" 2)) | toJSON }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `{"a":{"b":2}}` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestJSONPatch(t *testing.T) {
+	var doc = `{"a":{"b":[1,2,3]},"c":"x","d~/e":true}`
+	var patch = `[
+		{"op":"test","path":"/c","value":"x"},
+		{"op":"add","path":"/a/b/1","value":9},
+		{"op":"add","path":"/a/b/-","value":4},
+		{"op":"remove","path":"/a/b/0"},
+		{"op":"replace","path":"/c","value":"y"},
+		{"op":"move","from":"/d~0~1e","path":"/moved"},
+		{"op":"copy","from":"/a","path":"/copied"},
+		{"op":"test","path":"/copied/b/0","value":9.0}
+	]`
+	res, err := jsonPatch(doc, patch)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	out, err := json.Marshal(res)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(out) != `{"a":{"b":[9,2,3,4]},"c":"y","copied":{"b":[9,2,3,4]},"moved":true}` {
+		t.Errorf(`Unexpected result %s`, out)
+	}
+}
+
+func TestJSONPatchErrors(t *testing.T) {
+	var cases = map[string]string{
+		`[{"op":"test","path":"/a","value":2}]`:                                  "operation 0",
+		`[{"op":"add","path":"/a","value":2},{"op":"remove","path":"/missing"}]`: "operation 1",
+		`[{"op":"add","path":"/list/5","value":1}]`:                              "out of range",
+		`[{"op":"move","from":"/list","path":"/list/0"}]`:                        "children",
+		`[{"op":"bogus","path":"/a"}]`:                                           "unknown op",
+	}
+	for patch, expected := range cases {
+		_, err := Interpolate(map[string]interface{}{"patch": patch}, `{{ jsonPatch "{\"a\":1,\"list\":[]}" .patch }}`)
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf(`Expected error containing %q for %s, got %v`, expected, patch, err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package template
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"golang.org/x/text/language"
+)
+
+// parseAcceptLanguage returns the language tags of an Accept-Language header ordered by preference
+// Malformed entries, the * wildcard and tags with q=0 are skipped rather than failing the whole header
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float32
+	}
+	var entries []weighted
+	for _, entry := range strings.Split(header, ",") {
+		if strings.TrimSpace(strings.SplitN(entry, ";", 2)[0]) == "*" {
+			continue
+		}
+		tags, q, err := language.ParseAcceptLanguage(entry)
+		if err != nil || len(tags) == 0 {
+			continue
+		}
+		entries = append(entries, weighted{tags[0].String(), q[0]})
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].q > entries[j].q
+	})
+	var tags = make([]string, len(entries))
+	for i, e := range entries {
+		tags[i] = e.tag
+	}
+	return tags
+}
+
+// matchLanguage returns the supported language tag that best matches an Accept-Language header using BCP 47 matching,
+// so "en-GB" matches "en" and "es-419" matches "es". An empty, malformed or unmatched header returns the first supported tag.
+func matchLanguage(supported interface{}, header string) (string, error) {
+	var names []string
+	switch v := supported.(type) {
+	case []string:
+		names = v
+	case []interface{}:
+		for _, s := range v {
+			names = append(names, fmt.Sprint(s))
+		}
+	default:
+		return "", fmt.Errorf("matchLanguage: unsupported type %T for supported languages", supported)
+	}
+	if len(names) == 0 {
+		return "", fmt.Errorf("matchLanguage: no supported languages")
+	}
+	var tags = make([]language.Tag, len(names))
+	for i, name := range names {
+		tag, err := language.Parse(name)
+		if err != nil {
+			return "", fmt.Errorf("matchLanguage: invalid supported language %q: %w", name, err)
+		}
+		tags[i] = tag
+	}
+	var desired []language.Tag
+	for _, name := range parseAcceptLanguage(header) {
+		desired = append(desired, language.Make(name))
+	}
+	_, index, confidence := language.NewMatcher(tags).Match(desired...)
+	if confidence == language.No {
+		return names[0], nil
+	}
+	return names[index], nil
+}
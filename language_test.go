@@ -0,0 +1,56 @@
+package template
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseAcceptLanguage(t *testing.T) {
+	for header, expected := range map[string][]string{
+		"en-US,en;q=0.9,fr;q=0.8":   {"en-US", "en", "fr"},
+		"fr;q=0.5, de , es;q=0.7":   {"de", "es", "fr"},
+		"da, en-gb;q=0.8, *;q=0.1":  {"da", "en-GB"},
+		"fr;q=0,de":                 {"de"},
+		"!!garbage, pt-BR;q=0.4":    {"pt-BR"},
+		"":                          {},
+		"not a language at all ;;;": {},
+	} {
+		if res := parseAcceptLanguage(header); !reflect.DeepEqual(res, expected) {
+			t.Errorf(`Unexpected result %q for %q`, res, header)
+		}
+	}
+}
+
+func TestMatchLanguage(t *testing.T) {
+	var supported = []interface{}{"en", "es", "fr"}
+	for header, expected := range map[string]string{
+		"en-US,en;q=0.9,fr;q=0.8": "en",
+		"fr-CA,es;q=0.5":          "fr",
+		"es-419":                  "es",
+		"de,fr;q=0.3":             "fr",
+		"pt-BR":                   "en",
+		"":                        "en",
+		"@@@":                     "en",
+	} {
+		res, err := matchLanguage(supported, header)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if res != expected {
+			t.Errorf(`Unexpected result %q for %q`, res, header)
+		}
+	}
+	if _, err := matchLanguage([]interface{}{}, "en"); err == nil {
+		t.Error("Expected error for no supported languages")
+	}
+
+	res, err := InterpolateStrict(map[string]interface{}{"lang": "es-MX,es;q=0.9"}, `{{ matchLanguage (list "en" "es") .lang }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "es" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
@@ -0,0 +1,164 @@
+package template
+
+import (
+	"fmt"
+	"text/template/parse"
+)
+
+// Limits bounds the size and complexity of templates, see ParseWithLimits
+// Zero leaves a limit unset
+type Limits struct {
+	// Maximum bytes of template source
+	MaxLen int `json:"maxLen"`
+	// Maximum number of actions, counting each {{ }} other than end, else and comments, across the template and its defines
+	MaxActions int `json:"maxActions"`
+	// Maximum nesting of if, range, with and parenthesized pipelines
+	MaxDepth int `json:"maxDepth"`
+}
+
+var templateLimits Limits
+
+// SetTemplateLimits sets the limits enforced by Parse, ParseNamed and when unmarshaling a Template
+// Templates exceeding them fail to parse. The zero Limits, the default, is unlimited.
+func SetTemplateLimits(limits Limits) {
+	templateLimits = limits
+}
+
+// ParseWithLimits is Parse that rejects templates exceeding limits in place of those set with SetTemplateLimits
+// Use it for templates from untrusted sources, which could otherwise make parsing and execution expensive
+func ParseWithLimits(src string, limits Limits) (*Template, error) {
+	var t Template
+	err := t.parseWithLimits("", src, limits)
+	if err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// checkSourceLimits returns an error when src is longer than limits allow, before it is parsed
+func (limits Limits) checkSourceLimits(src string) error {
+	if limits.MaxLen > 0 && len(src) > limits.MaxLen {
+		return fmt.Errorf("template source of %d bytes exceeds the limit of %d bytes", len(src), limits.MaxLen)
+	}
+	return nil
+}
+
+// checkTreeLimits returns an error when the templates parsed into t exceed limits
+// Partials shared with the RootTemplate are not counted
+func (limits Limits) checkTreeLimits(t *Template) error {
+	if limits.MaxActions <= 0 && limits.MaxDepth <= 0 {
+		return nil
+	}
+	var c = limitCounter{limits: limits}
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree == nil {
+			continue
+		}
+		if shared := RootTemplate.Lookup(tmpl.Name()); shared != nil && shared.Tree == tmpl.Tree {
+			continue
+		}
+		if err := c.walk(tmpl.Tree.Root, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// limitCounter counts the actions and nesting of parse trees against limits
+type limitCounter struct {
+	limits  Limits
+	actions int
+}
+
+func (c *limitCounter) action() error {
+	c.actions++
+	if c.limits.MaxActions > 0 && c.actions > c.limits.MaxActions {
+		return fmt.Errorf("template exceeds the limit of %d actions", c.limits.MaxActions)
+	}
+	return nil
+}
+
+func (c *limitCounter) nest(depth int) error {
+	if c.limits.MaxDepth > 0 && depth > c.limits.MaxDepth {
+		return fmt.Errorf("template nesting exceeds the depth limit of %d", c.limits.MaxDepth)
+	}
+	return nil
+}
+
+func (c *limitCounter) walk(node parse.Node, depth int) error {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return nil
+		}
+		for _, child := range n.Nodes {
+			if err := c.walk(child, depth); err != nil {
+				return err
+			}
+		}
+	case *parse.ActionNode:
+		if err := c.action(); err != nil {
+			return err
+		}
+		return c.walk(n.Pipe, depth)
+	case *parse.IfNode:
+		return c.branch(&n.BranchNode, depth)
+	case *parse.RangeNode:
+		return c.branch(&n.BranchNode, depth)
+	case *parse.WithNode:
+		return c.branch(&n.BranchNode, depth)
+	case *parse.TemplateNode:
+		if err := c.action(); err != nil {
+			return err
+		}
+		return c.walk(n.Pipe, depth)
+	case *parse.BreakNode, *parse.ContinueNode:
+		return c.action()
+	case *parse.PipeNode:
+		if n == nil {
+			return nil
+		}
+		for _, cmd := range n.Cmds {
+			if err := c.walk(cmd, depth); err != nil {
+				return err
+			}
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			if err := c.arg(arg, depth); err != nil {
+				return err
+			}
+		}
+	case *parse.ChainNode:
+		return c.arg(n.Node, depth)
+	}
+	return nil
+}
+
+// arg walks an argument of a command, arguments that are pipelines are parenthesized and nest
+func (c *limitCounter) arg(node parse.Node, depth int) error {
+	if _, ok := node.(*parse.PipeNode); !ok {
+		return c.walk(node, depth)
+	}
+	if err := c.nest(depth + 1); err != nil {
+		return err
+	}
+	return c.walk(node, depth+1)
+}
+
+func (c *limitCounter) branch(n *parse.BranchNode, depth int) error {
+	if err := c.action(); err != nil {
+		return err
+	}
+	if err := c.nest(depth + 1); err != nil {
+		return err
+	}
+	if err := c.walk(n.Pipe, depth); err != nil {
+		return err
+	}
+	if err := c.walk(n.List, depth+1); err != nil {
+		return err
+	}
+	return c.walk(n.ElseList, depth+1)
+}
@@ -0,0 +1,88 @@
+package template
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParseWithLimits(t *testing.T) {
+	var cases = []struct {
+		src    string
+		limits Limits
+		err    string
+	}{
+		{`{{ .a }}{{ .b }}`, Limits{MaxLen: 16}, ``},
+		{`{{ .a }}{{ .b }}!`, Limits{MaxLen: 16}, `17 bytes exceeds the limit of 16 bytes`},
+		{`{{ .a }}{{/* comment */}}{{ if .b }}{{ .c }}{{ else }}{{ end }}`, Limits{MaxActions: 3}, ``},
+		{`{{ .a }}{{ if .b }}{{ .c }}{{ end }}{{ .d }}`, Limits{MaxActions: 3}, `limit of 3 actions`},
+		{`{{ define "x" }}{{ .a }}{{ .b }}{{ end }}{{ template "x" . }}`, Limits{MaxActions: 2}, `limit of 2 actions`},
+		{`{{ range .a }}{{ if . }}{{ with .b }}{{ . }}{{ end }}{{ end }}{{ end }}`, Limits{MaxDepth: 3}, ``},
+		{`{{ range .a }}{{ if . }}{{ with .b }}{{ if . }}{{ end }}{{ end }}{{ end }}{{ end }}`, Limits{MaxDepth: 3}, `depth limit of 3`},
+		{`{{ add 1 (add 2 (add 3 4)) }}`, Limits{MaxDepth: 2}, ``},
+		{`{{ add 1 (add 2 (add 3 (add 4 5))) }}`, Limits{MaxDepth: 2}, `depth limit of 2`},
+		{`{{ if .a }}{{ (index (index .b 0) 1).c }}{{ end }}`, Limits{MaxDepth: 2}, `depth limit of 2`},
+		{strings.Repeat(`{{ .a }}`, 1000), Limits{}, ``},
+	}
+	for _, c := range cases {
+		_, err := ParseWithLimits(c.src, c.limits)
+		if c.err == "" && err != nil {
+			t.Errorf(`Unexpected error for %q: %v`, c.src, err)
+		} else if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+			t.Errorf(`Expected error %q for %q, got %v`, c.err, c.src, err)
+		}
+	}
+}
+
+func TestParseWithLimitsIgnoresPartials(t *testing.T) {
+	restoreRootTemplate(t)
+	err := LoadPartial("long", strings.Repeat(`{{ .a }}`, 10))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	tmpl, err := ParseWithLimits(`{{ template "long" . }}`, Limits{MaxActions: 1})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := tmpl.ExecuteToString(map[string]interface{}{"a": 1})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != strings.Repeat("1", 10) {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestSetTemplateLimits(t *testing.T) {
+	SetTemplateLimits(Limits{MaxActions: 2})
+	defer SetTemplateLimits(Limits{})
+
+	var tmpl Template
+	err := json.Unmarshal([]byte(`"{{ .a }}{{ .b }}{{ .c }}"`), &tmpl)
+	if err == nil || !strings.Contains(err.Error(), "limit of 2 actions") {
+		t.Errorf(`Expected UnmarshalJSON to enforce the limits, got %v`, err)
+	}
+	_, err = Parse(`{{ .a }}{{ .b }}`)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func FuzzParseWithLimits(f *testing.F) {
+	f.Add(`{{ .a }}`)
+	f.Add(`{{ if .a }}{{ range .b }}{{ . }}{{ end }}{{ else if .c }}{{ end }}`)
+	f.Add(`{{ define "x" }}{{ template "x" . }}{{ end }}`)
+	f.Add(`{{ add 1 (add 2 (add 3 (add 4 (add 5 6)))) }}`)
+	f.Add(`{{ with $x := (dict "a" 1) }}{{ $x.a }}{{ end }}`)
+	f.Add(strings.Repeat(`{{ if . }}`, 20) + strings.Repeat(`{{ end }}`, 20))
+	var limits = Limits{MaxLen: 512, MaxActions: 16, MaxDepth: 4}
+	f.Fuzz(func(t *testing.T, src string) {
+		_, err := ParseWithLimits(src, limits)
+		if err == nil && len(src) > limits.MaxLen {
+			t.Errorf(`Accepted %d bytes of source`, len(src))
+		}
+	})
+}
@@ -0,0 +1,134 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+)
+
+// MapTemplate is a template map compiled once with CompileMap and executed many times
+// It implements UnmarshalJSON so it can be used directly in config structs
+type MapTemplate struct {
+	templates map[string]*Template
+	maps      map[string]*MapTemplate
+	values    map[string]interface{}
+}
+
+// CompileMap parses every string in templateMap, recursively, into a MapTemplate
+// Values other than strings and maps are passed through to the output as InterpolateMap would.
+// All parse errors are reported, joined and ordered by key path, each wrapped in an ExecError.
+func CompileMap(templateMap map[string]interface{}) (*MapTemplate, error) {
+	var errs []error
+	var m = compileMap(templateMap, "", &errs)
+	if len(errs) > 0 {
+		sort.Slice(errs, func(a, b int) bool {
+			return errs[a].(*ExecError).Key < errs[b].(*ExecError).Key
+		})
+		return nil, errors.Join(errs...)
+	}
+	return m, nil
+}
+
+func compileMap(templateMap map[string]interface{}, prefix string, errs *[]error) *MapTemplate {
+	var m = &MapTemplate{
+		templates: map[string]*Template{},
+		maps:      map[string]*MapTemplate{},
+		values:    map[string]interface{}{},
+	}
+	for key, i := range templateMap {
+		switch v := i.(type) {
+		case string:
+			t, err := Parse(v)
+			if err != nil {
+				*errs = append(*errs, newExecError(prefix+key, v, err))
+				continue
+			}
+			m.templates[key] = t
+		case json.Number:
+			f, err := v.Float64()
+			if err != nil {
+				*errs = append(*errs, newExecError(prefix+key, v.String(), err))
+				continue
+			}
+			m.values[key] = f
+		case map[string]interface{}:
+			m.maps[key] = compileMap(v, prefix+key+".", errs)
+		default:
+			m.values[key] = v
+		}
+	}
+	return m
+}
+
+// Execute executes the compiled templates with data, returning the interpolated map
+// Errors are wrapped in an ExecError carrying the dotted key path of the failing template
+// The templates are a single render, so memo and the counters are shared between them
+func (m *MapTemplate) Execute(data interface{}) (map[string]interface{}, error) {
+	return m.execute(data, "", newRenderOverlay(nil))
+}
+
+func (m *MapTemplate) execute(data interface{}, prefix string, overlay map[string]interface{}) (map[string]interface{}, error) {
+	var res = make(map[string]interface{}, len(m.templates)+len(m.maps)+len(m.values))
+	for key, v := range m.values {
+		res[key] = v
+	}
+	for key, t := range m.templates {
+		var templateOverlay map[string]interface{}
+		if t.usesRenderState() {
+			templateOverlay = overlay
+		}
+		var buf bytes.Buffer
+		err := t.execute(&buf, data, rejectNoValue, templateOverlay)
+		if err != nil {
+			var execErr *ExecError
+			if errors.As(err, &execErr) {
+				return nil, newExecError(prefix+key, execErr.Source, execErr.Err)
+			}
+			return nil, err
+		}
+		res[key] = buf.String()
+	}
+	for key, sub := range m.maps {
+		deep, err := sub.execute(data, prefix+key+".", overlay)
+		if err != nil {
+			return nil, err
+		}
+		res[key] = deep
+	}
+	return res, nil
+}
+
+// UnmarshalJSON implementation for MapTemplate
+func (m *MapTemplate) UnmarshalJSON(data []byte) error {
+	var templateMap map[string]interface{}
+	err := json.Unmarshal(data, &templateMap)
+	if err != nil {
+		return err
+	}
+	compiled, err := CompileMap(templateMap)
+	if err != nil {
+		return err
+	}
+	*m = *compiled
+	return nil
+}
+
+// MarshalJSON implementation for MapTemplate, emitting the template sources
+func (m MapTemplate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.sourceMap())
+}
+
+func (m *MapTemplate) sourceMap() map[string]interface{} {
+	var res = map[string]interface{}{}
+	for key, v := range m.values {
+		res[key] = v
+	}
+	for key, t := range m.templates {
+		res[key] = t.Source()
+	}
+	for key, sub := range m.maps {
+		res[key] = sub.sourceMap()
+	}
+	return res
+}
@@ -0,0 +1,122 @@
+package template
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCompileMap(t *testing.T) {
+	var tmpl = map[string]interface{}{
+		"id":     "{{ .event.id }}",
+		"amount": json.Number("12.5"),
+		"active": true,
+		"nested": map[string]interface{}{"name": "{{ .event.name | toLower }}"},
+	}
+	m, err := CompileMap(tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	expected, err := InterpolateMap(wideTemplateData, tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for i := 0; i < 2; i++ {
+		res, err := m.Execute(wideTemplateData)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !reflect.DeepEqual(res, expected) {
+			t.Errorf(`Unexpected result %v`, res)
+		}
+	}
+}
+
+func TestCompileMapParseErrors(t *testing.T) {
+	_, err := CompileMap(map[string]interface{}{
+		"ok": "{{ 1 }}",
+		"b":  "{{ .a ",
+		"a":  map[string]interface{}{"c": "{{ nonexistentFunc }}"},
+	})
+	if err == nil {
+		t.Errorf(`Expected parse errors`)
+		return
+	}
+	var execErr *ExecError
+	if !errors.As(err, &execErr) || execErr.Key != "a.c" {
+		t.Errorf(`Expected the first error by key path to be a.c, got %v`, err)
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); !ok || len(joined.Unwrap()) != 2 {
+		t.Errorf(`Expected both parse errors to be reported, got %v`, err)
+	}
+}
+
+func TestMapTemplateExecuteError(t *testing.T) {
+	m, err := CompileMap(map[string]interface{}{
+		"nested": map[string]interface{}{"bad": `{{ parseJSON "{" }}`},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = m.Execute(nil)
+	var execErr *ExecError
+	if !errors.As(err, &execErr) || execErr.Key != "nested.bad" {
+		t.Errorf(`Expected an ExecError for nested.bad, got %v`, err)
+	}
+}
+
+func TestMapTemplateUnmarshalJSON(t *testing.T) {
+	var config struct {
+		Payload MapTemplate `json:"payload"`
+	}
+	err := json.Unmarshal([]byte(`{"payload":{"id":"{{ .event.id }}","n":2,"nested":{"name":"{{ .event.name }}"}}}`), &config)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := config.Payload.Execute(wideTemplateData)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var expected = map[string]interface{}{
+		"id":     "8D469E95",
+		"n":      float64(2),
+		"nested": map[string]interface{}{"name": "Order"},
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf(`Unexpected result %v`, res)
+	}
+	b, err := json.Marshal(config.Payload)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(b) != `{"id":"{{ .event.id }}","n":2,"nested":{"name":"{{ .event.name }}"}}` {
+		t.Errorf(`Unexpected result %s`, b)
+	}
+
+	err = json.Unmarshal([]byte(`{"payload":{"bad":"{{ .a "}}`), &config)
+	if err == nil {
+		t.Errorf(`Expected a parse error`)
+	}
+}
+
+func BenchmarkMapTemplateWide(b *testing.B) {
+	m, err := CompileMap(wideTemplateMap(300))
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := m.Execute(wideTemplateData)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,615 @@
+package template
+
+import (
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// The markdown functions implement a deterministic subset of CommonMark without raw HTML:
+//   - ATX (# Heading) and setext (underlined with === or ---) headings
+//   - paragraphs, with hard line breaks from two trailing spaces or a trailing backslash
+//   - bullet (-, * or +) and ordered (1. or 1)) lists, nested by indentation and always rendered tight
+//   - fenced code blocks (``` or ~~~) with an optional language, block quotes and thematic breaks
+//   - emphasis and strong emphasis with * and _, code spans, [links](url "title"), <autolinks> and backslash escapes
+// Tabs are expanded to four spaces. Raw HTML is escaped rather than passed through, and links with schemes other than http, https, mailto and tel
+// are rendered as their text.
+
+type mdBlockKind int
+
+const (
+	mdParagraph mdBlockKind = iota
+	mdHeading
+	mdRule
+	mdCode
+	mdQuote
+	mdList
+)
+
+// mdBlock is a block of a markdown document
+type mdBlock struct {
+	kind mdBlockKind
+	// Heading level
+	level int
+	// Paragraph and heading text, and code block lines
+	lines []string
+	// Code block language
+	lang string
+	// Ordered list number of the first item
+	ordered bool
+	start   int
+	// Quote contents, and the blocks of each list item
+	children []mdBlock
+	items    [][]mdBlock
+}
+
+var (
+	mdHeadingPattern  = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	mdListItemPattern = regexp.MustCompile(`^( {0,3})([-*+]|(\d{1,9})[.)])( +|$)`)
+	mdFencePattern    = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})[ \t]*([^`]*?)[ \t]*$")
+	mdRulePattern     = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	mdSetextPattern   = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	mdAutolinkPattern = regexp.MustCompile(`^<([a-zA-Z][a-zA-Z0-9+.-]{1,31}:[^\s<>]*|[a-zA-Z0-9.!#$%&'*+/=?^_` + "`" + `{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*)>`)
+)
+
+func isBlankLine(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+// mdIndent returns the number of leading spaces of line
+func mdIndent(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// mdInterrupts reports whether line starts a block that ends a paragraph
+func mdInterrupts(line string) bool {
+	return mdHeadingPattern.MatchString(line) || mdFencePattern.MatchString(line) || mdRulePattern.MatchString(line) ||
+		strings.HasPrefix(strings.TrimLeft(line, " "), ">") || mdListItemPattern.MatchString(line) && !isBlankLine(mdListItemPattern.ReplaceAllString(line, ""))
+}
+
+// parseMarkdown splits a markdown document into blocks
+func parseMarkdown(md string) []mdBlock {
+	md = strings.ReplaceAll(strings.ReplaceAll(md, "\r\n", "\n"), "\t", "    ")
+	return parseMarkdownLines(strings.Split(md, "\n"))
+}
+
+func parseMarkdownLines(lines []string) []mdBlock {
+	var blocks []mdBlock
+	for i := 0; i < len(lines); {
+		var line = lines[i]
+		switch {
+		case isBlankLine(line):
+			i++
+		case mdFencePattern.MatchString(line):
+			var m = mdFencePattern.FindStringSubmatch(line)
+			var indent = mdIndent(line)
+			var block = mdBlock{kind: mdCode}
+			if fields := strings.Fields(m[2]); len(fields) > 0 {
+				block.lang = fields[0]
+			}
+			for i++; i < len(lines); i++ {
+				var trimmed = strings.TrimSpace(lines[i])
+				if strings.HasPrefix(trimmed, m[1]) && strings.Trim(trimmed, m[1][:1]) == "" {
+					i++
+					break
+				}
+				block.lines = append(block.lines, strings.TrimPrefix(lines[i], strings.Repeat(" ", min(indent, mdIndent(lines[i])))))
+			}
+			blocks = append(blocks, block)
+		case mdHeadingPattern.MatchString(line):
+			var m = mdHeadingPattern.FindStringSubmatch(line)
+			blocks = append(blocks, mdBlock{kind: mdHeading, level: len(m[1]), lines: []string{m[2]}})
+			i++
+		case mdRulePattern.MatchString(line):
+			blocks = append(blocks, mdBlock{kind: mdRule})
+			i++
+		case strings.HasPrefix(strings.TrimLeft(line, " "), ">"):
+			var quoted []string
+			for ; i < len(lines) && strings.HasPrefix(strings.TrimLeft(lines[i], " "), ">"); i++ {
+				var content = strings.TrimPrefix(strings.TrimLeft(lines[i], " "), ">")
+				quoted = append(quoted, strings.TrimPrefix(content, " "))
+			}
+			blocks = append(blocks, mdBlock{kind: mdQuote, children: parseMarkdownLines(quoted)})
+		case mdListItemPattern.MatchString(line):
+			var block mdBlock
+			block, i = parseMarkdownList(lines, i)
+			blocks = append(blocks, block)
+		default:
+			var block = mdBlock{kind: mdParagraph}
+			for ; i < len(lines) && !isBlankLine(lines[i]); i++ {
+				if len(block.lines) > 0 {
+					if m := mdSetextPattern.FindStringSubmatch(lines[i]); m != nil {
+						block.kind, block.level = mdHeading, 1
+						if m[1][0] == '-' {
+							block.level = 2
+						}
+						i++
+						break
+					}
+					if mdInterrupts(lines[i]) {
+						break
+					}
+				}
+				block.lines = append(block.lines, strings.TrimLeft(lines[i], " "))
+			}
+			blocks = append(blocks, block)
+		}
+	}
+	return blocks
+}
+
+// parseMarkdownList parses the list starting at lines[i], returning it and the index of the line after it
+func parseMarkdownList(lines []string, i int) (mdBlock, int) {
+	var first = mdListItemPattern.FindStringSubmatch(lines[i])
+	var block = mdBlock{kind: mdList, ordered: first[3] != ""}
+	if block.ordered {
+		block.start, _ = strconv.Atoi(first[3])
+	}
+	var marker = first[2][len(first[2])-1:]
+	for i < len(lines) {
+		var m = mdListItemPattern.FindStringSubmatch(lines[i])
+		if m == nil || (m[3] != "") != block.ordered || m[2][len(m[2])-1:] != marker || mdRulePattern.MatchString(lines[i]) {
+			break
+		}
+		var contentIndent = len(m[0])
+		if isBlankLine(m[4]) || len(m[4]) > 4 {
+			contentIndent = len(m[1]) + len(m[2]) + 1
+		}
+		var item = []string{strings.TrimLeft(lines[i][min(len(m[0]), len(lines[i])):], " ")}
+		for i++; i < len(lines); i++ {
+			var line = lines[i]
+			if isBlankLine(line) {
+				// A blank line continues the item only when it's followed by indented content
+				var next = i + 1
+				for next < len(lines) && isBlankLine(lines[next]) {
+					next++
+				}
+				if next < len(lines) && mdIndent(lines[next]) >= contentIndent {
+					item = append(item, "")
+					continue
+				}
+				break
+			}
+			if mdIndent(line) >= contentIndent {
+				item = append(item, line[contentIndent:])
+				continue
+			}
+			// Lazy continuation of the item's paragraph
+			if !isBlankLine(item[len(item)-1]) && !mdInterrupts(line) {
+				item = append(item, strings.TrimLeft(line, " "))
+				continue
+			}
+			break
+		}
+		block.items = append(block.items, parseMarkdownLines(item))
+		// Blank lines between items
+		var next = i
+		for next < len(lines) && isBlankLine(lines[next]) {
+			next++
+		}
+		if next < len(lines) && mdListItemPattern.MatchString(lines[next]) {
+			i = next
+		}
+	}
+	return block, i
+}
+
+type mdInlineKind int
+
+const (
+	mdText mdInlineKind = iota
+	mdCodeSpan
+	mdEmphasis
+	mdStrong
+	mdLink
+	mdHardBreak
+	mdSoftBreak
+)
+
+// mdInline is a span of inline markdown
+type mdInline struct {
+	kind        mdInlineKind
+	text        string
+	href, title string
+	children    []mdInline
+}
+
+// isMarkdownPunct reports whether b is ASCII punctuation, which may be backslash escaped
+func isMarkdownPunct(b byte) bool {
+	return b < utf8.RuneSelf && unicode.IsPunct(rune(b)) || strings.IndexByte("$+<=>^`|~", b) >= 0
+}
+
+// parseMarkdownInline parses the inline markdown of a paragraph or heading
+func parseMarkdownInline(s string) []mdInline {
+	var nodes []mdInline
+	var text strings.Builder
+	var flush = func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, mdInline{kind: mdText, text: text.String()})
+			text.Reset()
+		}
+	}
+	for i := 0; i < len(s); {
+		var c = s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && s[i+1] == '\n':
+			flush()
+			nodes = append(nodes, mdInline{kind: mdHardBreak})
+			i += 2
+			continue
+		case c == '\\' && i+1 < len(s) && isMarkdownPunct(s[i+1]):
+			text.WriteByte(s[i+1])
+			i += 2
+			continue
+		case c == '\n':
+			var trimmed = strings.TrimRight(text.String(), " ")
+			var hard = text.Len()-len(trimmed) >= 2
+			text.Reset()
+			text.WriteString(trimmed)
+			flush()
+			if hard {
+				nodes = append(nodes, mdInline{kind: mdHardBreak})
+			} else {
+				nodes = append(nodes, mdInline{kind: mdSoftBreak})
+			}
+			i++
+			for i < len(s) && s[i] == ' ' {
+				i++
+			}
+			continue
+		case c == '`':
+			if code, end, ok := mdCodeSpanAt(s, i); ok {
+				flush()
+				nodes = append(nodes, mdInline{kind: mdCodeSpan, text: code})
+				i = end
+				continue
+			}
+			var run = mdRunLength(s, i)
+			text.WriteString(s[i : i+run])
+			i += run
+			continue
+		case c == '[':
+			if link, end, ok := mdLinkAt(s, i); ok {
+				flush()
+				nodes = append(nodes, link)
+				i = end
+				continue
+			}
+		case c == '<':
+			if m := mdAutolinkPattern.FindStringSubmatch(s[i:]); m != nil {
+				flush()
+				var href = m[1]
+				if !strings.Contains(href, ":") {
+					href = "mailto:" + href
+				}
+				nodes = append(nodes, mdInline{kind: mdLink, href: href, children: []mdInline{{kind: mdText, text: m[1]}}})
+				i += len(m[0])
+				continue
+			}
+		case c == '*' || c == '_':
+			if node, end, ok := mdEmphasisAt(s, i); ok {
+				flush()
+				nodes = append(nodes, node)
+				i = end
+				continue
+			}
+			var run = mdRunLength(s, i)
+			text.WriteString(s[i : i+run])
+			i += run
+			continue
+		}
+		text.WriteByte(c)
+		i++
+	}
+	flush()
+	return nodes
+}
+
+// mdRunLength returns the length of the run of the character at s[i]
+func mdRunLength(s string, i int) int {
+	var n = 1
+	for i+n < len(s) && s[i+n] == s[i] {
+		n++
+	}
+	return n
+}
+
+// mdCodeSpanAt parses a code span opened by the backtick run at s[i]
+func mdCodeSpanAt(s string, i int) (code string, end int, ok bool) {
+	var run = mdRunLength(s, i)
+	for j := i + run; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		var closing = mdRunLength(s, j)
+		if closing == run {
+			code = strings.ReplaceAll(s[i+run:j], "\n", " ")
+			if len(code) > 1 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+				code = code[1 : len(code)-1]
+			}
+			return code, j + closing, true
+		}
+		j += closing
+	}
+	return "", 0, false
+}
+
+// mdSkip returns the index after the escape or code span at s[j], or j+1
+func mdSkip(s string, j int) int {
+	if s[j] == '\\' && j+1 < len(s) {
+		return j + 2
+	}
+	if s[j] == '`' {
+		if _, end, ok := mdCodeSpanAt(s, j); ok {
+			return end
+		}
+		return j + mdRunLength(s, j)
+	}
+	return j + 1
+}
+
+// mdLinkAt parses an inline link [text](destination "title") opened by the bracket at s[i]
+func mdLinkAt(s string, i int) (link mdInline, end int, ok bool) {
+	var depth int
+	var j = i
+	for ; j < len(s); j = mdSkip(s, j) {
+		if s[j] == '[' {
+			depth++
+		} else if s[j] == ']' {
+			depth--
+			if depth == 0 {
+				break
+			}
+		}
+	}
+	if j >= len(s) || j+1 >= len(s) || s[j+1] != '(' {
+		return mdInline{}, 0, false
+	}
+	// The destination may contain balanced parentheses
+	var close, parens = -1, 0
+	for k := j + 2; k < len(s) && close < 0; k++ {
+		switch s[k] {
+		case '\\':
+			k++
+		case '(':
+			parens++
+		case ')':
+			if parens == 0 {
+				close = k - (j + 2)
+			}
+			parens--
+		}
+	}
+	if close < 0 {
+		return mdInline{}, 0, false
+	}
+	var dest = strings.TrimSpace(s[j+2 : j+2+close])
+	var href, title = dest, ""
+	if k := strings.IndexAny(dest, " \n"); k >= 0 {
+		href, title = dest[:k], strings.TrimSpace(dest[k:])
+		if len(title) < 2 || !(title[0] == '"' && title[len(title)-1] == '"' || title[0] == '\'' && title[len(title)-1] == '\'') {
+			return mdInline{}, 0, false
+		}
+		title = title[1 : len(title)-1]
+	}
+	href = strings.TrimSuffix(strings.TrimPrefix(href, "<"), ">")
+	return mdInline{kind: mdLink, href: href, title: title, children: parseMarkdownInline(s[i+1 : j])}, j + 3 + close, true
+}
+
+// mdEmphasisAt parses emphasis or strong emphasis opened by the delimiter run at s[i]
+// The opener must be followed by non-space and the closer, a run of the same length, preceded by non-space.
+// Underscores also can't open or close within a word.
+func mdEmphasisAt(s string, i int) (node mdInline, end int, ok bool) {
+	var c = s[i]
+	var run = mdRunLength(s, i)
+	if run > 3 || i+run >= len(s) || s[i+run] == ' ' || s[i+run] == '\n' {
+		return mdInline{}, 0, false
+	}
+	if c == '_' && i > 0 && isWordByte(s[i-1]) {
+		return mdInline{}, 0, false
+	}
+	for j := i + run; j < len(s); {
+		if s[j] != c {
+			j = mdSkip(s, j)
+			continue
+		}
+		var closing = mdRunLength(s, j)
+		if closing == run && s[j-1] != ' ' && s[j-1] != '\n' && !(c == '_' && j+closing < len(s) && isWordByte(s[j+closing])) {
+			var children = parseMarkdownInline(s[i+run : j])
+			switch run {
+			case 1:
+				node = mdInline{kind: mdEmphasis, children: children}
+			case 2:
+				node = mdInline{kind: mdStrong, children: children}
+			default:
+				node = mdInline{kind: mdStrong, children: []mdInline{{kind: mdEmphasis, children: children}}}
+			}
+			return node, j + closing, true
+		}
+		j += closing
+	}
+	return mdInline{}, 0, false
+}
+
+func isWordByte(b byte) bool {
+	return b >= utf8.RuneSelf || unicode.IsLetter(rune(b)) || unicode.IsDigit(rune(b))
+}
+
+// mdSafeHref reports whether a link destination is relative or uses a scheme safe to render
+func mdSafeHref(href string) bool {
+	var colon = strings.IndexByte(href, ':')
+	if colon < 0 || strings.ContainsAny(href[:colon], "/?#") {
+		return true
+	}
+	switch strings.ToLower(href[:colon]) {
+	case "http", "https", "mailto", "tel":
+		return true
+	}
+	return false
+}
+
+func renderMarkdownInlineHTML(b *strings.Builder, nodes []mdInline) {
+	for _, n := range nodes {
+		switch n.kind {
+		case mdText:
+			b.WriteString(html.EscapeString(n.text))
+		case mdCodeSpan:
+			b.WriteString("<code>" + html.EscapeString(n.text) + "</code>")
+		case mdEmphasis:
+			b.WriteString("<em>")
+			renderMarkdownInlineHTML(b, n.children)
+			b.WriteString("</em>")
+		case mdStrong:
+			b.WriteString("<strong>")
+			renderMarkdownInlineHTML(b, n.children)
+			b.WriteString("</strong>")
+		case mdLink:
+			if !mdSafeHref(n.href) {
+				renderMarkdownInlineHTML(b, n.children)
+				continue
+			}
+			b.WriteString(`<a href="` + html.EscapeString(n.href) + `"`)
+			if n.title != "" {
+				b.WriteString(` title="` + html.EscapeString(n.title) + `"`)
+			}
+			b.WriteString(">")
+			renderMarkdownInlineHTML(b, n.children)
+			b.WriteString("</a>")
+		case mdHardBreak:
+			b.WriteString("<br />\n")
+		case mdSoftBreak:
+			b.WriteString("\n")
+		}
+	}
+}
+
+func renderMarkdownInlineText(b *strings.Builder, nodes []mdInline) {
+	for _, n := range nodes {
+		switch n.kind {
+		case mdText, mdCodeSpan:
+			b.WriteString(n.text)
+		case mdEmphasis, mdStrong:
+			renderMarkdownInlineText(b, n.children)
+		case mdLink:
+			var text strings.Builder
+			renderMarkdownInlineText(&text, n.children)
+			b.WriteString(text.String())
+			if mdSafeHref(n.href) && n.href != text.String() && n.href != "mailto:"+text.String() {
+				b.WriteString(" (" + n.href + ")")
+			}
+		case mdHardBreak:
+			b.WriteString("\n")
+		case mdSoftBreak:
+			b.WriteString(" ")
+		}
+	}
+}
+
+func renderMarkdownHTML(b *strings.Builder, blocks []mdBlock) {
+	for _, block := range blocks {
+		switch block.kind {
+		case mdParagraph:
+			b.WriteString("<p>")
+			renderMarkdownInlineHTML(b, parseMarkdownInline(strings.Join(block.lines, "\n")))
+			b.WriteString("</p>\n")
+		case mdHeading:
+			var tag = "h" + strconv.Itoa(block.level)
+			b.WriteString("<" + tag + ">")
+			renderMarkdownInlineHTML(b, parseMarkdownInline(strings.Join(block.lines, "\n")))
+			b.WriteString("</" + tag + ">\n")
+		case mdRule:
+			b.WriteString("<hr />\n")
+		case mdCode:
+			b.WriteString("<pre><code")
+			if block.lang != "" {
+				b.WriteString(` class="language-` + html.EscapeString(block.lang) + `"`)
+			}
+			b.WriteString(">")
+			for _, line := range block.lines {
+				b.WriteString(html.EscapeString(line) + "\n")
+			}
+			b.WriteString("</code></pre>\n")
+		case mdQuote:
+			b.WriteString("<blockquote>\n")
+			renderMarkdownHTML(b, block.children)
+			b.WriteString("</blockquote>\n")
+		case mdList:
+			var tag = "ul"
+			if block.ordered {
+				tag = "ol"
+			}
+			b.WriteString("<" + tag)
+			if block.ordered && block.start != 1 {
+				b.WriteString(` start="` + strconv.Itoa(block.start) + `"`)
+			}
+			b.WriteString(">\n")
+			for _, item := range block.items {
+				b.WriteString("<li>")
+				for i, child := range item {
+					if child.kind == mdParagraph {
+						if i > 0 {
+							b.WriteString("\n")
+						}
+						renderMarkdownInlineHTML(b, parseMarkdownInline(strings.Join(child.lines, "\n")))
+						continue
+					}
+					if i == 0 || item[i-1].kind == mdParagraph {
+						b.WriteString("\n")
+					}
+					renderMarkdownHTML(b, item[i:i+1])
+				}
+				b.WriteString("</li>\n")
+			}
+			b.WriteString("</" + tag + ">\n")
+		}
+	}
+}
+
+// renderMarkdownText renders each block as plain text
+func renderMarkdownText(blocks []mdBlock) []string {
+	var paragraphs []string
+	for _, block := range blocks {
+		var b strings.Builder
+		switch block.kind {
+		case mdParagraph, mdHeading:
+			renderMarkdownInlineText(&b, parseMarkdownInline(strings.Join(block.lines, "\n")))
+		case mdRule:
+			continue
+		case mdCode:
+			b.WriteString(strings.Join(block.lines, "\n"))
+		case mdQuote:
+			b.WriteString(strings.Join(renderMarkdownText(block.children), "\n\n"))
+		case mdList:
+			for n, item := range block.items {
+				var bullet = "- "
+				if block.ordered {
+					bullet = strconv.Itoa(block.start+n) + ". "
+				}
+				if n > 0 {
+					b.WriteString("\n")
+				}
+				var content = strings.Join(renderMarkdownText(item), "\n")
+				b.WriteString(bullet + strings.ReplaceAll(content, "\n", "\n"+strings.Repeat(" ", len(bullet))))
+			}
+		}
+		paragraphs = append(paragraphs, b.String())
+	}
+	return paragraphs
+}
+
+// markdownToHTML renders markdown as HTML, see the supported subset above
+func markdownToHTML(md string) string {
+	var b strings.Builder
+	renderMarkdownHTML(&b, parseMarkdown(md))
+	return b.String()
+}
+
+// markdownStrip renders markdown as plain text, removing emphasis markers, keeping list bullets and code verbatim
+// and following link text with the URL in parentheses
+func markdownStrip(md string) string {
+	return strings.Join(renderMarkdownText(parseMarkdown(md)), "\n\n")
+}
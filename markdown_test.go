@@ -0,0 +1,72 @@
+package template
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestMarkdownGolden renders each testdata/markdown/*.md file and compares it to the .html and .txt files of the same name
+func TestMarkdownGolden(t *testing.T) {
+	files, err := filepath.Glob("testdata/markdown/*.md")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(files) == 0 {
+		t.Error("No golden files found")
+		return
+	}
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		var base = strings.TrimSuffix(file, ".md")
+		for ext, render := range map[string]func(string) string{".html": markdownToHTML, ".txt": markdownStrip} {
+			expected, err := os.ReadFile(base + ext)
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+			if res := render(string(src)); res != string(expected) {
+				t.Errorf("Unexpected result for %s%s:\n%s", base, ext, res)
+			}
+			if res := render(string(src)); res != render(string(src)) {
+				t.Errorf("Non-deterministic result for %s%s", base, ext)
+			}
+		}
+	}
+}
+
+func TestMarkdownInline(t *testing.T) {
+	for md, expected := range map[string]string{
+		``:                           ``,
+		`plain`:                      "<p>plain</p>\n",
+		`*a **b** c*`:                "<p><em>a <strong>b</strong> c</em></p>\n",
+		`__bold__ and _em_`:          "<p><strong>bold</strong> and <em>em</em></p>\n",
+		`[a *b*](/x "t")`:            "<p><a href=\"/x\" title=\"t\">a <em>b</em></a></p>\n",
+		`[w](https://w.org/a_(b))`:   "<p><a href=\"https://w.org/a_(b)\">w</a></p>\n",
+		"`<tag>`":                    "<p><code>&lt;tag&gt;</code></p>\n",
+		`[not a link]`:               "<p>[not a link]</p>\n",
+		"line\\\nbreak":              "<p>line<br />\nbreak</p>\n",
+		"* not emphasis *":           "<ul>\n<li>not emphasis *</li>\n</ul>\n",
+		"a * b":                      "<p>a * b</p>\n",
+		`[x](data:text/html;base64)`: "<p>x</p>\n",
+	} {
+		if res := markdownToHTML(md); res != expected {
+			t.Errorf(`Unexpected result %q for %q`, res, md)
+		}
+	}
+
+	res, err := InterpolateStrict(map[string]interface{}{"body": "Hi *{{name}}*, see [docs](https://example.com)"}, `{{ markdownToHTML .body }}|{{ markdownStrip .body }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "<p>Hi <em>{{name}}</em>, see <a href=\"https://example.com\">docs</a></p>\n|Hi {{name}}, see docs (https://example.com)" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
@@ -0,0 +1,63 @@
+package template
+
+import (
+	"strings"
+	"unicode"
+)
+
+// nameSuffixes are generational and professional suffixes kept with the last name by nameParts
+var nameSuffixes = map[string]bool{
+	"jr": true, "jr.": true, "sr": true, "sr.": true, "ii": true, "iii": true, "iv": true,
+	"phd": true, "ph.d.": true, "md": true, "m.d.": true, "esq": true, "esq.": true,
+}
+
+// initials returns the upper cased first letter of each of the first max words of name
+// A max of zero or less uses every word. Leading punctuation of a word, as in "(Bob)", is skipped.
+func initials(name string, max interface{}) (string, error) {
+	n, err := interfaceToWholeInt64(max)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	var count int64
+	for _, word := range strings.Fields(name) {
+		if n > 0 && count == n {
+			break
+		}
+		for _, r := range word {
+			if unicode.IsLetter(r) || unicode.IsDigit(r) {
+				b.WriteRune(unicode.ToUpper(r))
+				count++
+				break
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// nameParts splits a full name into first, middle and last names
+// The last word is the last name, together with a suffix such as Jr. that follows it, and a name written
+// "Last, First Middle" is reordered. A single word is a first name.
+func nameParts(name string) map[string]interface{} {
+	var words []string
+	if last, rest, ok := strings.Cut(name, ","); ok && !nameSuffixes[strings.ToLower(strings.TrimSpace(rest))] {
+		words = append(strings.Fields(rest), strings.Fields(last)...)
+	} else {
+		words = strings.Fields(strings.ReplaceAll(name, ",", " "))
+	}
+	var parts = map[string]interface{}{"first": "", "middle": "", "last": ""}
+	if len(words) == 0 {
+		return parts
+	}
+	parts["first"] = words[0]
+	words = words[1:]
+	var lastLen = 1
+	if len(words) > 1 && nameSuffixes[strings.ToLower(words[len(words)-1])] {
+		lastLen = 2
+	}
+	if len(words) >= lastLen {
+		parts["last"] = strings.Join(words[len(words)-lastLen:], " ")
+		parts["middle"] = strings.Join(words[:len(words)-lastLen], " ")
+	}
+	return parts
+}
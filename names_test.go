@@ -0,0 +1,66 @@
+package template
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInitials(t *testing.T) {
+	var cases = []struct {
+		name     string
+		max      interface{}
+		expected string
+	}{
+		{"John Smith", 2, "JS"},
+		{"john ronald reuel tolkien", 2, "JR"},
+		{"john ronald reuel tolkien", 0, "JRRT"},
+		{"  Émile   Zola ", "2", "ÉZ"},
+		{"ø ñ", 5, "ØÑ"},
+		{"山田 太郎", 2, "山太"},
+		{"Robert (Bob) Jones", 3, "RBJ"},
+		{"", 2, ""},
+	}
+	for _, c := range cases {
+		res, err := initials(c.name, c.max)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if res != c.expected {
+			t.Errorf(`Unexpected initials %q for %q`, res, c.name)
+		}
+	}
+	_, err := initials("John Smith", 1.5)
+	if err == nil {
+		t.Errorf(`Expected a fractional max to fail`)
+	}
+}
+
+func TestNameParts(t *testing.T) {
+	var cases = map[string][3]string{
+		"Cher":                   {"Cher", "", ""},
+		"John Smith":             {"John", "", "Smith"},
+		"José María Aznar López": {"José", "María Aznar", "López"},
+		"Smith, John Paul":       {"John", "Paul", "Smith"},
+		"Martin Luther King Jr.": {"Martin", "Luther", "King Jr."},
+		"John Smith, Jr.":        {"John", "", "Smith Jr."},
+		"Zoë  Ångström":          {"Zoë", "", "Ångström"},
+		"毛 泽东":                   {"毛", "", "泽东"},
+		"":                       {"", "", ""},
+	}
+	for name, expected := range cases {
+		var res = nameParts(name)
+		var want = map[string]interface{}{"first": expected[0], "middle": expected[1], "last": expected[2]}
+		if !reflect.DeepEqual(res, want) {
+			t.Errorf(`Unexpected parts %v for %q`, res, name)
+		}
+	}
+	res, err := InterpolateStrict(map[string]interface{}{"name": "Ada King Lovelace"}, `{{ (nameParts .name).first }} {{ initials .name 0 }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "Ada AKL" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
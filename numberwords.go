@@ -0,0 +1,93 @@
+package template
+
+import (
+	"strconv"
+	"strings"
+)
+
+var smallNumberWords = [...]string{
+	"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten",
+	"eleven", "twelve", "thirteen", "fourteen", "fifteen", "sixteen", "seventeen", "eighteen", "nineteen",
+}
+
+var tensWords = [...]string{"", "", "twenty", "thirty", "forty", "fifty", "sixty", "seventy", "eighty", "ninety"}
+
+// scaleWords name each power of a thousand, up to the largest that fits in an int64
+var scaleWords = [...]string{"", "thousand", "million", "billion", "trillion", "quadrillion", "quintillion"}
+
+// toOrdinal formats an integer with its English ordinal suffix, such as 1st, 12th or 23rd
+// Negative numbers keep their sign, -3 is -3rd
+func toOrdinal(n interface{}) (string, error) {
+	i, err := interfaceToWholeInt64(n)
+	if err != nil {
+		return "", err
+	}
+	var abs = i % 100
+	if abs < 0 {
+		abs = -abs
+	}
+	var suffix = "th"
+	if abs < 11 || abs > 13 {
+		switch abs % 10 {
+		case 1:
+			suffix = "st"
+		case 2:
+			suffix = "nd"
+		case 3:
+			suffix = "rd"
+		}
+	}
+	return strconv.FormatInt(i, 10) + suffix, nil
+}
+
+// spellNumber spells an integer in English words, such as "one thousand two hundred thirty-four"
+// Negative numbers are prefixed with "minus"
+func spellNumber(n interface{}) (string, error) {
+	i, err := interfaceToWholeInt64(n)
+	if err != nil {
+		return "", err
+	}
+	if i == 0 {
+		return smallNumberWords[0], nil
+	}
+	var words []string
+	// Negate as unsigned so the smallest int64 doesn't overflow
+	var u = uint64(i)
+	if i < 0 {
+		words = append(words, "minus")
+		u = -u
+	}
+	var groups []uint64
+	for ; u > 0; u /= 1000 {
+		groups = append(groups, u%1000)
+	}
+	for scale := len(groups) - 1; scale >= 0; scale-- {
+		if groups[scale] == 0 {
+			continue
+		}
+		words = append(words, spellHundreds(groups[scale])...)
+		if scale > 0 {
+			words = append(words, scaleWords[scale])
+		}
+	}
+	return strings.Join(words, " "), nil
+}
+
+// spellHundreds spells a number from 1 to 999
+func spellHundreds(n uint64) []string {
+	var words []string
+	if n >= 100 {
+		words = append(words, smallNumberWords[n/100], "hundred")
+		n %= 100
+	}
+	switch {
+	case n == 0:
+	case n < 20:
+		words = append(words, smallNumberWords[n])
+	case n%10 == 0:
+		words = append(words, tensWords[n/10])
+	default:
+		words = append(words, tensWords[n/10]+"-"+smallNumberWords[n%10])
+	}
+	return words
+}
@@ -0,0 +1,65 @@
+package template
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+)
+
+func TestToOrdinal(t *testing.T) {
+	var cases = map[interface{}]string{
+		0: "0th", 1: "1st", 2: "2nd", 3: "3rd", 4: "4th", 11: "11th", 12: "12th", 13: "13th",
+		21: "21st", 22: "22nd", 101: "101st", 111: "111th", 112: "112th", 1003: "1003rd",
+		-1: "-1st", -3: "-3rd", -11: "-11th", json.Number("23"): "23rd", "42": "42nd", 2.0: "2nd",
+	}
+	for n, expected := range cases {
+		res, err := toOrdinal(n)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if res != expected {
+			t.Errorf(`Unexpected result %q for %v`, res, n)
+		}
+	}
+	_, err := toOrdinal(2.5)
+	if err == nil {
+		t.Errorf(`Expected a fractional number to fail`)
+	}
+}
+
+func TestSpellNumber(t *testing.T) {
+	var cases = map[interface{}]string{
+		0:                         "zero",
+		7:                         "seven",
+		13:                        "thirteen",
+		40:                        "forty",
+		42:                        "forty-two",
+		100:                       "one hundred",
+		115:                       "one hundred fifteen",
+		1000:                      "one thousand",
+		1234:                      "one thousand two hundred thirty-four",
+		1000001:                   "one million one",
+		json.Number("2500000000"): "two billion five hundred million",
+		-3:                        "minus three",
+		int64(math.MinInt64):      "minus nine quintillion two hundred twenty-three quadrillion three hundred seventy-two trillion thirty-six billion eight hundred fifty-four million seven hundred seventy-five thousand eight hundred eight",
+	}
+	for n, expected := range cases {
+		res, err := spellNumber(n)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if res != expected {
+			t.Errorf(`Unexpected result %q for %v`, res, n)
+		}
+	}
+	res, err := InterpolateStrict(map[string]interface{}{"count": json.Number("3")}, `{{ spellNumber .count }} items, {{ toOrdinal .count }} try`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "three items, 3rd try" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
@@ -0,0 +1,80 @@
+package template
+
+import (
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Observer receives timings of template executions and of the http requests templates make, e.g. for metrics
+// Methods may be called concurrently
+type Observer interface {
+	// OnExecute is called after each template execution
+	// name is the InterpolateMap key path or template name, or a hash of the source when the template is unnamed
+	OnExecute(name string, duration time.Duration, err error)
+	// OnHTTPRequest is called after each request made by a template function, status is zero when no response was received
+	OnHTTPRequest(url string, status int, duration time.Duration)
+}
+
+// NopObserver is an Observer that does nothing
+type NopObserver struct{}
+
+// OnExecute implementation for NopObserver
+func (NopObserver) OnExecute(name string, duration time.Duration, err error) {}
+
+// OnHTTPRequest implementation for NopObserver
+func (NopObserver) OnHTTPRequest(url string, status int, duration time.Duration) {}
+
+// CountingObserver is an Observer counting executions, errors and http requests
+type CountingObserver struct {
+	mu           sync.Mutex
+	Executions   map[string]int
+	Errors       map[string]int
+	HTTPRequests map[string]int
+}
+
+// OnExecute implementation for CountingObserver
+func (o *CountingObserver) OnExecute(name string, duration time.Duration, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.Executions == nil {
+		o.Executions = map[string]int{}
+		o.Errors = map[string]int{}
+	}
+	o.Executions[name]++
+	if err != nil {
+		o.Errors[name]++
+	}
+}
+
+// OnHTTPRequest implementation for CountingObserver
+func (o *CountingObserver) OnHTTPRequest(url string, status int, duration time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.HTTPRequests == nil {
+		o.HTTPRequests = map[string]int{}
+	}
+	o.HTTPRequests[url]++
+}
+
+var observer Observer
+
+// SetObserver sets the Observer notified of template executions and http requests, nil disables observation
+func SetObserver(o Observer) {
+	observer = o
+}
+
+// observeExecute reports an execution started at start to the observer
+// name is used when set, otherwise src is hashed
+func observeExecute(name, src string, start time.Time, err error) {
+	if observer == nil {
+		return
+	}
+	if name == "" || name == RootTemplate.Name() {
+		var h = fnv.New64a()
+		h.Write([]byte(src))
+		name = strconv.FormatUint(h.Sum64(), 16)
+	}
+	observer.OnExecute(name, time.Since(start), err)
+}
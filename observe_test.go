@@ -0,0 +1,65 @@
+package template
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestObserver(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	var o CountingObserver
+	SetObserver(&o)
+	defer SetObserver(nil)
+
+	_, err := InterpolateMap(map[string]interface{}{"url": srv.URL}, map[string]interface{}{
+		"status": `{{ (http "GET" .url (dict)).StatusCode }}`,
+		"nested": map[string]interface{}{"id": "{{ .id }}"},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = InterpolateMap(nil, map[string]interface{}{"status": `{{ parseJSON "{" }}`})
+	if err == nil {
+		t.Errorf(`Expected parseJSON to fail`)
+	}
+	tmpl, err := Parse(`{{ .x }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = tmpl.ExecuteToString(nil)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if o.Executions["status"] != 2 || o.Errors["status"] != 1 || o.Executions["nested.id"] != 1 {
+		t.Errorf(`Unexpected executions %v errors %v`, o.Executions, o.Errors)
+	}
+	if len(o.Executions) != 3 {
+		t.Errorf(`Expected the unnamed template to be reported by source hash, got %v`, o.Executions)
+	}
+	if o.HTTPRequests[srv.URL] != 1 {
+		t.Errorf(`Unexpected http requests %v`, o.HTTPRequests)
+	}
+}
+
+func TestObserveExecuteDisabled(t *testing.T) {
+	SetObserver(nil)
+	var err = errors.New("x")
+	allocs := testing.AllocsPerRun(100, func() {
+		observeExecute("", "{{ .x }}", time.Now(), err)
+	})
+	if allocs != 0 {
+		t.Errorf(`Expected no allocations without an observer, got %v`, allocs)
+	}
+	var _ Observer = NopObserver{}
+}
@@ -0,0 +1,162 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// noValueSentinels are the strings text/template renders for missing and nil values
+var noValueSentinels = []string{"<no value>", "<nil>"}
+
+var rejectNoValue bool
+
+// RejectNoValue makes Execute and Interpolate fail when the rendered output contains "<no value>" or "<nil>"
+// Output is buffered before being written so nothing is written when it is rejected
+func RejectNoValue(enable bool) {
+	rejectNoValue = enable
+}
+
+var maxOutputBytes int64
+
+// SetMaxOutputBytes limits the number of bytes a template may render, aborting execution with an OutputLimitError
+// Zero, the default, is unlimited
+func SetMaxOutputBytes(n int64) {
+	maxOutputBytes = n
+}
+
+// OutputLimitError is returned when a template renders more than the configured maximum output
+type OutputLimitError struct {
+	Limit int64
+}
+
+// Error implementation for OutputLimitError
+func (e *OutputLimitError) Error() string {
+	return fmt.Sprintf("template output exceeds the limit of %d bytes", e.Limit)
+}
+
+// limitWriter fails writes once more than its limit has been written
+type limitWriter struct {
+	w         io.Writer
+	limit     int64
+	remaining int64
+}
+
+func (lw *limitWriter) Write(p []byte) (int, error) {
+	if int64(len(p)) > lw.remaining {
+		n, _ := lw.w.Write(p[:lw.remaining])
+		lw.remaining -= int64(n)
+		return n, &OutputLimitError{Limit: lw.limit}
+	}
+	n, err := lw.w.Write(p)
+	lw.remaining -= int64(n)
+	return n, err
+}
+
+// limitOutput wraps w to enforce the maximum output, if one is set
+func limitOutput(w io.Writer) io.Writer {
+	if maxOutputBytes <= 0 {
+		return w
+	}
+	return &limitWriter{w: w, limit: maxOutputBytes, remaining: maxOutputBytes}
+}
+
+// NoValueError reports a "<no value>" or "<nil>" found in rendered output
+type NoValueError struct {
+	// Value is the sentinel string found
+	Value string
+	// Offset is the byte offset of the sentinel in the output
+	Offset int
+}
+
+// Error implementation for NoValueError
+func (e *NoValueError) Error() string {
+	return fmt.Sprintf("output contains %q at offset %d", e.Value, e.Offset)
+}
+
+// checkNoValue returns a NoValueError for the first sentinel in out
+func checkNoValue(out []byte) error {
+	var found *NoValueError
+	for _, sentinel := range noValueSentinels {
+		if i := bytes.Index(out, []byte(sentinel)); i >= 0 && (found == nil || i < found.Offset) {
+			found = &NoValueError{Value: sentinel, Offset: i}
+		}
+	}
+	if found == nil {
+		return nil
+	}
+	return found
+}
+
+// ExecuteStrict executes the template, failing if the output contains "<no value>" or "<nil>" regardless of RejectNoValue
+func (t *Template) ExecuteStrict(w io.Writer, data interface{}) error {
+	return t.execute(w, data, true, nil)
+}
+
+// jsonErrorContext is the number of bytes either side of a syntax error included in an InvalidJSONError
+const jsonErrorContext = 20
+
+// InvalidJSONError reports rendered output that isn't a valid JSON document
+type InvalidJSONError struct {
+	// Offset is the byte offset in the output where the error was detected
+	Offset int64
+	// Snippet is the output surrounding the offset
+	Snippet string
+	// Err is the underlying encoding/json error
+	Err error
+}
+
+// Error implementation for InvalidJSONError
+func (e *InvalidJSONError) Error() string {
+	return fmt.Sprintf("invalid JSON output at offset %d near %q: %v", e.Offset, e.Snippet, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *InvalidJSONError) Unwrap() error {
+	return e.Err
+}
+
+// compactJSON validates and compacts out, returning an InvalidJSONError when it isn't valid JSON
+func compactJSON(out []byte) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	var err = json.Compact(&buf, out)
+	if err == nil {
+		return json.RawMessage(buf.Bytes()), nil
+	}
+	var offset = int64(len(out))
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		offset = syntaxErr.Offset
+	}
+	var start, end = offset - jsonErrorContext, offset + jsonErrorContext
+	if start < 0 {
+		start = 0
+	}
+	if end > int64(len(out)) {
+		end = int64(len(out))
+	}
+	return nil, &InvalidJSONError{Offset: offset, Snippet: string(out[start:end]), Err: err}
+}
+
+// ExecuteToValidJSON executes the template and returns the output compacted, failing if it isn't a valid JSON document
+func (t *Template) ExecuteToValidJSON(data interface{}) (json.RawMessage, error) {
+	var tBuf bytes.Buffer
+	var err = t.Execute(&tBuf, data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return compactJSON(tBuf.Bytes())
+}
+
+// InterpolateToValidJSON interpolates a template string with data, failing if the output isn't a valid JSON document
+func InterpolateToValidJSON(data interface{}, text string) (json.RawMessage, error) {
+	res, err := InterpolateStrict(data, text)
+	if err != nil {
+		return nil, err
+	}
+	return compactJSON([]byte(res))
+}
@@ -0,0 +1,182 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRejectNoValue(t *testing.T) {
+	var data = map[string]interface{}{"date": (*string)(nil)}
+	var src = `sent at {{ .date }} to {{ .missing }}`
+
+	res, err := InterpolateStrict(data, src)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "sent at <nil> to <no value>" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+
+	RejectNoValue(true)
+	defer RejectNoValue(false)
+
+	_, err = InterpolateStrict(data, src)
+	var noValue *NoValueError
+	if !errors.As(err, &noValue) {
+		t.Errorf(`Expected NoValueError, got %v`, err)
+		return
+	}
+	if noValue.Value != "<nil>" || noValue.Offset != 8 {
+		t.Errorf(`Unexpected NoValueError %+v`, noValue)
+	}
+
+	var tmpl *Template
+	err = json.Unmarshal([]byte(`"{{ .name }}"`), &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{})
+	if !errors.As(err, &noValue) {
+		t.Errorf(`Expected NoValueError, got %v`, err)
+	}
+	if buf.Len() != 0 {
+		t.Errorf(`Expected no output to be written, got %q`, buf.String())
+	}
+}
+
+func TestExecuteStrict(t *testing.T) {
+	var tmpl *Template
+	err := json.Unmarshal([]byte(`"{{ .name }}"`), &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	err = tmpl.ExecuteStrict(&buf, map[string]interface{}{"name": "x"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != "x" {
+		t.Errorf(`Unexpected result %q`, buf.String())
+	}
+	err = tmpl.ExecuteStrict(&buf, map[string]interface{}{})
+	var noValue *NoValueError
+	if !errors.As(err, &noValue) {
+		t.Errorf(`Expected NoValueError, got %v`, err)
+	}
+}
+
+func TestExecuteToValidJSON(t *testing.T) {
+	var tmpl *Template
+	err := json.Unmarshal([]byte(`"{ \"id\": {{ .id }}, \"comment\": {{ toJSON .comment }} }"`), &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := tmpl.ExecuteToValidJSON(map[string]interface{}{"id": 1, "comment": `say "hi"`})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(res) != `{"id":1,"comment":"say \"hi\""}` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestInterpolateToValidJSONInvalid(t *testing.T) {
+	_, err := InterpolateToValidJSON(map[string]interface{}{"comment": `say "hi"`}, `{"comment": "{{ .comment }}"}`)
+	var jsonErr *InvalidJSONError
+	if !errors.As(err, &jsonErr) {
+		t.Errorf(`Expected InvalidJSONError, got %v`, err)
+		return
+	}
+	if jsonErr.Offset != 19 || jsonErr.Snippet != `{"comment": "say "hi""}` {
+		t.Errorf(`Unexpected InvalidJSONError %+v`, jsonErr)
+	}
+}
+
+func TestMaxOutputBytes(t *testing.T) {
+	SetMaxOutputBytes(10)
+	defer SetMaxOutputBytes(0)
+
+	var items = make([]int, 1000)
+	res, err := InterpolateStrict(items[:5], `{{ range . }}x{{ end }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "xxxxx" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+
+	_, err = InterpolateStrict(items, `{{ range $i, $_ := . }}{{ $i }}{{ end }}`)
+	var limitErr *OutputLimitError
+	if !errors.As(err, &limitErr) {
+		t.Errorf(`Expected OutputLimitError, got %v`, err)
+	}
+
+	var tmpl *Template
+	err = json.Unmarshal([]byte(`"{{ .body }}"`), &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{"body": "0123456789abcdef"})
+	if !errors.As(err, &limitErr) {
+		t.Errorf(`Expected OutputLimitError, got %v`, err)
+	}
+	if buf.String() != "0123456789" {
+		t.Errorf(`Unexpected partial output %q`, buf.String())
+	}
+}
+
+type failingWriter struct {
+	written bytes.Buffer
+	limit   int
+}
+
+func (f *failingWriter) Write(p []byte) (int, error) {
+	if f.written.Len()+len(p) > f.limit {
+		return 0, errors.New("disk full")
+	}
+	return f.written.Write(p)
+}
+
+func TestInterpolateTo(t *testing.T) {
+	var buf bytes.Buffer
+	var rows = []interface{}{
+		map[string]interface{}{"id": 1, "name": "a"},
+		map[string]interface{}{"id": 2, "name": "b"},
+	}
+	err := InterpolateTo(&buf, rows, `{{ range . }}{{ .id }},{{ .name }}`+"\n"+`{{ end }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != "1,a\n2,b\n" {
+		t.Errorf(`Unexpected result %q`, buf.String())
+	}
+
+	var w = &failingWriter{limit: 6}
+	err = InterpolateTo(w, rows, `{{ range . }}{{ .id }},{{ .name }}`+"\n"+`{{ end }}`)
+	if err == nil || !strings.Contains(err.Error(), "disk full") {
+		t.Errorf(`Expected write error, got %v`, err)
+	}
+	if w.written.String() != "1,a\n2," {
+		t.Errorf(`Expected partial output to have been written, got %q`, w.written.String())
+	}
+
+	err = InterpolateTo(&buf, nil, `{{ .x `)
+	var execErr *ExecError
+	if !errors.As(err, &execErr) {
+		t.Errorf(`Expected parse error as ExecError, got %v`, err)
+	}
+}
@@ -0,0 +1,93 @@
+package template
+
+import (
+	"encoding/json"
+	"errors"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// interpolateJob is a string template within a map passed to InterpolateMapParallel
+type interpolateJob struct {
+	key    string
+	target map[string]interface{}
+	field  string
+	text   string
+}
+
+// InterpolateMapParallel is InterpolateMap interpolating up to concurrency templates at a time
+// A concurrency of zero or less uses GOMAXPROCS. Unlike InterpolateMap every template is executed,
+// and the errors of all the templates that failed are joined, ordered by key path.
+// Each template is a separate render, so memo and the counters aren't shared between them.
+func InterpolateMapParallel(data interface{}, templateMap map[string]interface{}, concurrency int) (map[string]interface{}, error) {
+	var jobs []interpolateJob
+	parsed, err := collectInterpolateJobs(templateMap, "", &jobs)
+	if err != nil {
+		return nil, err
+	}
+	if concurrency <= 0 {
+		concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	var results = make([]string, len(jobs))
+	var errs = make([]error, len(jobs))
+	var wg sync.WaitGroup
+	var sem = make(chan struct{}, concurrency)
+	for i := range jobs {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			results[i], errs[i] = interpolateKey(jobs[i].key, data, jobs[i].text)
+		}(i)
+	}
+	wg.Wait()
+
+	var failed []int
+	for i, job := range jobs {
+		if errs[i] != nil {
+			failed = append(failed, i)
+			continue
+		}
+		job.target[job.field] = results[i]
+	}
+	if len(failed) > 0 {
+		sort.Slice(failed, func(a, b int) bool {
+			return jobs[failed[a]].key < jobs[failed[b]].key
+		})
+		var joined = make([]error, len(failed))
+		for i, f := range failed {
+			joined[i] = errs[f]
+		}
+		return nil, errors.Join(joined...)
+	}
+	return parsed, nil
+}
+
+// collectInterpolateJobs copies templateMap as InterpolateMap would, recording the string templates to interpolate as jobs
+func collectInterpolateJobs(templateMap map[string]interface{}, prefix string, jobs *[]interpolateJob) (map[string]interface{}, error) {
+	var parsed = map[string]interface{}{}
+	for key, i := range templateMap {
+		switch v := i.(type) {
+		case string:
+			*jobs = append(*jobs, interpolateJob{key: prefix + key, target: parsed, field: key, text: v})
+		case json.Number:
+			f, err := v.Float64()
+			if err != nil {
+				return nil, err
+			}
+			parsed[key] = f
+		case map[string]interface{}:
+			deepParsed, err := collectInterpolateJobs(v, prefix+key+".", jobs)
+			if err != nil {
+				return nil, err
+			}
+			parsed[key] = deepParsed
+		default:
+			parsed[key] = v
+		}
+	}
+	return parsed, nil
+}
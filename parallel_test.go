@@ -0,0 +1,77 @@
+package template
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func wideTemplateMap(n int) map[string]interface{} {
+	var m = map[string]interface{}{}
+	for i := 0; i < n; i++ {
+		m[fmt.Sprintf("field%d", i)] = fmt.Sprintf(`{{ .event.id }}-{{ .event.name | toLower }}-%d`, i)
+	}
+	m["nested"] = map[string]interface{}{"id": "{{ .event.id }}", "n": 1.5}
+	return m
+}
+
+var wideTemplateData = map[string]interface{}{
+	"event": map[string]interface{}{"id": "8D469E95", "name": "Order"},
+}
+
+func TestInterpolateMapParallel(t *testing.T) {
+	var tmpl = wideTemplateMap(50)
+	expected, err := InterpolateMap(wideTemplateData, tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := InterpolateMapParallel(wideTemplateData, tmpl, 4)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf(`Unexpected result %v`, res)
+	}
+}
+
+func TestInterpolateMapParallelErrors(t *testing.T) {
+	_, err := InterpolateMapParallel(nil, map[string]interface{}{
+		"ok": "{{ 1 }}",
+		"b":  `{{ parseJSON "{" }}`,
+		"a":  map[string]interface{}{"c": `{{ nonexistentFunc }}`},
+	}, 0)
+	if err == nil {
+		t.Errorf(`Expected errors`)
+		return
+	}
+	var execErr *ExecError
+	if !errors.As(err, &execErr) || execErr.Key != "a.c" {
+		t.Errorf(`Expected the first error by key path to be a.c, got %v`, err)
+	}
+	if joined, ok := err.(interface{ Unwrap() []error }); !ok || len(joined.Unwrap()) != 2 {
+		t.Errorf(`Expected both errors to be reported, got %v`, err)
+	}
+}
+
+func BenchmarkInterpolateMapWide(b *testing.B) {
+	var tmpl = wideTemplateMap(300)
+	for i := 0; i < b.N; i++ {
+		_, err := InterpolateMap(wideTemplateData, tmpl)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkInterpolateMapParallelWide(b *testing.B) {
+	var tmpl = wideTemplateMap(300)
+	for i := 0; i < b.N; i++ {
+		_, err := InterpolateMapParallel(wideTemplateData, tmpl, 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
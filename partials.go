@@ -0,0 +1,402 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"unsafe"
+)
+
+var partialExtensions = []string{".tmpl"}
+var partialOverwrite bool
+
+// partialSource is the source of a loaded partial, retained so the RootTemplate can be rebuilt without it
+type partialSource struct {
+	name string
+	src  string
+	// file the partial was loaded from, empty for partials loaded from a string
+	file string
+}
+
+// partialSources are the loaded partials in load order
+var partialSources []partialSource
+
+// parsePartial parses src into the RootTemplate as name and retains the source
+func parsePartial(name, src string) error {
+	return parsePartialSource(partialSource{name: name, src: src})
+}
+
+// parsePartialSource parses a partial into the RootTemplate and retains its source
+func parsePartialSource(partial partialSource) error {
+	_, err := RootTemplate.New(partial.name).Parse(partial.src)
+	if err != nil {
+		return err
+	}
+	for i, ps := range partialSources {
+		if ps.name == partial.name {
+			partialSources[i] = partial
+			return nil
+		}
+	}
+	partialSources = append(partialSources, partial)
+	return nil
+}
+
+// AllowPartialOverwrite controls whether LoadPartialNamed may replace a partial that is already loaded
+// When not allowed, which is the default, it returns an error instead
+func AllowPartialOverwrite(allow bool) {
+	partialOverwrite = allow
+}
+
+// SetPartialExtensions sets the file extensions loaded when a directory is passed to LoadPartialFiles
+func SetPartialExtensions(extensions ...string) {
+	partialExtensions = extensions
+}
+
+// LoadPartialFiles parses the given files and adds them to the RootTemplate
+// Each argument may be a filename, a glob pattern ("partials/*.tmpl") or a directory, which is loaded recursively
+// keeping files with one of the partial extensions. Patterns matching no files are errors, as are template names,
+// including those of defines, that are used by two files or are already loaded from another file or a string.
+// Loading the same files again replaces them, so a directory can be loaded again to pick up its changes.
+func LoadPartialFiles(patterns ...string) (err error) {
+	var filenames []string
+	for _, pattern := range patterns {
+		var matches []string
+		matches, err = expandPartialPattern(pattern)
+		if err != nil {
+			return
+		}
+		filenames = append(filenames, matches...)
+	}
+	var partials = make([]partialSource, len(filenames))
+	for i, filename := range filenames {
+		var b []byte
+		b, err = os.ReadFile(filename)
+		if err != nil {
+			return
+		}
+		var file string
+		file, err = filepath.Abs(filename)
+		if err != nil {
+			return
+		}
+		partials[i] = partialSource{name: filepath.Base(filename), src: string(b), file: file}
+	}
+	return loadPartialSources(partials)
+}
+
+// LoadPartialsFS parses the files in fsys matching the glob patterns and adds them to the RootTemplate
+// Templates are named by file base name, and duplicate template names are errors, as with LoadPartialFiles.
+// Patterns matching no files are also errors.
+func LoadPartialsFS(fsys fs.FS, patterns ...string) (err error) {
+	var filenames []string
+	for _, pattern := range patterns {
+		var matches []string
+		matches, err = fs.Glob(fsys, pattern)
+		if err != nil {
+			return fmt.Errorf("invalid partial pattern %q: %w", pattern, err)
+		}
+		if len(matches) == 0 {
+			return fmt.Errorf("partial pattern %q matched no files", pattern)
+		}
+		filenames = append(filenames, matches...)
+	}
+	var partials = make([]partialSource, len(filenames))
+	for i, filename := range filenames {
+		var b []byte
+		b, err = fs.ReadFile(fsys, filename)
+		if err != nil {
+			return
+		}
+		partials[i] = partialSource{name: path.Base(filename), src: string(b), file: filename}
+	}
+	return loadPartialSources(partials)
+}
+
+// loadPartialSources checks that partials loaded from files don't define any template name twice, then parses them
+func loadPartialSources(partials []partialSource) error {
+	err := checkDuplicatePartials(partials)
+	if err != nil {
+		return err
+	}
+	for _, ps := range partials {
+		err = parsePartialSource(ps)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDuplicatePartials errors if a template name, including those of defines, is used by two of the partials
+// or by a template already loaded other than from one of their files, so no partial is silently overridden
+func checkDuplicatePartials(partials []partialSource) error {
+	var files = map[string]bool{}
+	for _, ps := range partials {
+		files[ps.file] = true
+	}
+	var loaded = map[string]partialSource{}
+	for _, ps := range partialSources {
+		loaded[ps.name] = ps
+	}
+	var seen = map[string]string{}
+	for _, ps := range partials {
+		var tree = parse.New(ps.name)
+		tree.Mode = parse.SkipFuncCheck
+		var trees = map[string]*parse.Tree{}
+		_, err := tree.Parse(ps.src, leftDelim, rightDelim, trees)
+		if err != nil {
+			return err
+		}
+		var names = make([]string, 0, len(trees))
+		for name := range trees {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if other, ok := seen[name]; ok {
+				return fmt.Errorf("duplicate partial %q defined by %s and %s", name, other, ps.file)
+			}
+			seen[name] = ps.file
+			var existing = RootTemplate.Lookup(name)
+			if existing == nil || existing.Tree == nil {
+				continue
+			}
+			// Templates loaded from one of the files are replaced by loading it again
+			var owner = loaded[existing.Tree.ParseName]
+			if owner.file != "" && files[owner.file] {
+				continue
+			}
+			if owner.file == "" {
+				return fmt.Errorf("duplicate partial %q defined by %s is already loaded", name, ps.file)
+			}
+			return fmt.Errorf("duplicate partial %q defined by %s and %s", name, owner.file, ps.file)
+		}
+	}
+	return nil
+}
+
+// expandPartialPattern resolves a filename, glob pattern or directory to the files it refers to
+func expandPartialPattern(pattern string) ([]string, error) {
+	if info, err := os.Stat(pattern); err == nil && info.IsDir() {
+		var filenames []string
+		err = filepath.WalkDir(pattern, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && hasPartialExtension(path) {
+				filenames = append(filenames, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(filenames) == 0 {
+			return nil, fmt.Errorf("partial directory %q contains no files with extensions %s", pattern, strings.Join(partialExtensions, ", "))
+		}
+		return filenames, nil
+	}
+	if !strings.ContainsAny(pattern, `*?[`) {
+		return []string{pattern}, nil
+	}
+	filenames, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid partial pattern %q: %w", pattern, err)
+	}
+	if len(filenames) == 0 {
+		return nil, fmt.Errorf("partial pattern %q matched no files", pattern)
+	}
+	return filenames, nil
+}
+
+func hasPartialExtension(filename string) bool {
+	var ext = filepath.Ext(filename)
+	for _, allowed := range partialExtensions {
+		if ext == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadPartial parses the given template strings and adds it to the RootTemplate
+// An existing partial with the same name is replaced, use LoadPartialNamed to guard against that
+func LoadPartial(name, template string) (err error) {
+	return parsePartial(name, template)
+}
+
+// LoadPartialNamed parses src and adds it to the RootTemplate as name, callable with {{ template "name" . }}
+// Loading a name that is already loaded is an error unless AllowPartialOverwrite is set
+func LoadPartialNamed(name, src string) error {
+	if !partialOverwrite && RootTemplate.Lookup(name) != nil {
+		return fmt.Errorf("duplicate partial %q is already loaded", name)
+	}
+	err := parsePartial(name, src)
+	if err != nil {
+		return fmt.Errorf("partial %q: %w", name, err)
+	}
+	return nil
+}
+
+// configuredPartials are the names of the partials loaded from Config.PartialsInline by the last Configure
+var configuredPartials = map[string]bool{}
+
+// loadPartialsInline loads each partial in the map by name, in name order
+// Partials loaded by a previous Configure are replaced, or removed when they are no longer configured,
+// so Configure can be called again to reload the configuration
+func loadPartialsInline(partials map[string]string) error {
+	var stale []string
+	for name := range configuredPartials {
+		if _, ok := partials[name]; !ok {
+			stale = append(stale, name)
+		}
+	}
+	sort.Strings(stale)
+	for _, name := range stale {
+		delete(configuredPartials, name)
+		if RootTemplate.Lookup(name) == nil {
+			continue
+		}
+		if err := RemovePartial(name); err != nil {
+			return err
+		}
+	}
+	var names = make([]string, 0, len(partials))
+	for name := range partials {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if configuredPartials[name] {
+			if err := parsePartial(name, partials[name]); err != nil {
+				return fmt.Errorf("partial %q: %w", name, err)
+			}
+			continue
+		}
+		if err := LoadPartialNamed(name, partials[name]); err != nil {
+			return err
+		}
+		configuredPartials[name] = true
+	}
+	return nil
+}
+
+// ListPartials returns the sorted names of the templates defined on the RootTemplate, including those declared with define
+func ListPartials() []string {
+	var names []string
+	for _, t := range RootTemplate.Templates() {
+		if t.Name() != RootTemplate.Name() {
+			names = append(names, t.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RemovePartial unloads the partial loaded as name, along with any templates it defined
+// The RootTemplate is rebuilt from the sources of the remaining partials, keeping its funcs and any other templates
+func RemovePartial(name string) error {
+	var remaining = make([]partialSource, 0, len(partialSources))
+	for _, ps := range partialSources {
+		if ps.name != name {
+			remaining = append(remaining, ps)
+		}
+	}
+	if len(remaining) == len(partialSources) {
+		return fmt.Errorf("partial %q is not loaded", name)
+	}
+	return rebuildRootTemplate(remaining)
+}
+
+// ResetPartials unloads all partials from the RootTemplate
+func ResetPartials() {
+	rebuildRootTemplate(nil)
+	configuredPartials = map[string]bool{}
+}
+
+// rebuildRootTemplate replaces the RootTemplate with a new one containing the given partials
+// Funcs added with RootTemplate.Funcs and templates parsed directly onto the RootTemplate are kept
+func rebuildRootTemplate(sources []partialSource) error {
+	var partials = make(map[string]bool, len(partialSources))
+	for _, ps := range partialSources {
+		partials[ps.name] = true
+	}
+	// Templates defined by a partial are parsed under its name, anything else was parsed onto the RootTemplate directly
+	var kept []*template.Template
+	for _, t := range RootTemplate.Templates() {
+		if t.Tree != nil && !partials[t.Tree.ParseName] {
+			kept = append(kept, t)
+		}
+	}
+	var root = template.New(RootTemplate.Name()).Delims(leftDelim, rightDelim).Funcs(TemplateFuncs).Funcs(rootFuncs())
+	for _, ps := range sources {
+		if _, err := root.New(ps.name).Parse(ps.src); err != nil {
+			return fmt.Errorf("partial %q: %w", ps.name, err)
+		}
+	}
+	for _, t := range kept {
+		if _, err := root.AddParseTree(t.Name(), t.Tree); err != nil {
+			return err
+		}
+	}
+	RootTemplate = root
+	partialSources = sources
+	return nil
+}
+
+// rootFuncs returns the funcs of the RootTemplate, including those added with RootTemplate.Funcs rather than to TemplateFuncs
+// text/template has no accessor for them, so they are read from its unexported fields, or are nil if those ever change
+func rootFuncs() template.FuncMap {
+	var common = reflect.ValueOf(RootTemplate).Elem().FieldByName("common")
+	if common.Kind() != reflect.Ptr || common.IsNil() {
+		return nil
+	}
+	var funcs = common.Elem().FieldByName("parseFuncs")
+	if !funcs.IsValid() || funcs.Type() != reflect.TypeOf(template.FuncMap(nil)) {
+		return nil
+	}
+	return reflect.NewAt(funcs.Type(), unsafe.Pointer(funcs.UnsafeAddr())).Elem().Interface().(template.FuncMap)
+}
+
+// include executes the partial loaded as name with data and returns the output
+// Unlike the template action the name may be computed at runtime, and the output can be piped to other funcs
+func include(name string, data interface{}) (string, error) {
+	return includeChain(nil, newRenderOverlay(nil), name, data)
+}
+
+// includeChain executes the partial name with the chain of files and partials rendering it
+func includeChain(chain []string, overlay map[string]interface{}, name string, data interface{}) (string, error) {
+	if name == RootTemplate.Name() || RootTemplate.Lookup(name) == nil {
+		return ``, fmt.Errorf("include: partial %q is not loaded", name)
+	}
+	chain, err := extendRenderChain("include", chain, name)
+	if err != nil {
+		return ``, err
+	}
+
+	tmpl, err := RootTemplate.Clone()
+
+	if err != nil {
+		return ``, err
+	}
+
+	tmpl.Funcs(renderChainFuncs(chain, overlay))
+
+	var tBuf bytes.Buffer
+	err = tmpl.ExecuteTemplate(&tBuf, name, data)
+
+	if err != nil {
+		return ``, err
+	}
+
+	return tBuf.String(), nil
+}
@@ -0,0 +1,445 @@
+package template
+
+import (
+	"embed"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+// writePartials creates files under a temp dir and returns the dir
+func writePartials(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, content := range files {
+		filename := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filename, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestLoadPartialFilesDirectory(t *testing.T) {
+	restoreRootTemplate(t)
+	dir := writePartials(t, map[string]string{
+		"header.tmpl":      `header {{ .name }}`,
+		"nested/foot.tmpl": `footer`,
+		"readme.md":        `not a partial`,
+	})
+	err := LoadPartialFiles(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := Interpolate(map[string]interface{}{"name": "x"}, `{{ template "header.tmpl" . }}|{{ template "foot.tmpl" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `header x|footer` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	if RootTemplate.Lookup("readme.md") != nil {
+		t.Error("Files without a partial extension should not be loaded from directories")
+	}
+}
+
+func TestLoadPartialFilesGlob(t *testing.T) {
+	restoreRootTemplate(t)
+	dir := writePartials(t, map[string]string{
+		"one.tmpl": `one`,
+		"two.tmpl": `two`,
+	})
+	err := LoadPartialFiles(filepath.Join(dir, "*.tmpl"))
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := Interpolate(nil, `{{ template "one.tmpl" }}{{ template "two.tmpl" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `onetwo` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestLoadPartialFilesNoMatches(t *testing.T) {
+	dir := t.TempDir()
+	var pattern = filepath.Join(dir, "*.tmpl")
+	err := LoadPartialFiles(pattern)
+	if err == nil || !strings.Contains(err.Error(), pattern) {
+		t.Errorf(`Expected error naming the pattern, got %v`, err)
+	}
+	err = LoadPartialFiles(dir)
+	if err == nil {
+		t.Error("Expected error for directory without partials")
+	}
+}
+
+func TestLoadPartialFilesDuplicate(t *testing.T) {
+	restoreRootTemplate(t)
+	dir := writePartials(t, map[string]string{
+		"a/dup.tmpl": `a`,
+		"b/dup.tmpl": `b`,
+	})
+	err := LoadPartialFiles(dir)
+	if err == nil || !strings.Contains(err.Error(), "dup.tmpl") {
+		t.Errorf(`Expected duplicate partial error, got %v`, err)
+	}
+}
+
+func TestLoadPartialFilesDuplicateDefine(t *testing.T) {
+	restoreRootTemplate(t)
+	dir := writePartials(t, map[string]string{
+		"a.tmpl": `{{ define "shared" }}a{{ end }}`,
+		"b.tmpl": `{{ define "shared" }}b{{ end }}`,
+	})
+	err := LoadPartialFiles(dir)
+	if err == nil || !strings.Contains(err.Error(), `"shared"`) || !strings.Contains(err.Error(), "a.tmpl") || !strings.Contains(err.Error(), "b.tmpl") {
+		t.Errorf(`Expected duplicate define error, got %v`, err)
+	}
+	if RootTemplate.Lookup("shared") != nil {
+		t.Error(`Expected nothing to be loaded`)
+	}
+
+	// A file can't override a template loaded from another file or from a string
+	first := writePartials(t, map[string]string{"first.tmpl": `{{ define "owned" }}first{{ end }}`})
+	err = LoadPartialFiles(first)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	second := writePartials(t, map[string]string{"second.tmpl": `{{ define "owned" }}second{{ end }}`})
+	err = LoadPartialFiles(second)
+	if err == nil || !strings.Contains(err.Error(), "first.tmpl") || !strings.Contains(err.Error(), "second.tmpl") {
+		t.Errorf(`Expected duplicate define error, got %v`, err)
+	}
+	err = LoadPartialNamed("inline.tmpl", `inline`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	third := writePartials(t, map[string]string{"inline.tmpl": `file`})
+	err = LoadPartialFiles(third)
+	if err == nil || !strings.Contains(err.Error(), "inline.tmpl") {
+		t.Errorf(`Expected duplicate partial error, got %v`, err)
+	}
+	res, err := Interpolate(nil, `{{ template "owned" }} {{ template "inline.tmpl" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `first inline` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestLoadPartialFilesReload(t *testing.T) {
+	restoreRootTemplate(t)
+	dir := writePartials(t, map[string]string{
+		"greet.tmpl": `hello{{ define "greet_define" }}{{ end }}`,
+	})
+	err := LoadPartialFiles(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = os.WriteFile(filepath.Join(dir, "greet.tmpl"), []byte(`hi{{ define "greet_define" }}{{ end }}`), 0o644)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = LoadPartialFiles(dir)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := Interpolate(nil, `{{ template "greet.tmpl" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `hi` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+//go:embed testdata/partials
+var testPartialsFS embed.FS
+
+// restoreRootTemplate resets RootTemplate when the test ends so partials with fixed names can be loaded again
+func restoreRootTemplate(t *testing.T) {
+	root, err := RootTemplate.Clone()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := append([]partialSource(nil), partialSources...)
+	configured := configuredPartials
+	configuredPartials = map[string]bool{}
+	for name := range configured {
+		configuredPartials[name] = true
+	}
+	t.Cleanup(func() {
+		RootTemplate = root
+		partialSources = sources
+		configuredPartials = configured
+	})
+}
+
+func TestLoadPartialsFS(t *testing.T) {
+	restoreRootTemplate(t)
+	err := LoadPartialsFS(testPartialsFS, "testdata/partials/*.tmpl")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := Interpolate(map[string]interface{}{"name": "x"}, `{{ template "greeting.tmpl" . }} {{ template "signature" "me" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `Hello x -- me` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	if RootTemplate.Lookup("notes.txt") != nil {
+		t.Error("Files not matching the pattern should not be loaded")
+	}
+}
+
+func TestLoadPartialsFSNoMatches(t *testing.T) {
+	err := LoadPartialsFS(testPartialsFS, "testdata/partials/*.missing")
+	if err == nil || !strings.Contains(err.Error(), "*.missing") {
+		t.Errorf(`Expected error naming the pattern, got %v`, err)
+	}
+}
+
+func TestLoadPartialNamed(t *testing.T) {
+	restoreRootTemplate(t)
+	err := LoadPartialNamed("header", `Header {{ .title }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := Interpolate(map[string]interface{}{"title": "x"}, `{{ template "header" . }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `Header x` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+
+	err = LoadPartialNamed("header", `Other`)
+	if err == nil {
+		t.Error("Expected error re-registering a partial")
+	}
+
+	AllowPartialOverwrite(true)
+	defer AllowPartialOverwrite(false)
+	err = LoadPartialNamed("header", `Other`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err = Interpolate(nil, `{{ template "header" . }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `Other` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestConfigurePartialsInline(t *testing.T) {
+	restoreRootTemplate(t)
+	err := Configure(Config{
+		PartialsInline: map[string]string{
+			"inline_a": `A{{ template "inline_b" }}`,
+			"inline_b": `B`,
+		},
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := Interpolate(nil, `{{ template "inline_a" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `AB` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestConfigurePartialsInlineTwice(t *testing.T) {
+	restoreRootTemplate(t)
+	var err error
+	err = Configure(Config{PartialsInline: map[string]string{"greet": `Hello`, "farewell": `Bye`}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = Configure(Config{PartialsInline: map[string]string{"greet": `Hi {{ . }}`}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := Interpolate("there", `{{ template "greet" . }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `Hi there` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	if RootTemplate.Lookup("farewell") != nil {
+		t.Error(`Expected farewell to be removed`)
+	}
+
+	// Partials loaded by other means are still protected
+	err = LoadPartialNamed("greet", `Hey`)
+	if err == nil {
+		t.Error(`Expected duplicate partial error`)
+	}
+}
+
+func TestRemovePartialKeepsRootFuncs(t *testing.T) {
+	restoreRootTemplate(t)
+	RootTemplate.Funcs(template.FuncMap{"rootOnlyFunc": func() string { return "func" }})
+	_, err := RootTemplate.New("root_only_template").Parse(`{{ rootOnlyFunc }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = LoadPartialNamed("removed_partial", `{{ define "removed_define" }}{{ end }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = RemovePartial("removed_partial")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if RootTemplate.Lookup("removed_partial") != nil || RootTemplate.Lookup("removed_define") != nil {
+		t.Error(`Expected the partial and its templates to be removed`)
+	}
+	res, err := Interpolate(nil, `{{ rootOnlyFunc }} {{ template "root_only_template" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `func func` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestListAndRemovePartials(t *testing.T) {
+	restoreRootTemplate(t)
+	ResetPartials()
+	var err error
+	err = LoadPartialNamed("list_a", `A{{ define "list_nested" }}N{{ end }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = LoadPartialNamed("list_b", `B`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if names := strings.Join(ListPartials(), ","); names != "list_a,list_b,list_nested" {
+		t.Errorf(`Unexpected partials %q`, names)
+	}
+
+	err = RemovePartial("list_a")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if names := strings.Join(ListPartials(), ","); names != "list_b" {
+		t.Errorf(`Unexpected partials after remove %q`, names)
+	}
+	_, err = Interpolate(nil, `{{ template "list_a" }}`)
+	if err == nil {
+		t.Error("Expected removed partial to be undefined")
+	}
+	res, err := Interpolate(nil, `{{ template "list_b" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "B" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	// A removed partial can be loaded again without allowing overwrites
+	err = LoadPartialNamed("list_a", `A2`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	if err = RemovePartial("missing"); err == nil {
+		t.Error("Expected error removing a partial that isn't loaded")
+	}
+
+	ResetPartials()
+	if len(ListPartials()) != 0 {
+		t.Errorf(`Unexpected partials after reset %q`, ListPartials())
+	}
+}
+
+func TestInclude(t *testing.T) {
+	restoreTemplateFuncs(t)
+	err := EnableSprig("indent")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for name, src := range map[string]string{
+		"card.tmpl":   "card {{ .number }}",
+		"paypal.tmpl": "paypal {{ .email }}\nverified",
+	} {
+		if err = LoadPartialNamed(name, src); err != nil {
+			t.Error(err)
+			return
+		}
+	}
+
+	var src = `{{ include (index (dict "card" "card.tmpl" "paypal" "paypal.tmpl") .method) . | indent 2 }}`
+	res, err := InterpolateStrict(map[string]interface{}{"method": "paypal", "email": "a@example.com"}, src)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "  paypal a@example.com\n  verified" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+
+	_, err = InterpolateStrict(nil, `{{ include "missing.tmpl" . }}`)
+	if err == nil || !strings.Contains(err.Error(), `partial "missing.tmpl" is not loaded`) {
+		t.Errorf(`Expected a not loaded error, got %v`, err)
+	}
+}
+
+func TestIncludeDepthLimit(t *testing.T) {
+	restoreRootTemplate(t)
+	err := LoadPartialNamed("loop.tmpl", `{{ include "loop.tmpl" . }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = InterpolateStrict(nil, `{{ include "loop.tmpl" . }}`)
+	if err == nil || !strings.Contains(err.Error(), "include depth limit of 16 exceeded") {
+		t.Errorf(`Expected a depth limit error, got %v`, err)
+	}
+}
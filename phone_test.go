@@ -0,0 +1,59 @@
+package template
+
+import (
+	"testing"
+)
+
+func TestPhoneValid(t *testing.T) {
+	for _, c := range []struct {
+		phone, region string
+		valid         bool
+	}{
+		{"+44 20 7946 0000", "", true},
+		{"+44 (0)20 7946 0000", "", true},
+		{"020 7946 0000", "GB", true},
+		{"07700 900123", "gb", true},
+		{"+1 (415) 555-2671", "", true},
+		{"1-415-555-2671", "US", true},
+		{"415.555.2671", "US", true},
+		{"(416) 555-0199", "CA", true},
+		{"0044 20 7946 0000", "US", true},
+		{"+33 6 12 34 56 78", "", true},
+		{"+39 06 6982 0000", "", true},
+		{"415 555 2671", "", false},
+		{"+1 415 555 267", "", false},
+		{"+1 115 555 2671", "", false},
+		{"+1 415 155 2671", "", false},
+		{"+44 20 7946", "", false},
+		{"+999 1234 5678", "", false},
+		{"+44 20 7946 0000 ext 1", "", false},
+		{"", "US", false},
+		{"phone", "US", false},
+	} {
+		if phoneValid(c.phone, c.region) != c.valid {
+			t.Errorf(`Expected phoneValid(%q, %q) to be %v`, c.phone, c.region, c.valid)
+		}
+	}
+}
+
+func TestPhoneCountry(t *testing.T) {
+	for phone, country := range map[string]string{
+		"+44 20 7946 0000":  "GB",
+		"+44 7700 900123":   "GB",
+		"+1 415 555 2671":   "US",
+		"+1 (416) 555-0199": "CA",
+		"+1 876 555 0123":   "JM",
+		"+1 800 555 0199":   "",
+		"001 604 555 0199":  "CA",
+		"+7 495 123 4567":   "RU",
+		"+7 701 123 4567":   "KZ",
+		"+49 30 123456":     "DE",
+		"+353 1 234 5678":   "IE",
+		"415 555 2671":      "",
+		"+44 12":            "",
+	} {
+		if res := phoneCountry(phone); res != country {
+			t.Errorf(`Unexpected result %q for %s`, res, phone)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package template
+
+import (
+	"strings"
+)
+
+// phoneticLetters transliterates s and returns its ASCII letters upper cased, dropping everything else
+func phoneticLetters(s string) string {
+	var b strings.Builder
+	for _, r := range transliterate(s) {
+		switch {
+		case r >= 'A' && r <= 'Z':
+			b.WriteRune(r)
+		case r >= 'a' && r <= 'z':
+			b.WriteRune(r - 'a' + 'A')
+		}
+	}
+	return b.String()
+}
+
+// soundexCodes are the soundex digits of each letter, 0 for vowels and y, which separate letters with equal
+// codes, and - for h and w, which don't
+const soundexCodes = "0123012-02245501262301-202"
+
+// soundex returns the American soundex code of s, such as R163 for Robert and Rupert
+// Accents are transliterated and other characters ignored, input without letters returns an empty string
+func soundex(s string) string {
+	var letters = phoneticLetters(s)
+	if letters == "" {
+		return ""
+	}
+	var code = []byte{letters[0]}
+	var last = soundexCodes[letters[0]-'A']
+	for i := 1; i < len(letters) && len(code) < 4; i++ {
+		var c = soundexCodes[letters[i]-'A']
+		switch c {
+		case '-':
+		case '0':
+			last = c
+		default:
+			if c != last {
+				code = append(code, c)
+			}
+			last = c
+		}
+	}
+	for len(code) < 4 {
+		code = append(code, '0')
+	}
+	return string(code)
+}
+
+// metaphone returns the original Metaphone key of s, such as RBRT for Robert
+// Accents are transliterated and other characters ignored, input without letters returns an empty string
+func metaphone(s string) string {
+	var w = phoneticLetters(s)
+	if w == "" {
+		return ""
+	}
+	// at returns the letter at i, or 0 outside the word
+	var at = func(i int) byte {
+		if i < 0 || i >= len(w) {
+			return 0
+		}
+		return w[i]
+	}
+	var isVowel = func(c byte) bool {
+		return c != 0 && strings.IndexByte("AEIOU", c) >= 0
+	}
+	var frontVowel = func(c byte) bool {
+		return c != 0 && strings.IndexByte("EIY", c) >= 0
+	}
+
+	var code strings.Builder
+	var start int
+	switch {
+	case strings.HasPrefix(w, "AE"):
+		code.WriteByte('E')
+		start = 2
+	case strings.HasPrefix(w, "GN"), strings.HasPrefix(w, "KN"), strings.HasPrefix(w, "PN"), strings.HasPrefix(w, "WR"):
+		code.WriteByte(w[1])
+		start = 2
+	case strings.HasPrefix(w, "WH"):
+		code.WriteByte('W')
+		start = 2
+	case w[0] == 'X':
+		code.WriteByte('S')
+		start = 1
+	case isVowel(w[0]):
+		code.WriteByte(w[0])
+		start = 1
+	}
+
+	for i := start; i < len(w); i++ {
+		var c, prev, next = w[i], at(i - 1), at(i + 1)
+		if c == prev && c != 'C' {
+			continue
+		}
+		switch c {
+		case 'A', 'E', 'I', 'O', 'U':
+			// Vowels are only kept at the start
+		case 'B':
+			if !(prev == 'M' && i == len(w)-1) {
+				code.WriteByte('B')
+			}
+		case 'C':
+			switch {
+			case prev == 'S' && frontVowel(next):
+				// Silent in SCE, SCI and SCY
+			case next == 'I' && at(i+2) == 'A':
+				code.WriteByte('X')
+			case frontVowel(next):
+				code.WriteByte('S')
+			case next == 'H' && prev == 'S':
+				code.WriteByte('K')
+			case next == 'H':
+				code.WriteByte('X')
+			default:
+				code.WriteByte('K')
+			}
+		case 'D':
+			if next == 'G' && frontVowel(at(i+2)) {
+				code.WriteByte('J')
+				i++
+			} else {
+				code.WriteByte('T')
+			}
+		case 'G':
+			switch {
+			case next == 'H' && i+2 < len(w) && !isVowel(at(i+2)):
+				// Silent in GH not at the end or before a vowel, as in night
+			case next == 'N' && (i+2 == len(w) || (w[i+1:] == "NED")):
+				// Silent in a final GN or GNED, as in sign and signed
+			case frontVowel(next) && prev != 'G':
+				code.WriteByte('J')
+			default:
+				code.WriteByte('K')
+			}
+		case 'H':
+			if isVowel(next) && strings.IndexByte("CGPST", prev) < 0 {
+				code.WriteByte('H')
+			}
+		case 'K':
+			if prev != 'C' {
+				code.WriteByte('K')
+			}
+		case 'P':
+			if next == 'H' {
+				code.WriteByte('F')
+			} else {
+				code.WriteByte('P')
+			}
+		case 'Q':
+			code.WriteByte('K')
+		case 'S':
+			if next == 'H' || (next == 'I' && (at(i+2) == 'O' || at(i+2) == 'A')) {
+				code.WriteByte('X')
+			} else {
+				code.WriteByte('S')
+			}
+		case 'T':
+			switch {
+			case next == 'I' && (at(i+2) == 'O' || at(i+2) == 'A'):
+				code.WriteByte('X')
+			case next == 'H':
+				code.WriteByte('0')
+			case next == 'C' && at(i+2) == 'H':
+				// Silent in TCH
+			default:
+				code.WriteByte('T')
+			}
+		case 'V':
+			code.WriteByte('F')
+		case 'W', 'Y':
+			if isVowel(next) {
+				code.WriteByte(c)
+			}
+		case 'X':
+			code.WriteString("KS")
+		case 'Z':
+			code.WriteByte('S')
+		default:
+			code.WriteByte(c)
+		}
+	}
+	return code.String()
+}
@@ -0,0 +1,40 @@
+package template
+
+import (
+	"testing"
+)
+
+func TestSoundex(t *testing.T) {
+	var cases = map[string]string{
+		"Robert": "R163", "Rupert": "R163", "Rubin": "R150", "Ashcraft": "A261", "Ashcroft": "A261",
+		"Tymczak": "T522", "Pfister": "P236", "Honeyman": "H555", "Lee": "L000", "Gutiérrez": "G362",
+		"o'brien": "O165", "": "", "1234": "", "日本": "",
+	}
+	for s, expected := range cases {
+		if res := soundex(s); res != expected {
+			t.Errorf(`Unexpected soundex %q for %q`, res, s)
+		}
+	}
+}
+
+func TestMetaphone(t *testing.T) {
+	var cases = map[string]string{
+		"Robert": "RBRT", "Rupert": "RPRT", "Thumb": "0M", "Smith": "SM0", "Philip": "FLP",
+		"Xavier": "SFR", "Wright": "RT", "Knight": "NT", "Science": "SNS", "School": "SKL",
+		"Church": "XRX", "Judge": "JJ", "Aeneas": "ENS", "Whale": "WL", "Nation": "NXN",
+		"Gnome": "NM", "Sign": "SN", "Ciara": "XR", "Björk": "BJRK", "": "", "42": "",
+	}
+	for s, expected := range cases {
+		if res := metaphone(s); res != expected {
+			t.Errorf(`Unexpected metaphone %q for %q`, res, s)
+		}
+	}
+	res, err := InterpolateStrict(map[string]interface{}{"a": "Stephen", "b": "Steven"}, `{{ eq (metaphone .a) (metaphone .b) }} {{ soundex .a }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "true S315" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
@@ -0,0 +1,120 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// irregularPlurals maps singular nouns to plurals that don't follow the regular rules
+var irregularPlurals = map[string]string{
+	"person": "people", "child": "children", "man": "men", "woman": "women", "mouse": "mice",
+	"goose": "geese", "tooth": "teeth", "foot": "feet", "ox": "oxen", "die": "dice",
+	"leaf": "leaves", "life": "lives", "knife": "knives", "wife": "wives", "half": "halves",
+	"wolf": "wolves", "shelf": "shelves", "calf": "calves", "loaf": "loaves", "thief": "thieves",
+	"potato": "potatoes", "tomato": "tomatoes", "hero": "heroes", "echo": "echoes", "veto": "vetoes",
+	"criterion": "criteria", "phenomenon": "phenomena", "analysis": "analyses", "crisis": "crises",
+	"quiz": "quizzes", "index": "indices", "matrix": "matrices", "vertex": "vertices", "bus": "buses",
+}
+
+// irregularSingulars is the inverse of irregularPlurals
+var irregularSingulars = func() map[string]string {
+	var m = make(map[string]string, len(irregularPlurals))
+	for singular, plural := range irregularPlurals {
+		m[plural] = singular
+	}
+	return m
+}()
+
+// uncountableNouns have the same singular and plural form
+var uncountableNouns = map[string]bool{
+	"sheep": true, "fish": true, "deer": true, "series": true, "species": true, "news": true,
+	"information": true, "equipment": true, "money": true, "rice": true, "aircraft": true, "data": true,
+}
+
+// plural returns singular when the count n is 1 and plural otherwise
+func plural(n interface{}, singular, plural string) (string, error) {
+	f, err := interfaceToFloat64(n)
+	if err != nil {
+		return "", err
+	}
+	if f == 1 {
+		return singular, nil
+	}
+	return plural, nil
+}
+
+// pluralWithCount is plural prefixed with the count, such as "3 items"
+func pluralWithCount(n interface{}, singular, pluralForm string) (string, error) {
+	word, err := plural(n, singular, pluralForm)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprint(n) + " " + word, nil
+}
+
+// pluralize returns the English plural of a noun following the regular rules and a small table of irregular nouns
+// The case of the word is kept when it is lower, upper or title case
+func pluralize(word string) string {
+	return inflect(word, irregularPlurals, func(w string) string {
+		switch {
+		case hasAnySuffix(w, "s", "x", "z", "ch", "sh"):
+			return w + "es"
+		case strings.HasSuffix(w, "y") && len(w) > 1 && !isVowel(w[len(w)-2]):
+			return w[:len(w)-1] + "ies"
+		default:
+			return w + "s"
+		}
+	})
+}
+
+// singularize returns the English singular of a plural noun, the inverse of pluralize
+func singularize(word string) string {
+	return inflect(word, irregularSingulars, func(w string) string {
+		switch {
+		case strings.HasSuffix(w, "ies") && len(w) > 3:
+			return w[:len(w)-3] + "y"
+		case hasAnySuffix(w, "sses", "xes", "zzes", "ches", "shes"):
+			return w[:len(w)-2]
+		case strings.HasSuffix(w, "s") && !hasAnySuffix(w, "ss", "us", "is"):
+			return w[:len(w)-1]
+		default:
+			return w
+		}
+	})
+}
+
+// inflect applies the irregular forms or regular rule to the lower case word, then restores its case
+func inflect(word string, irregular map[string]string, regular func(string) string) string {
+	var lower = strings.ToLower(word)
+	if lower == "" || uncountableNouns[lower] {
+		return word
+	}
+	var res, ok = irregular[lower]
+	if !ok {
+		res = regular(lower)
+	}
+	switch {
+	case word == lower:
+		return res
+	case word == strings.ToUpper(word):
+		return strings.ToUpper(res)
+	default:
+		first, size := utf8.DecodeRuneInString(res)
+		return string(unicode.ToUpper(first)) + res[size:]
+	}
+}
+
+func hasAnySuffix(s string, suffixes ...string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func isVowel(c byte) bool {
+	return strings.IndexByte("aeiou", c) >= 0
+}
@@ -0,0 +1,58 @@
+package template
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestPlural(t *testing.T) {
+	var cases = map[interface{}]string{
+		0:                   "0 items",
+		1:                   "1 item",
+		2:                   "2 items",
+		1.0:                 "1 item",
+		1.5:                 "1.5 items",
+		json.Number("1"):    "1 item",
+		json.Number("0"):    "0 items",
+		json.Number("1000"): "1000 items",
+		"1":                 "1 item",
+	}
+	for n, expected := range cases {
+		res, err := InterpolateStrict(map[string]interface{}{"n": n}, `{{ pluralWithCount .n "item" "items" }}`)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if res != expected {
+			t.Errorf(`Unexpected result %q for %v`, res, n)
+		}
+	}
+	res, err := InterpolateStrict(map[string]interface{}{"n": json.Number("1")}, `{{ .n }} {{ plural .n "person" "people" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "1 person" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	_, err = plural("many", "item", "items")
+	if err == nil {
+		t.Errorf(`Expected a non numeric count to fail`)
+	}
+}
+
+func TestPluralize(t *testing.T) {
+	var cases = map[string]string{
+		"item": "items", "box": "boxes", "church": "churches", "dish": "dishes", "class": "classes",
+		"city": "cities", "day": "days", "person": "people", "child": "children", "knife": "knives",
+		"sheep": "sheep", "Item": "Items", "CITY": "CITIES", "Person": "People", "bus": "buses", "": "",
+	}
+	for singular, expected := range cases {
+		if res := pluralize(singular); res != expected {
+			t.Errorf(`Unexpected plural %q for %q`, res, singular)
+		}
+		if res := singularize(expected); res != singular {
+			t.Errorf(`Unexpected singular %q for %q`, res, expected)
+		}
+	}
+}
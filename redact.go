@@ -0,0 +1,166 @@
+package template
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// redactedText replaces tracked values in errors and logs
+const redactedText = "[REDACTED]"
+
+// minRedactedLen is the length below which a tracked value is too short to be told apart from other text
+const minRedactedLen = 4
+
+// DefaultRedactedFuncs are the functions whose results are redacted unless others are set with SetRedactedFuncs
+var DefaultRedactedFuncs = []string{"getAuthXBearerToken", "getAuthXBearerTokenFresh", "getSecret", "basicAuth", "env"}
+
+var redactedFuncs = makeRedactedFuncs(DefaultRedactedFuncs)
+
+func makeRedactedFuncs(names []string) map[string]bool {
+	var funcs = make(map[string]bool, len(names))
+	for _, name := range names {
+		funcs[name] = true
+	}
+	return funcs
+}
+
+// SetRedactedFuncs sets the functions whose string results are tracked during a render and replaced by [REDACTED]
+// in the errors it returns, the errors passed to the Observer and the requests passed to the HTTPLogger
+// getSecret results are redacted whether or not it is listed. It applies to templates parsed after it is called.
+// Passing no names restores DefaultRedactedFuncs.
+func SetRedactedFuncs(names ...string) {
+	if len(names) == 0 {
+		names = DefaultRedactedFuncs
+	}
+	redactedFuncs = makeRedactedFuncs(names)
+}
+
+// track records value to be redacted from the errors and logs of the render
+func (s *renderState) track(value string) {
+	if len(value) < minRedactedLen {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.redacted == nil {
+		s.redacted = map[string]bool{}
+	}
+	s.redacted[value] = true
+}
+
+// trackedFunc returns fn with the strings it returns tracked by the render
+func (s *renderState) trackedFunc(fn interface{}) interface{} {
+	var v = reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func {
+		return fn
+	}
+	var t = v.Type()
+	return reflect.MakeFunc(t, func(args []reflect.Value) []reflect.Value {
+		var out []reflect.Value
+		if t.IsVariadic() {
+			out = v.CallSlice(args)
+		} else {
+			out = v.Call(args)
+		}
+		for _, res := range out {
+			if res.Kind() == reflect.String {
+				s.track(res.String())
+			}
+		}
+		return out
+	}).Interface()
+}
+
+// redactedFuncMap returns the redacted functions of funcs, falling back to TemplateFuncs, tracked by the render
+func (s *renderState) redactedFuncMap(funcs map[string]interface{}) map[string]interface{} {
+	var tracked = make(map[string]interface{}, len(redactedFuncs))
+	for name := range redactedFuncs {
+		fn, ok := funcs[name]
+		if !ok {
+			fn, ok = TemplateFuncs[name]
+		}
+		if ok {
+			tracked[name] = s.trackedFunc(fn)
+		}
+	}
+	return tracked
+}
+
+// redact replaces the values tracked by the render that appear in text
+func (s *renderState) redact(text string) string {
+	s.mu.Lock()
+	var values = make([]string, 0, len(s.redacted))
+	for value := range s.redacted {
+		values = append(values, value)
+	}
+	s.mu.Unlock()
+	// Longest first so a value containing another is replaced whole
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+	for _, value := range values {
+		text = strings.ReplaceAll(text, value, redactedText)
+	}
+	return text
+}
+
+// redactorKey is the request context key of the function redacting the logged and observed request
+type redactorKey struct{}
+
+// redactorFrom returns the redaction for a request, which leaves text unchanged unless the request was made by a render
+func redactorFrom(ctx context.Context) func(string) string {
+	if redact, ok := ctx.Value(redactorKey{}).(func(string) string); ok {
+		return redact
+	}
+	return noRedact
+}
+
+func noRedact(text string) string {
+	return text
+}
+
+// redactedError is an error with tracked values replaced in its message
+type redactedError struct {
+	msg string
+	err error
+}
+
+func (e *redactedError) Error() string {
+	return e.msg
+}
+
+func (e *redactedError) Unwrap() error {
+	return e.err
+}
+
+// redactError returns err with redact applied to its message
+func redactError(err error, redact func(string) string) error {
+	if err == nil {
+		return nil
+	}
+	var msg = redact(err.Error())
+	if msg == err.Error() {
+		return err
+	}
+	return &redactedError{msg: msg, err: err}
+}
+
+// renderStateKey is the overlay entry holding the state of the render, which isn't a function
+// It can't be called as it isn't a valid identifier, and is left out of the funcs of templates by renderChainFuncs
+const renderStateKey = "\x00renderState"
+
+// renderStateOf returns the state of the render of overlay, nil when overlay isn't a render
+func renderStateOf(overlay map[string]interface{}) *renderState {
+	s, _ := overlay[renderStateKey].(*renderState)
+	return s
+}
+
+// redactRenderError redacts the values tracked by the render of overlay from err
+func redactRenderError(overlay map[string]interface{}, err error) error {
+	if s := renderStateOf(overlay); s != nil {
+		return redactError(err, s.redact)
+	}
+	return err
+}
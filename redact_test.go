@@ -0,0 +1,103 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// errorObserver records the errors of executions
+type errorObserver struct {
+	NopObserver
+	mu     sync.Mutex
+	errors []error
+}
+
+func (o *errorObserver) OnExecute(name string, duration time.Duration, err error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err != nil {
+		o.errors = append(o.errors, err)
+	}
+}
+
+func TestRedactErrors(t *testing.T) {
+	var key = AuthXCacheKey("https://authx.example.com", "client", "auth-1")
+	authxTokenCache.SetEx(key, "Bearer fake-token-1234", time.Minute)
+	defer PurgeAuthXToken(key)
+	restoreSecretProvider(t)
+	SetSecretProvider(&countingSecretProvider{secrets: map[string]string{"API_KEY": "k3y-5678"}})
+	t.Setenv("TEMPLATE_TEST_PASSWORD", "hunter2-env")
+	var o errorObserver
+	SetObserver(&o)
+	defer SetObserver(nil)
+
+	var cases = map[string]string{
+		`{{ semverParse (getAuthXBearerToken "https://authx.example.com" "client" "auth-1") }}`:                         "fake-token-1234",
+		`{{ $t := getAuthXBearerToken "https://authx.example.com" "client" "auth-1" }}{{ semverParse (print "v" $t) }}`: "fake-token-1234",
+		`{{ semverParse (getSecret "API_KEY") }}`:                                                                       "k3y-5678",
+		`{{ semverParse (basicAuth "user" "passw0rd") }}`:                                                               "dXNlcjpwYXNzdzByZA==",
+		`{{ semverParse (env "TEMPLATE_TEST_PASSWORD") }}`:                                                              "hunter2-env",
+		`{{ with env "TEMPLATE_TEST_PASSWORD" }}{{ semverParse . }}{{ end }}`:                                           "hunter2-env",
+	}
+	for src, secret := range cases {
+		_, err := InterpolateStrict(nil, src)
+		if err == nil || !strings.Contains(err.Error(), "[REDACTED]") || strings.Contains(err.Error(), secret) {
+			t.Errorf(`Unexpected InterpolateStrict error for %s: %v`, src, err)
+		}
+
+		tmpl, err := Parse(src)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		err = tmpl.Execute(&bytes.Buffer{}, nil)
+		var execErr *ExecError
+		if !errors.As(err, &execErr) || !strings.Contains(err.Error(), "[REDACTED]") || strings.Contains(err.Error(), secret) {
+			t.Errorf(`Unexpected Execute error for %s: %v`, src, err)
+		}
+
+		_, err = InterpolateMap(nil, map[string]interface{}{"a": map[string]interface{}{"b": src}})
+		if err == nil || !strings.HasPrefix(err.Error(), "a.b: ") || strings.Contains(err.Error(), secret) {
+			t.Errorf(`Unexpected InterpolateMap error for %s: %v`, src, err)
+		}
+	}
+
+	if len(o.errors) != 3*len(cases) {
+		t.Errorf(`Expected %d observed errors, got %d`, 3*len(cases), len(o.errors))
+	}
+	for _, err := range o.errors {
+		for _, secret := range cases {
+			if strings.Contains(err.Error(), secret) {
+				t.Errorf(`Secret observed in %v`, err)
+			}
+		}
+	}
+}
+
+func TestSetRedactedFuncs(t *testing.T) {
+	defer SetRedactedFuncs()
+	SetRedactedFuncs("bearerAuth")
+	var data = map[string]interface{}{"token": "tok-1234", "user": "user", "pass": "passw0rd"}
+	const src = `{{ semverParse (print (bearerAuth .token) (basicAuth .user .pass)) }}`
+	_, err := InterpolateStrict(data, src)
+	if err == nil || strings.Contains(err.Error(), "tok-1234") || !strings.Contains(err.Error(), "dXNlcjpwYXNzdzByZA==") {
+		t.Errorf(`Unexpected error %v`, err)
+	}
+
+	SetRedactedFuncs()
+	_, err = InterpolateStrict(data, src)
+	if err == nil || !strings.Contains(err.Error(), "tok-1234") || strings.Contains(err.Error(), "dXNlcjpwYXNzdzByZA==") {
+		t.Errorf(`Unexpected error %v`, err)
+	}
+
+	// Values too short to tell apart from other text are left alone
+	t.Setenv("TEMPLATE_TEST_SHORT", "abc")
+	_, err = InterpolateStrict(nil, `{{ semverParse (print "x" (env "TEMPLATE_TEST_SHORT")) }}`)
+	if err == nil || strings.Contains(err.Error(), "[REDACTED]") {
+		t.Errorf(`Unexpected error %v`, err)
+	}
+}
@@ -0,0 +1,93 @@
+package template
+
+// region is a first level subdivision of a country, such as a US state or Canadian province
+type region struct {
+	code, name string
+}
+
+// regions are the subdivisions of the countries supported by regionCode and regionName, keyed by alpha-2 country code
+var regions = map[string][]region{
+	"US": {
+		{"AL", "Alabama"}, {"AK", "Alaska"}, {"AZ", "Arizona"}, {"AR", "Arkansas"}, {"CA", "California"},
+		{"CO", "Colorado"}, {"CT", "Connecticut"}, {"DE", "Delaware"}, {"DC", "District of Columbia"}, {"FL", "Florida"},
+		{"GA", "Georgia"}, {"HI", "Hawaii"}, {"ID", "Idaho"}, {"IL", "Illinois"}, {"IN", "Indiana"},
+		{"IA", "Iowa"}, {"KS", "Kansas"}, {"KY", "Kentucky"}, {"LA", "Louisiana"}, {"ME", "Maine"},
+		{"MD", "Maryland"}, {"MA", "Massachusetts"}, {"MI", "Michigan"}, {"MN", "Minnesota"}, {"MS", "Mississippi"},
+		{"MO", "Missouri"}, {"MT", "Montana"}, {"NE", "Nebraska"}, {"NV", "Nevada"}, {"NH", "New Hampshire"},
+		{"NJ", "New Jersey"}, {"NM", "New Mexico"}, {"NY", "New York"}, {"NC", "North Carolina"}, {"ND", "North Dakota"},
+		{"OH", "Ohio"}, {"OK", "Oklahoma"}, {"OR", "Oregon"}, {"PA", "Pennsylvania"}, {"RI", "Rhode Island"},
+		{"SC", "South Carolina"}, {"SD", "South Dakota"}, {"TN", "Tennessee"}, {"TX", "Texas"}, {"UT", "Utah"},
+		{"VT", "Vermont"}, {"VA", "Virginia"}, {"WA", "Washington"}, {"WV", "West Virginia"}, {"WI", "Wisconsin"},
+		{"WY", "Wyoming"},
+		{"AS", "American Samoa"}, {"GU", "Guam"}, {"MP", "Northern Mariana Islands"}, {"PR", "Puerto Rico"},
+		{"UM", "United States Minor Outlying Islands"}, {"VI", "U.S. Virgin Islands"},
+	},
+	"CA": {
+		{"AB", "Alberta"}, {"BC", "British Columbia"}, {"MB", "Manitoba"}, {"NB", "New Brunswick"},
+		{"NL", "Newfoundland and Labrador"}, {"NS", "Nova Scotia"}, {"NT", "Northwest Territories"}, {"NU", "Nunavut"},
+		{"ON", "Ontario"}, {"PE", "Prince Edward Island"}, {"QC", "Quebec"}, {"SK", "Saskatchewan"}, {"YT", "Yukon"},
+	},
+}
+
+// regionAliases are common abbreviations and alternative names of regions, keyed by alpha-2 country code
+// Keys are normalized as by normalizeCountryKey, so "Calif." is "calif"
+var regionAliases = map[string]map[string]string{
+	"US": {
+		"ala": "AL", "ariz": "AZ", "ark": "AR", "calif": "CA", "cal": "CA", "colo": "CO", "conn": "CT",
+		"del": "DE", "washington dc": "DC", "washington d c": "DC", "dist of columbia": "DC", "fla": "FL",
+		"ill": "IL", "ind": "IN", "kan": "KS", "kans": "KS", "mass": "MA", "mich": "MI", "minn": "MN",
+		"miss": "MS", "mont": "MT", "neb": "NE", "nebr": "NE", "nev": "NV", "okla": "OK", "ore": "OR",
+		"oreg": "OR", "penn": "PA", "penna": "PA", "tenn": "TN", "tex": "TX", "wash": "WA", "wva": "WV",
+		"w va": "WV", "wis": "WI", "wisc": "WI", "wyo": "WY", "virgin islands": "VI", "us virgin islands": "VI",
+	},
+	"CA": {
+		"alta": "AB", "man": "MB", "nfld": "NL", "newfoundland": "NL", "labrador": "NL", "nwt": "NT",
+		"ont": "ON", "pei": "PE", "que": "QC", "pq": "QC", "sask": "SK", "yuk": "YT", "yukon territory": "YT",
+	},
+}
+
+// regionIndex maps the normalized codes, names and aliases of every region to its entry, by country
+var regionIndex = func() map[string]map[string]*region {
+	var index = make(map[string]map[string]*region, len(regions))
+	for country, list := range regions {
+		var byKey = make(map[string]*region, len(list)*2+len(regionAliases[country]))
+		var byCode = make(map[string]*region, len(list))
+		for i := range list {
+			var r = &list[i]
+			byCode[r.code] = r
+			byKey[normalizeCountryKey(r.code)] = r
+			byKey[normalizeCountryKey(r.name)] = r
+		}
+		for alias, code := range regionAliases[country] {
+			byKey[alias] = byCode[code]
+		}
+		index[country] = byKey
+	}
+	return index
+}()
+
+// findRegion looks up a region of country by code, name or common abbreviation
+func findRegion(country interface{}, input string) *region {
+	var c = findCountry(country)
+	if c == nil {
+		return nil
+	}
+	return regionIndex[c.alpha2][normalizeCountryKey(input)]
+}
+
+// regionCode returns the two letter code of a US state or territory or Canadian province or territory given its code,
+// name or a common abbreviation such as "Calif.", case and punctuation insensitively, or an empty string when unknown
+func regionCode(country interface{}, input string) string {
+	if r := findRegion(country, input); r != nil {
+		return r.code
+	}
+	return ""
+}
+
+// regionName returns the English name of a US or Canadian region, or an empty string when unknown
+func regionName(country interface{}, code string) string {
+	if r := findRegion(country, code); r != nil {
+		return r.name
+	}
+	return ""
+}
@@ -0,0 +1,59 @@
+package template
+
+import (
+	"testing"
+)
+
+func TestRegionCode(t *testing.T) {
+	for _, tc := range []struct {
+		country, input, code string
+	}{
+		{"US", "California", "CA"},
+		{"US", "CA", "CA"},
+		{"US", "ca", "CA"},
+		{"US", "calif.", "CA"},
+		{"US", " new  york ", "NY"},
+		{"US", "N.Y.", "NY"},
+		{"US", "W. Va.", "WV"},
+		{"US", "Washington, D.C.", "DC"},
+		{"US", "Puerto Rico", "PR"},
+		{"USA", "Mass.", "MA"},
+		{"United States", "Tex", "TX"},
+		{"CA", "Ontario", "ON"},
+		{"CA", "Québec", "QC"},
+		{"Canada", "B.C.", "BC"},
+		{"CA", "P.E.I.", "PE"},
+		{"CA", "Nfld.", "NL"},
+		{"CA", "California", ""},
+		{"US", "Ontario", ""},
+		{"US", "Atlantis", ""},
+		{"US", "", ""},
+		{"MX", "Jalisco", ""},
+		{"", "CA", ""},
+	} {
+		if res := regionCode(tc.country, tc.input); res != tc.code {
+			t.Errorf(`Unexpected result %q for %q in %q`, res, tc.input, tc.country)
+		}
+	}
+}
+
+func TestRegionName(t *testing.T) {
+	if res := regionName("US", "dc"); res != "District of Columbia" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	if res := regionName("CA", "NU"); res != "Nunavut" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	if res := regionName("US", "ZZ"); res != "" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+
+	res, err := InterpolateStrict(map[string]interface{}{"state": "Yukon Territory"}, `{{ firstNonEmpty (regionCode "CA" .state) "??" }} {{ or (regionCode "US" "Nowhere") "??" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "YT ??" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
@@ -0,0 +1,263 @@
+package template
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sync"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// renderState is the state of a single render shared by functions such as counterNext
+// A render is one call to Execute, InterpolateStrict, InterpolateMap or MapTemplate.Execute, including the partials,
+// files and templates it renders with include, UNSAFE_render, tryRender and memo. Concurrent renders never share state.
+type renderState struct {
+	mu           sync.Mutex
+	counters     map[string]int64
+	accumulators map[string]*accumulator
+	memos        map[string]string
+	memoPending  map[string]bool
+	secrets      map[string]string
+	// redacted are the values to redact from the errors and logs of the render, see SetRedactedFuncs
+	redacted map[string]bool
+	// ctx is the context of the render, which ends sleeps early
+	ctx context.Context
+	// slept is the total time the render has slept
+	slept    time.Duration
+	warnings []warning
+	// overlay is the funcs of the render, with which memo renders its source
+	overlay map[string]interface{}
+	// dryRun is set for a dry run, in which cacheGetOrSet doesn't cache what it renders
+	dryRun bool
+}
+
+// accumulator is an exact running total, formatted with as many decimal places as the most precise value added
+type accumulator struct {
+	sum   big.Rat
+	scale int
+}
+
+func (a *accumulator) total() json.Number {
+	return json.Number(a.sum.FloatString(a.scale))
+}
+
+// maxAccumulatorScale bounds the decimal places of an accumulator, beyond which values are not decimals
+const maxAccumulatorScale = 30
+
+// decimalScale returns the number of decimal places needed to represent r exactly
+func decimalScale(r *big.Rat) (int, error) {
+	var scaled = new(big.Rat).Set(r)
+	var ten = big.NewRat(10, 1)
+	for scale := 0; scale <= maxAccumulatorScale; scale++ {
+		if scaled.IsInt() {
+			return scale, nil
+		}
+		scaled.Mul(scaled, ten)
+	}
+	return 0, fmt.Errorf("%s has more than %d decimal places", r.FloatString(maxAccumulatorScale), maxAccumulatorScale)
+}
+
+// funcs are the render scoped functions bound to s
+func (s *renderState) funcs() map[string]interface{} {
+	return map[string]interface{}{
+		"counterNext":  s.counterNext,
+		"counterValue": s.counterValue,
+		"counterReset": s.counterReset,
+		"accumAdd":     s.accumAdd,
+		"accumGet":     s.accumGet,
+		"memo":         s.memo,
+		"getSecret":    s.getSecret,
+		"sleep":        s.sleep,
+		"retry":        s.retry,
+		"warn":         s.warn,
+	}
+}
+
+// counterNext increments the named counter, which starts at zero, and returns its new value
+func (s *renderState) counterNext(name string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.counters == nil {
+		s.counters = map[string]int64{}
+	}
+	s.counters[name]++
+	return s.counters[name]
+}
+
+// counterValue returns the current value of the named counter without incrementing it
+func (s *renderState) counterValue(name string) int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.counters[name]
+}
+
+// counterReset resets the named counter to zero so the next counterNext returns 1, returning an empty string
+func (s *renderState) counterReset(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.counters, name)
+	return ""
+}
+
+// accumAdd adds a number to the named accumulator, which starts at zero, and returns the running total
+// Numbers and numeric strings are added exactly as decimals, so 0.1 plus 0.2 is 0.3
+func (s *renderState) accumAdd(name string, v interface{}) (json.Number, error) {
+	r, err := interfaceToRat(v)
+	if err != nil {
+		return "", fmt.Errorf("accumAdd: %w", err)
+	}
+	scale, err := decimalScale(r)
+	if err != nil {
+		return "", fmt.Errorf("accumAdd: %w", err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.accumulators == nil {
+		s.accumulators = map[string]*accumulator{}
+	}
+	var a = s.accumulators[name]
+	if a == nil {
+		a = &accumulator{}
+		s.accumulators[name] = a
+	}
+	a.sum.Add(&a.sum, r)
+	a.scale = max(a.scale, scale)
+	return a.total(), nil
+}
+
+// accumGet returns the total of the named accumulator, 0 when nothing has been added
+func (s *renderState) accumGet(name string) json.Number {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if a := s.accumulators[name]; a != nil {
+		return a.total()
+	}
+	return "0"
+}
+
+// memo renders src with data the first time it's called with key in the render, returning the same result for
+// every later call with key without rendering src again. Template arguments are always evaluated, so the expensive
+// expression, such as an http lookup, must be in src to be evaluated once. Failed renders aren't memoized.
+func (s *renderState) memo(key string, src string, data interface{}) (string, error) {
+	s.mu.Lock()
+	if res, ok := s.memos[key]; ok {
+		s.mu.Unlock()
+		return res, nil
+	}
+	if s.memoPending[key] {
+		s.mu.Unlock()
+		return "", fmt.Errorf("memo: %q depends on itself", key)
+	}
+	if s.memoPending == nil {
+		s.memoPending = map[string]bool{}
+	}
+	s.memoPending[key] = true
+	s.mu.Unlock()
+
+	res, err := interpolateChain(nil, s.overlay, "", data, src)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.memoPending, key)
+	if err != nil {
+		return "", err
+	}
+	if s.memos == nil {
+		s.memos = map[string]string{}
+	}
+	s.memos[key] = res
+	return res, nil
+}
+
+// unscopedRenderFunc is the placeholder for a render scoped function in TemplateFuncs
+// It's only called by templates executed outside a render, such as an HTMLTemplate
+func unscopedRenderFunc(name string) func(...interface{}) (interface{}, error) {
+	return func(...interface{}) (interface{}, error) {
+		return nil, fmt.Errorf("%s is only available to templates executed with Execute or the Interpolate functions", name)
+	}
+}
+
+// newRenderOverlay starts a new render, returning the funcs of a new render state with overlay funcs added
+// The result is the overlay to pass to renderChainFuncs for the templates of the render
+func newRenderOverlay(overlay map[string]interface{}) map[string]interface{} {
+	return newContextRenderOverlay(context.Background(), overlay)
+}
+
+// newContextRenderOverlay is newRenderOverlay for a render with the context ctx
+func newContextRenderOverlay(ctx context.Context, overlay map[string]interface{}) map[string]interface{} {
+	var state = &renderState{ctx: ctx}
+	var funcs = state.funcs()
+	for name, fn := range state.httpFuncs() {
+		funcs[name] = fn
+	}
+	for name, fn := range overlay {
+		funcs[name] = fn
+	}
+	for name, fn := range state.redactedFuncMap(funcs) {
+		funcs[name] = fn
+	}
+	funcs[renderStateKey] = state
+	state.overlay = funcs
+	return funcs
+}
+
+// renderScopedFuncs are the functions whose use requires a template to be bound to a render when executed
+// Those rendering nested templates are included as the nested templates may use render scoped functions.
+var renderScopedFuncs = func() map[string]bool {
+	var names = map[string]bool{"include": true, "UNSAFE_render": true, "tryRender": true}
+	for name := range (&renderState{}).funcs() {
+		names[name] = true
+	}
+	return names
+}()
+
+// renderScope records whether a template uses render scoped functions
+type renderScope int8
+
+const (
+	// renderScopeUnknown templates, such as those parsed with the embedded text/template methods, are checked when executed
+	renderScopeUnknown renderScope = iota
+	renderScopeNone
+	renderScopeUsed
+)
+
+func renderScopeOf(tmpl *template.Template) renderScope {
+	if usesRenderState(tmpl) {
+		return renderScopeUsed
+	}
+	return renderScopeNone
+}
+
+// usesRenderState reports whether t uses render scoped functions, see usesRenderState
+func (t *Template) usesRenderState() bool {
+	switch t.renderScope {
+	case renderScopeNone:
+		return false
+	case renderScopeUsed:
+		return true
+	}
+	return usesRenderState(t.Template)
+}
+
+// usesRenderState reports whether tmpl, or any template associated with it such as a partial, calls a render scoped
+// function or one whose results are redacted
+func usesRenderState(tmpl *template.Template) bool {
+	var found bool
+	for _, t := range tmpl.Templates() {
+		if t.Tree == nil {
+			continue
+		}
+		walkNodes(t.Tree.Root, func(node parse.Node) {
+			if ident, ok := node.(*parse.IdentifierNode); ok && (renderScopedFuncs[ident.Ident] || redactedFuncs[ident.Ident]) {
+				found = true
+			}
+		})
+		if found {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,238 @@
+package template
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestCounters(t *testing.T) {
+	var data = map[string]interface{}{"rows": []interface{}{"a", "b", "c"}}
+	tmpl, err := Parse(`{{ range .rows }}{{ counterNext "line" }}:{{ . }} {{ end }}{{ counterValue "line" }} {{ counterValue "other" }}{{ counterReset "line" }} {{ counterNext "line" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for i := 0; i < 2; i++ {
+		res, err := tmpl.ExecuteToString(data)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		// Each Execute starts its counters again
+		if res != "1:a 2:b 3:c 3 0 1" {
+			t.Errorf(`Unexpected result %q`, res)
+		}
+	}
+
+	res, err := InterpolateStrict(data, `{{ range .rows }}{{ counterNext "n" }}{{ end }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "123" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestCountersConcurrentExecute(t *testing.T) {
+	var rows = make([]interface{}, 200)
+	var expected strings.Builder
+	for i := range rows {
+		rows[i] = i
+		fmt.Fprintf(&expected, "%d,", i+1)
+	}
+	tmpl, err := Parse(`{{ range .rows }}{{ counterNext "line" }},{{ end }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var buf bytes.Buffer
+			if err := tmpl.Execute(&buf, map[string]interface{}{"rows": rows}); err != nil {
+				t.Error(err)
+				return
+			}
+			if buf.String() != expected.String() {
+				t.Errorf(`Unexpected result %q`, buf.String())
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCountersSharedWithNestedRenders(t *testing.T) {
+	restoreRootTemplate(t)
+	if err := LoadPartial("line", `{{ counterNext "line" }}`); err != nil {
+		t.Error(err)
+		return
+	}
+	var data = map[string]interface{}{"rows": []interface{}{"a", "b"}}
+	tmpl, err := Parse(`{{ range .rows }}{{ template "line" }}{{ include "line" . }}{{ tryRender "{{ counterNext \"line\" }}" . "x" }}{{ end }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := tmpl.ExecuteToString(data)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "123456" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestCountersOutsideRender(t *testing.T) {
+	tmpl, err := ParseHTML(`{{ counterNext "line" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if _, err := tmpl.ExecuteToString(nil); err == nil || !strings.Contains(err.Error(), "counterNext is only available") {
+		t.Errorf(`Unexpected error %v`, err)
+	}
+	res, err := InterpolateHTML(nil, `{{ counterNext "line" }}{{ counterNext "line" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "12" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestAccumulators(t *testing.T) {
+	var data = map[string]interface{}{"items": []interface{}{
+		map[string]interface{}{"amount": 0.1},
+		map[string]interface{}{"amount": json.Number("0.2")},
+		map[string]interface{}{"amount": "12.345"},
+		map[string]interface{}{"amount": 100},
+	}}
+	tmpl, err := Parse(`{{ range .items }}D,{{ .amount }},{{ accumAdd "total" .amount }}{{ "\n" }}{{ end }}T,{{ accumGet "total" }},{{ accumGet "none" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for i := 0; i < 2; i++ {
+		res, err := tmpl.ExecuteToString(data)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if res != "D,0.1,0.1\nD,0.2,0.3\nD,12.345,12.645\nD,100,112.645\nT,112.645,0" {
+			t.Errorf(`Unexpected result %q`, res)
+		}
+	}
+
+	if _, err := InterpolateStrict(nil, `{{ accumAdd "total" "abc" }}`); err == nil {
+		t.Error("Expected error for non-numeric value")
+	}
+
+	// Concurrent renders keep separate totals
+	var wg sync.WaitGroup
+	for i := 1; i <= 8; i++ {
+		wg.Add(1)
+		go func(n int) {
+			defer wg.Done()
+			var amounts = make([]interface{}, n)
+			for j := range amounts {
+				amounts[j] = map[string]interface{}{"amount": 1.5}
+			}
+			res, err := tmpl.ExecuteToString(map[string]interface{}{"items": amounts})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if !strings.HasSuffix(res, fmt.Sprintf("T,%.1f,0", 1.5*float64(n))) {
+				t.Errorf(`Unexpected result %q for %d items`, res, n)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func TestMemo(t *testing.T) {
+	restoreTemplateFuncs(t)
+	var calls int32
+	if err := RegisterFunc("expensiveLookup", func(id string) string {
+		atomic.AddInt32(&calls, 1)
+		return "customer-" + id
+	}); err != nil {
+		t.Error(err)
+		return
+	}
+	var data = map[string]interface{}{"id": "42"}
+	var templateMap = map[string]interface{}{
+		"name":  `{{ memo "customer" "{{ expensiveLookup .id }}" . }}`,
+		"email": `{{ memo "customer" "{{ expensiveLookup .id }}" . }}@example.com`,
+		"nested": map[string]interface{}{
+			"again": `{{ memo "customer" "{{ expensiveLookup .id }}" . }}/{{ memo "customer" "ignored" . }}`,
+		},
+		"other": `{{ memo "other" "{{ expensiveLookup \"7\" }}" . }}`,
+	}
+	var expected = map[string]interface{}{
+		"name":   "customer-42",
+		"email":  "customer-42@example.com",
+		"nested": map[string]interface{}{"again": "customer-42/customer-42"},
+		"other":  "customer-7",
+	}
+
+	res, err := InterpolateMap(data, templateMap)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf(`Unexpected result %v`, res)
+	}
+	if calls != 2 {
+		t.Errorf(`Unexpected %d evaluations`, calls)
+	}
+
+	// Each InterpolateMap and MapTemplate.Execute is a new render
+	calls = 0
+	compiled, err := CompileMap(templateMap)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for i := 0; i < 2; i++ {
+		res, err = compiled.Execute(data)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if !reflect.DeepEqual(res, expected) {
+			t.Errorf(`Unexpected result %v`, res)
+		}
+	}
+	if calls != 4 {
+		t.Errorf(`Unexpected %d evaluations`, calls)
+	}
+}
+
+func TestMemoErrors(t *testing.T) {
+	_, err := InterpolateStrict(nil, `{{ memo "a" "{{ memo \"a\" \"x\" . }}" . }}`)
+	if err == nil || !strings.Contains(err.Error(), `memo: "a" depends on itself`) {
+		t.Errorf(`Unexpected error %v`, err)
+	}
+	// Failures aren't memoized
+	res, err := InterpolateStrict(nil, `{{ tryRender "{{ memo \"a\" \"{{ index 1 0 }}\" . }}" . "failed" }} {{ memo "a" "ok" . }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "failed ok" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
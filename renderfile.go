@@ -0,0 +1,110 @@
+package template
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RenderFile executes the template in templatePath against the data in dataPath and writes the output to w
+// Data files ending in .yaml or .yml are parsed as YAML, others as JSON with numbers decoded as json.Number.
+// An empty dataPath executes the template with nil data. The template is named after templatePath and
+// uses the partials and functions configured on the RootTemplate.
+func RenderFile(templatePath, dataPath string, w io.Writer) error {
+	src, err := os.ReadFile(templatePath)
+	if err != nil {
+		return err
+	}
+	var data interface{}
+	if dataPath != "" {
+		f, err := os.Open(dataPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		switch strings.ToLower(filepath.Ext(dataPath)) {
+		case ".yaml", ".yml":
+			data, err = decodeYAMLData(f)
+		default:
+			data, err = decodeJSONData(f)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", dataPath, err)
+		}
+	}
+	return renderSource(templatePath, string(src), data, w)
+}
+
+// RenderReader executes the template read from tmpl against the JSON document read from data and writes the output to w
+// Numbers are decoded as json.Number and a nil data reader executes the template with nil data
+func RenderReader(tmpl, data io.Reader, w io.Writer) error {
+	var v interface{}
+	if data != nil {
+		var err error
+		v, err = decodeJSONData(data)
+		if err != nil {
+			return err
+		}
+	}
+	return renderReader(tmpl, v, w)
+}
+
+// RenderReaderYAML is RenderReader with the data read as a YAML document
+func RenderReaderYAML(tmpl, data io.Reader, w io.Writer) error {
+	var v interface{}
+	if data != nil {
+		var err error
+		v, err = decodeYAMLData(data)
+		if err != nil {
+			return err
+		}
+	}
+	return renderReader(tmpl, v, w)
+}
+
+func renderReader(tmpl io.Reader, data interface{}, w io.Writer) error {
+	src, err := io.ReadAll(tmpl)
+	if err != nil {
+		return err
+	}
+	return renderSource("", string(src), data, w)
+}
+
+// renderSource parses src as the template name and executes it against data
+func renderSource(name, src string, data interface{}, w io.Writer) error {
+	t, err := ParseNamed(name, src)
+	if err != nil {
+		return newExecError("", src, err)
+	}
+	return t.Execute(w, data)
+}
+
+// decodeJSONData decodes a single JSON document with numbers as json.Number
+func decodeJSONData(r io.Reader) (interface{}, error) {
+	var v interface{}
+	var dec = json.NewDecoder(r)
+	dec.UseNumber()
+	err := dec.Decode(&v)
+	if err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after JSON document")
+	}
+	return v, nil
+}
+
+// decodeYAMLData decodes a single YAML document, an empty document decodes to nil
+func decodeYAMLData(r io.Reader) (interface{}, error) {
+	var v interface{}
+	err := yaml.NewDecoder(r).Decode(&v)
+	if err == io.EOF {
+		return nil, nil
+	}
+	return v, err
+}
@@ -0,0 +1,85 @@
+package template
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRenderFile(t *testing.T) {
+	var dir = t.TempDir()
+	var files = map[string]string{
+		"welcome.tmpl": `{{ .name }} owes {{ .amount }}{{ if .tags }} {{ .tags }}{{ end }}`,
+		"data.json":    `{"name": "Ada", "amount": 12345678901234567890.10, "tags": ["a", "b"]}`,
+		"data.yml":     "name: Grace\namount: 3\n",
+		"bad.json":     `{"name": "Ada"} {}`,
+	}
+	for name, content := range files {
+		err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+	}
+	var tmplPath = filepath.Join(dir, "welcome.tmpl")
+
+	var buf bytes.Buffer
+	err := RenderFile(tmplPath, filepath.Join(dir, "data.json"), &buf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != "Ada owes 12345678901234567890.10 [a b]" {
+		t.Errorf(`Unexpected result %q`, buf.String())
+	}
+
+	buf.Reset()
+	err = RenderFile(tmplPath, filepath.Join(dir, "data.yml"), &buf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != "Grace owes 3" {
+		t.Errorf(`Unexpected result %q`, buf.String())
+	}
+
+	err = RenderFile(tmplPath, filepath.Join(dir, "bad.json"), &buf)
+	if err == nil || !strings.Contains(err.Error(), "bad.json") {
+		t.Errorf(`Expected the data error to name the file, got %v`, err)
+	}
+	err = RenderFile(tmplPath, filepath.Join(dir, "missing.json"), &buf)
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf(`Expected a not exist error, got %v`, err)
+	}
+}
+
+func TestRenderReader(t *testing.T) {
+	var buf bytes.Buffer
+	err := RenderReader(strings.NewReader(`{{ .n }}`), strings.NewReader(`{"n": 1.50}`), &buf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != "1.50" {
+		t.Errorf(`Unexpected result %q`, buf.String())
+	}
+
+	buf.Reset()
+	err = RenderReaderYAML(strings.NewReader(`{{ .list }}`), strings.NewReader("list: [1, 2]"), &buf)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != "[1 2]" {
+		t.Errorf(`Unexpected result %q`, buf.String())
+	}
+
+	buf.Reset()
+	err = RenderReader(strings.NewReader(`static`), nil, &buf)
+	if err != nil || buf.String() != "static" {
+		t.Errorf(`Unexpected result %q, %v`, buf.String(), err)
+	}
+}
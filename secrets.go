@@ -0,0 +1,112 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// ErrSecretNotFound is returned by a SecretProvider when a secret doesn't exist
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretProvider supplies the secrets templates read with getSecret, such as API keys for the requests they make
+// Methods may be called concurrently
+type SecretProvider interface {
+	// GetSecret returns the secret named name, or ErrSecretNotFound if there is none
+	GetSecret(name string) (string, error)
+}
+
+var secretProvider SecretProvider
+
+// SetSecretProvider sets the provider used by getSecret, nil makes getSecret fail, which is the default
+func SetSecretProvider(p SecretProvider) {
+	secretProvider = p
+}
+
+// EnvSecretProvider is a SecretProvider reading environment variables, limited to an allowlist
+type EnvSecretProvider struct {
+	allowed map[string]bool
+}
+
+// NewEnvSecretProvider returns a provider reading the environment variables names, and no others
+func NewEnvSecretProvider(names ...string) *EnvSecretProvider {
+	var allowed = make(map[string]bool, len(names))
+	for _, name := range names {
+		allowed[name] = true
+	}
+	return &EnvSecretProvider{allowed: allowed}
+}
+
+// GetSecret implementation for EnvSecretProvider
+func (p *EnvSecretProvider) GetSecret(name string) (string, error) {
+	if !p.allowed[name] {
+		return "", fmt.Errorf("environment variable %q is not an allowed secret", name)
+	}
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+// getSecret returns the secret named name from the SecretProvider
+// Secrets are requested once per render and never cached beyond it. They are redacted from the errors of the
+// render and the requests it logs, see SetRedactedFuncs.
+func (s *renderState) getSecret(name string) (string, error) {
+	s.mu.Lock()
+	value, ok := s.secrets[name]
+	s.mu.Unlock()
+	if ok {
+		return value, nil
+	}
+	var provider = secretProvider
+	if provider == nil {
+		return "", fmt.Errorf("getSecret: no secret provider is configured, see SetSecretProvider")
+	}
+	value, err := provider.GetSecret(name)
+	if err != nil {
+		return "", fmt.Errorf("getSecret %q: %w", name, err)
+	}
+	s.mu.Lock()
+	if s.secrets == nil {
+		s.secrets = map[string]string{}
+	}
+	s.secrets[name] = value
+	s.mu.Unlock()
+	s.track(value)
+	return value, nil
+}
+
+// doHTTP is doHTTP with the request made in the context of the render, so rate limit waits end with it and the
+// HTTPLogger receives it, and with the values tracked by the render redacted from the logged and observed request
+func (s *renderState) doHTTP(req *http.Request) (*http.Response, error) {
+	var ctx = s.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return doHTTP(req.WithContext(context.WithValue(ctx, redactorKey{}, s.redact)))
+}
+
+// httpFuncs are the functions sending requests as part of the render
+// Functions replaced with RegisterFunc are left to the replacement.
+func (s *renderState) httpFuncs() map[string]interface{} {
+	var funcs = map[string]interface{}{
+		"http":      httpFunc(s.doHTTP),
+		"http_data": httpDataFunc(s.doHTTP),
+		"graphql": func(url string, headers map[interface{}]interface{}, query string, variables interface{}) (interface{}, error) {
+			return graphqlDo(s.doHTTP, url, headers, query, variables)
+		},
+		"ipLookup":                 ipLookupFunc(s.doHTTP, true),
+		"binLookup":                binLookupFunc(s.doHTTP, true),
+		"getAuthXBearerToken":      authxBearerTokenFunc(s.doHTTP),
+		"getAuthXBearerTokenFresh": authxBearerTokenFreshFunc(s.doHTTP),
+	}
+	for name := range funcs {
+		if registeredFuncs[name] {
+			delete(funcs, name)
+		}
+	}
+	return funcs
+}
@@ -0,0 +1,138 @@
+package template
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countingSecretProvider serves secrets from a map, counting the lookups
+type countingSecretProvider struct {
+	secrets map[string]string
+	lookups int32
+}
+
+func (p *countingSecretProvider) GetSecret(name string) (string, error) {
+	atomic.AddInt32(&p.lookups, 1)
+	value, ok := p.secrets[name]
+	if !ok {
+		return "", ErrSecretNotFound
+	}
+	return value, nil
+}
+
+func restoreSecretProvider(t *testing.T) {
+	var p = secretProvider
+	t.Cleanup(func() {
+		secretProvider = p
+	})
+}
+
+func TestGetSecret(t *testing.T) {
+	restoreSecretProvider(t)
+	SetSecretProvider(nil)
+	_, err := InterpolateStrict(nil, `{{ getSecret "API_KEY" }}`)
+	if err == nil || !strings.Contains(err.Error(), "no secret provider") {
+		t.Errorf(`Unexpected error %v`, err)
+	}
+
+	var p = &countingSecretProvider{secrets: map[string]string{"API_KEY": "k3y"}}
+	SetSecretProvider(p)
+	for i := 1; i <= 2; i++ {
+		result, err := InterpolateStrict(nil, `{{ getSecret "API_KEY" }}-{{ getSecret "API_KEY" }}`)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if result != "k3y-k3y" {
+			t.Errorf(`Unexpected result %q`, result)
+		}
+		// Secrets are cached for the render only
+		if n := atomic.LoadInt32(&p.lookups); n != int32(i) {
+			t.Errorf(`Expected %d lookups, got %d`, i, n)
+		}
+	}
+
+	_, err = InterpolateStrict(nil, `{{ getSecret "MISSING" }}`)
+	if !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf(`Unexpected error %v`, err)
+	}
+}
+
+func TestEnvSecretProvider(t *testing.T) {
+	t.Setenv("TEMPLATE_TEST_SECRET", "s3cret")
+	t.Setenv("TEMPLATE_TEST_OTHER", "other")
+	var p = NewEnvSecretProvider("TEMPLATE_TEST_SECRET", "TEMPLATE_TEST_UNSET")
+	value, err := p.GetSecret("TEMPLATE_TEST_SECRET")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if value != "s3cret" {
+		t.Errorf(`Unexpected result %q`, value)
+	}
+	_, err = p.GetSecret("TEMPLATE_TEST_OTHER")
+	if err == nil || !strings.Contains(err.Error(), "not an allowed secret") {
+		t.Errorf(`Unexpected error %v`, err)
+	}
+	_, err = p.GetSecret("TEMPLATE_TEST_UNSET")
+	if !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf(`Unexpected error %v`, err)
+	}
+}
+
+func TestGetSecretRedactedFromHTTPLogs(t *testing.T) {
+	restoreSecretProvider(t)
+	SetSecretProvider(&countingSecretProvider{secrets: map[string]string{"API_KEY": "k3y-123"}})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("key") != "k3y-123" || r.Header.Get("X-Partner-Key") != "k3y-123" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	var entries []httpLogEntry
+	SetHTTPLogger(func(ctx context.Context, method, url string, header http.Header, status int, duration time.Duration, err error) {
+		entries = append(entries, httpLogEntry{method, url, header, status, err})
+	}, nil)
+	defer SetHTTPLogger(nil, nil)
+	var o CountingObserver
+	SetObserver(&o)
+	defer SetObserver(nil)
+
+	result, err := InterpolateStrict(map[string]interface{}{"url": srv.URL}, `{{ $key := getSecret "API_KEY" }}{{ (http "GET" (print .url "?key=" $key) (dict "X-Partner-Key" $key)).StatusCode }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if result != "204" {
+		t.Errorf(`Unexpected result %q`, result)
+	}
+	_, err = InterpolateStrict(nil, `{{ http "GET" (print "http://127.0.0.1:0/?key=" (getSecret "API_KEY")) (dict) }}`)
+	if err == nil {
+		t.Errorf(`Expected request to fail`)
+	}
+
+	if len(entries) != 2 {
+		t.Errorf(`Unexpected log entries %v`, entries)
+		return
+	}
+	if entries[0].url != srv.URL+"?key=[REDACTED]" || entries[0].header.Get("X-Partner-Key") != "[REDACTED]" {
+		t.Errorf(`Unexpected log entry %+v`, entries[0])
+	}
+	if entries[1].err == nil || strings.Contains(entries[1].err.Error(), "k3y-123") {
+		t.Errorf(`Unexpected log entry %+v`, entries[1])
+	}
+	for url := range o.HTTPRequests {
+		if strings.Contains(url, "k3y-123") {
+			t.Errorf(`Secret observed in %q`, url)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package template
+
+import (
+	"fmt"
+
+	"github.com/Masterminds/semver"
+)
+
+// parseSemver parses a semantic version, tolerating a leading v and missing minor or patch components
+func parseSemver(v string) (*semver.Version, error) {
+	version, err := semver.NewVersion(v)
+	if err != nil {
+		return nil, fmt.Errorf("invalid semantic version %q: %w", v, err)
+	}
+	return version, nil
+}
+
+// semverParse returns the major, minor and patch numbers, prerelease and metadata of a semantic version,
+// and version, its normalized form
+func semverParse(v string) (map[string]interface{}, error) {
+	version, err := parseSemver(v)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{
+		"major":      version.Major(),
+		"minor":      version.Minor(),
+		"patch":      version.Patch(),
+		"prerelease": version.Prerelease(),
+		"metadata":   version.Metadata(),
+		"version":    version.String(),
+	}, nil
+}
+
+// semverCompare returns -1, 0 or 1 as semantic version a is lower than, equal to or higher than b
+// Prereleases are lower than their release and build metadata is ignored
+func semverCompare(a, b string) (int, error) {
+	va, err := parseSemver(a)
+	if err != nil {
+		return 0, err
+	}
+	vb, err := parseSemver(b)
+	if err != nil {
+		return 0, err
+	}
+	return va.Compare(vb), nil
+}
+
+// semverSatisfies reports whether a semantic version satisfies a constraint such as ">= 2.3.0", "~1.2", "^2"
+// or ">= 1.2, < 2", with || separating alternatives. Prereleases only satisfy constraints naming a prerelease.
+func semverSatisfies(constraint, v string) (bool, error) {
+	c, err := semver.NewConstraint(constraint)
+	if err != nil {
+		return false, fmt.Errorf("invalid semantic version constraint %q: %w", constraint, err)
+	}
+	version, err := parseSemver(v)
+	if err != nil {
+		return false, err
+	}
+	return c.Check(version), nil
+}
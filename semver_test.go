@@ -0,0 +1,107 @@
+package template
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSemverParse(t *testing.T) {
+	res, err := semverParse("v2.3.0-beta.1+build.5")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var expected = map[string]interface{}{
+		"major": int64(2), "minor": int64(3), "patch": int64(0),
+		"prerelease": "beta.1", "metadata": "build.5", "version": "2.3.0-beta.1+build.5",
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Errorf(`Unexpected version %v`, res)
+	}
+	res, err = semverParse("1.2")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res["version"] != "1.2.0" {
+		t.Errorf(`Unexpected version %v`, res)
+	}
+	_, err = semverParse("one.two")
+	if err == nil || !strings.Contains(err.Error(), `"one.two"`) {
+		t.Errorf(`Expected an invalid version error, got %v`, err)
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	// Each version is lower than the next, by semver precedence
+	var ordered = []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2",
+		"1.0.0-beta.11", "1.0.0-rc.1", "v1.0.0", "1.0.1", "1.1", "2.3.0", "v10.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			var expected = 0
+			if i < j {
+				expected = -1
+			} else if i > j {
+				expected = 1
+			}
+			res, err := semverCompare(ordered[i], ordered[j])
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			if res != expected {
+				t.Errorf(`Unexpected comparison %d of %s and %s`, res, ordered[i], ordered[j])
+			}
+		}
+	}
+	res, err := semverCompare("1.2.3+build.1", "1.2.3+build.2")
+	if err != nil || res != 0 {
+		t.Errorf(`Expected build metadata to be ignored, got %d, %v`, res, err)
+	}
+}
+
+func TestSemverSatisfies(t *testing.T) {
+	var cases = []struct {
+		constraint, version string
+		expected            bool
+	}{
+		{">= 2.3.0", "2.3.0", true},
+		{">= 2.3.0", "v2.10", true},
+		{">= 2.3.0", "2.2.9", false},
+		{">= 2.3.0", "2.4.0-beta", false},
+		{">= 2.3.0-0", "2.4.0-beta", true},
+		{"< 2", "1.9.9", true},
+		{"~1.2", "1.2.9", true},
+		{"~1.2", "1.3.0", false},
+		{"^2", "2.9.0", true},
+		{"^2", "3.0.0", false},
+		{">= 1.2, < 2", "1.5.0", true},
+		{"< 1 || >= 3", "2.0.0", false},
+	}
+	for _, c := range cases {
+		res, err := semverSatisfies(c.constraint, c.version)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if res != c.expected {
+			t.Errorf(`Unexpected result %v for %s satisfying %q`, res, c.version, c.constraint)
+		}
+	}
+	_, err := semverSatisfies(">>= 1", "1.0.0")
+	if err == nil || !strings.Contains(err.Error(), "constraint") {
+		t.Errorf(`Expected an invalid constraint error, got %v`, err)
+	}
+
+	out, err := InterpolateStrict(map[string]interface{}{"app": map[string]interface{}{"version": "v2.3"}}, `{{ if semverSatisfies ">= 2.3.0" .app.version }}new{{ else }}old{{ end }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if out != "new" {
+		t.Errorf(`Unexpected result %q`, out)
+	}
+}
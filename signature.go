@@ -0,0 +1,86 @@
+package template
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/the-control-group/go-timeutils"
+)
+
+// signPayload returns a webhook signature header value "t=<unix>,v1=<hex hmac-sha256 of t.body>" for the current time
+func signPayload(secret, body string) string {
+	return payloadSignature(secret, body, clock().Unix())
+}
+
+// signPayloadAt is signPayload at the given unix timestamp
+func signPayloadAt(secret, body string, timestamp interface{}) (string, error) {
+	ts, err := interfaceToWholeInt64(timestamp)
+	if err != nil {
+		return "", fmt.Errorf("signPayloadAt: %w", err)
+	}
+	return payloadSignature(secret, body, ts), nil
+}
+
+func payloadSignature(secret, body string, timestamp int64) string {
+	var t = strconv.FormatInt(timestamp, 10)
+	return "t=" + t + ",v1=" + hex.EncodeToString(payloadHMAC(secret, t, body))
+}
+
+func payloadHMAC(secret, timestamp, body string) []byte {
+	var mac = hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write([]byte(body))
+	return mac.Sum(nil)
+}
+
+// verifyPayloadSignature reports whether header is a valid signPayload signature of body
+// Any of several v1 signatures may match, compared in constant time. The timestamp must be within
+// tolerance of the current time, a duration such as "5m" or a number of seconds; zero skips the check.
+// Malformed headers are reported as invalid rather than as errors.
+func verifyPayloadSignature(secret, body, header string, tolerance interface{}) (bool, error) {
+	tol, err := timeutils.InterfaceToApproxBigDuration(tolerance)
+	if err != nil {
+		return false, fmt.Errorf("verifyPayloadSignature: tolerance: %w", err)
+	}
+	var timestamp string
+	var signatures [][]byte
+	for _, part := range strings.Split(header, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "t":
+			timestamp = value
+		case "v1":
+			sig, err := hex.DecodeString(value)
+			if err == nil {
+				signatures = append(signatures, sig)
+			}
+		}
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || len(signatures) == 0 {
+		return false, nil
+	}
+	if tol > 0 {
+		var age = clock().Sub(time.Unix(ts, 0))
+		if age > time.Duration(tol) || age < -time.Duration(tol) {
+			return false, nil
+		}
+	}
+	var expected = payloadHMAC(secret, timestamp, body)
+	var valid bool
+	for _, sig := range signatures {
+		if hmac.Equal(sig, expected) {
+			valid = true
+		}
+	}
+	return valid, nil
+}
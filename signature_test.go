@@ -0,0 +1,66 @@
+package template
+
+import (
+	"testing"
+	"time"
+)
+
+const testSignature = "t=1700000000,v1=c89214b5b5da833daed6f0b8c5bb6bd58cea9022bd80ccc78230f3942d632925"
+
+func TestSignPayload(t *testing.T) {
+	var data = map[string]interface{}{"secret": "whsec_test", "body": `{"id":"evt_1"}`}
+	res, err := InterpolateStrict(data, `{{ signPayloadAt .secret .body 1700000000 }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != testSignature {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+
+	defer SetClock(nil)
+	SetClock(FixedClock(time.Unix(1700000000, 0)))
+	res, err = InterpolateStrict(data, `{{ signPayload .secret .body }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != testSignature {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestVerifyPayloadSignature(t *testing.T) {
+	defer SetClock(nil)
+	SetClock(FixedClock(time.Unix(1700000100, 0)))
+	var body = `{"id":"evt_1"}`
+	for _, c := range []struct {
+		body, header string
+		tolerance    interface{}
+		valid        bool
+	}{
+		{body, testSignature, "5m", true},
+		{body, testSignature, 0, true},
+		{body, "t=1700000000,v1=00ff,v1=c89214b5b5da833daed6f0b8c5bb6bd58cea9022bd80ccc78230f3942d632925", "5m", true},
+		{body, testSignature, "1m", false},
+		{`{"id":"evt_2"}`, testSignature, "5m", false},
+		{body, "t=1700000001,v1=c89214b5b5da833daed6f0b8c5bb6bd58cea9022bd80ccc78230f3942d632925", "5m", false},
+		{body, "v1=c89214b5b5da833daed6f0b8c5bb6bd58cea9022bd80ccc78230f3942d632925", 0, false},
+		{body, "t=1700000000", 0, false},
+		{body, "garbage", 0, false},
+	} {
+		valid, err := verifyPayloadSignature("whsec_test", c.body, c.header, c.tolerance)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if valid != c.valid {
+			t.Errorf(`Expected %v for %q with tolerance %v`, c.valid, c.header, c.tolerance)
+		}
+	}
+
+	_, err := verifyPayloadSignature("whsec_test", body, testSignature, true)
+	if err == nil {
+		t.Errorf(`Expected an invalid tolerance error`)
+	}
+}
@@ -0,0 +1,121 @@
+package template
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/the-control-group/go-timeutils"
+)
+
+// DefaultMaxSleepPerExecute is the total time sleep may pause a render unless another limit is set with SetMaxSleepPerExecute
+const DefaultMaxSleepPerExecute = 5 * time.Second
+
+var maxSleepPerExecute = DefaultMaxSleepPerExecute
+
+// SetMaxSleepPerExecute sets the total time sleep may pause a render, zero disables sleep
+func SetMaxSleepPerExecute(limit time.Duration) {
+	maxSleepPerExecute = limit
+}
+
+// interfaceToDuration converts Go durations such as "1.5s" or "500ms", approximate durations such as "2 mins", and numbers of nanoseconds
+func interfaceToDuration(i interface{}) (time.Duration, error) {
+	switch v := i.(type) {
+	case time.Duration:
+		return v, nil
+	case string:
+		if d, err := time.ParseDuration(strings.TrimSpace(v)); err == nil {
+			return d, nil
+		}
+		// ParseApproxBigDuration ignores text it doesn't recognize
+		d, err := timeutils.ParseApproxBigDuration([]byte(v))
+		if err == nil && d == 0 {
+			err = fmt.Errorf("invalid duration %q", v)
+		}
+		return time.Duration(d), err
+	}
+	d, err := timeutils.InterfaceToApproxBigDuration(i)
+	return time.Duration(d), err
+}
+
+// sleep pauses the render for d, returning an empty string
+// Renders may sleep at most the limit set with SetMaxSleepPerExecute in total, sleeping longer is an error
+// rather than a shorter sleep. The sleep ends early with an error when the context of the render is done.
+func (s *renderState) sleep(d interface{}) (string, error) {
+	dur, err := interfaceToDuration(d)
+	if err != nil {
+		return "", fmt.Errorf("sleep: %w", err)
+	}
+	err = s.wait(dur)
+	if err != nil {
+		return "", fmt.Errorf("sleep: %w", err)
+	}
+	return "", nil
+}
+
+// wait pauses the render for d, counting it against the sleep limit of the render
+func (s *renderState) wait(d time.Duration) error {
+	var limit = maxSleepPerExecute
+	if limit <= 0 {
+		return fmt.Errorf("sleeping is disabled")
+	}
+	if d < 0 {
+		return fmt.Errorf("negative duration %s", d)
+	}
+	s.mu.Lock()
+	if s.slept+d > limit {
+		var slept = s.slept
+		s.mu.Unlock()
+		return fmt.Errorf("%s exceeds the limit of %s per execution, %s already slept", d, limit, slept)
+	}
+	s.slept += d
+	s.mu.Unlock()
+
+	var timer = time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// maxRetryAttempts bounds the renders of retry, which the sleep limit doesn't when there is no delay
+const maxRetryAttempts = 100
+
+// retry renders src with data up to attempts times, waiting delay between attempts, until its output is neither
+// empty nor "false", returning that output. Failed renders are retried. Waiting counts against the sleep limit of
+// the render and ends early when its context is done. The error when attempts are exhausted has the last output.
+func (s *renderState) retry(attemptsArg interface{}, delay interface{}, src string, data interface{}) (string, error) {
+	attempts, err := interfaceToWholeInt64(attemptsArg)
+	if err != nil {
+		return "", fmt.Errorf("retry: %w", err)
+	}
+	if attempts < 1 || attempts > maxRetryAttempts {
+		return "", fmt.Errorf("retry: attempts must be between 1 and %d, got %d", maxRetryAttempts, attempts)
+	}
+	wait, err := interfaceToDuration(delay)
+	if err != nil {
+		return "", fmt.Errorf("retry: %w", err)
+	}
+	var res string
+	for attempt := int64(1); ; attempt++ {
+		res, err = interpolateChain(nil, s.overlay, "", data, src)
+		if err == nil {
+			if out := strings.TrimSpace(res); out != "" && out != "false" {
+				return res, nil
+			}
+		}
+		if attempt == attempts {
+			break
+		}
+		if waitErr := s.wait(wait); waitErr != nil {
+			return "", fmt.Errorf("retry: attempt %d of %d: %w", attempt, attempts, waitErr)
+		}
+	}
+	if err != nil {
+		return "", fmt.Errorf("retry: no success after %d attempts, last output %q: %w", attempts, res, err)
+	}
+	return "", fmt.Errorf("retry: no success after %d attempts, last output %q", attempts, res)
+}
@@ -0,0 +1,136 @@
+package template
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSleep(t *testing.T) {
+	defer SetMaxSleepPerExecute(DefaultMaxSleepPerExecute)
+	SetMaxSleepPerExecute(50 * time.Millisecond)
+
+	var start = time.Now()
+	result, err := InterpolateStrict(map[string]interface{}{"ns": int64(10 * time.Millisecond)}, `a{{ sleep "20ms" }}b{{ sleep .ns }}c`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if result != "abc" {
+		t.Errorf(`Unexpected result %q`, result)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf(`Expected to sleep 30ms, took %s`, elapsed)
+	}
+
+	// The limit is per execution, and exceeding it is an error
+	for i := 0; i < 2; i++ {
+		_, err = InterpolateStrict(nil, `{{ sleep "30ms" }}{{ sleep "30ms" }}`)
+		if err == nil || !strings.Contains(err.Error(), "exceeds the limit of 50ms per execution, 30ms already slept") {
+			t.Errorf(`Unexpected error %v`, err)
+		}
+	}
+	_, err = InterpolateStrict(nil, `{{ sleep "2 mins" }}`)
+	if err == nil || !strings.Contains(err.Error(), "2m0s exceeds the limit") {
+		t.Errorf(`Unexpected error %v`, err)
+	}
+	for _, src := range []string{`{{ sleep "-1s" }}`, `{{ sleep "soon" }}`, `{{ sleep true }}`} {
+		_, err = InterpolateStrict(nil, src)
+		if err == nil {
+			t.Errorf(`Expected error for %s`, src)
+		}
+	}
+
+	SetMaxSleepPerExecute(0)
+	_, err = InterpolateStrict(nil, `{{ sleep "1ms" }}`)
+	if err == nil || !strings.Contains(err.Error(), "disabled") {
+		t.Errorf(`Unexpected error %v`, err)
+	}
+}
+
+func TestSleepContext(t *testing.T) {
+	defer SetMaxSleepPerExecute(DefaultMaxSleepPerExecute)
+	SetMaxSleepPerExecute(time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	var start = time.Now()
+	_, err := InterpolateContext(ctx, nil, `{{ sleep "1s" }}`)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf(`Unexpected error %v`, err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf(`Expected the sleep to end with the context, took %s`, elapsed)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	defer SetMaxSleepPerExecute(DefaultMaxSleepPerExecute)
+	SetMaxSleepPerExecute(100 * time.Millisecond)
+	var requests int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&requests, 1) {
+		case 1:
+			fmt.Fprint(w, `{"status":"pending"}`)
+		case 2:
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `not json`)
+		default:
+			fmt.Fprint(w, `{"status":"done","id":"job-1"}`)
+		}
+	}))
+	defer srv.Close()
+
+	const src = `{{ retry .attempts "10ms" "{{ with (http \"GET\" .url (dict)).Body | parseJSON }}{{ if eq .status \"done\" }}{{ .id }}{{ end }}{{ end }}" . }}`
+	result, err := InterpolateStrict(map[string]interface{}{"url": srv.URL, "attempts": json.Number("5")}, src)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if result != "job-1" {
+		t.Errorf(`Unexpected result %q`, result)
+	}
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Errorf(`Expected 3 requests, got %d`, n)
+	}
+
+	atomic.StoreInt32(&requests, 0)
+	_, err = InterpolateStrict(map[string]interface{}{"url": srv.URL, "attempts": 2}, src)
+	if err == nil || !strings.Contains(err.Error(), "no success after 2 attempts") {
+		t.Errorf(`Unexpected error %v`, err)
+	}
+
+	_, err = InterpolateStrict(nil, `{{ retry 3 "10ms" "false" nil }}`)
+	if err == nil || !strings.Contains(err.Error(), `no success after 3 attempts, last output "false"`) {
+		t.Errorf(`Unexpected error %v`, err)
+	}
+
+	// Waiting between attempts counts against the sleep limit
+	_, err = InterpolateStrict(nil, `{{ retry 5 "40ms" "" nil }}`)
+	if err == nil || !strings.Contains(err.Error(), "attempt 3 of 5") || !strings.Contains(err.Error(), "exceeds the limit") {
+		t.Errorf(`Unexpected error %v`, err)
+	}
+
+	for _, src := range []string{`{{ retry 0 "1ms" "x" nil }}`, `{{ retry 1000 "0s" "" nil }}`, `{{ retry 1 "later" "x" nil }}`} {
+		_, err = InterpolateStrict(nil, src)
+		if err == nil {
+			t.Errorf(`Expected error for %s`, src)
+		}
+	}
+}
+
+func TestRetryContext(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := InterpolateContext(ctx, nil, `{{ retry 3 "2s" "" nil }}`)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf(`Unexpected error %v`, err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
 	"math/rand"
 	"net"
 	"net/http"
@@ -20,69 +21,200 @@ import (
 	"strings"
 	"text/template"
 	"time"
+	"unicode"
 
 	gcloud_storage "cloud.google.com/go/storage"
 	"github.com/Masterminds/sprig"
 	"github.com/go-jose/go-jose/v4"
-	"github.com/google/uuid"
 	"github.com/the-control-group/go-currency"
 	"github.com/the-control-group/go-timeutils"
-	"github.com/the-control-group/go-ttlcache"
+	"golang.org/x/text/unicode/norm"
 )
 
 // Config is a convenience struct for importing packages
 type Config struct {
 	// Allows use of the UNSAFE_render method from go-template
 	AllowUnsafeRender bool `json:"allowUnsafeRender"`
-	// Partials to load
+	// Partials to load, as filenames, glob patterns or directories
 	Partials []string `json:"partials"`
+	// File extensions loaded from partial directories, defaults to .tmpl
+	PartialExtensions []string `json:"partialExtensions"`
+	// Partials to load from source, keyed by name
+	PartialsInline map[string]string `json:"partialsInline"`
+	// Makes Interpolate return an empty string rather than the template source on error
+	InterpolateEmptyOnError bool `json:"interpolateEmptyOnError"`
+	// Allows loading a partial to replace an already loaded partial of the same name
+	AllowPartialOverwrite bool `json:"allowPartialOverwrite"`
+	// Backend for cacheSet/cacheGet and related template functions, defaults to an in-process cache
+	CacheBackend Cache `json:"-"`
+	// Requests per second allowed by the http template functions, keyed by host pattern
+	HTTPRateLimits map[string]float64 `json:"httpRateLimits"`
+	// Maximum time authx bearer tokens are cached, zero caches until the refresh margin
+	AuthXCacheTTL timeutils.ApproxBigDuration `json:"authxCacheTTL"`
+	// How long before expiry cached authx bearer tokens are refreshed, defaults to 1 minute
+	AuthXRefreshMargin timeutils.ApproxBigDuration `json:"authxRefreshMargin"`
+	// Fails execution when the output contains "<no value>" or "<nil>"
+	RejectNoValue bool `json:"rejectNoValue"`
+	// Action delimiters used when parsing templates and partials, default to "{{" and "}}"
+	LeftDelim  string `json:"leftDelim"`
+	RightDelim string `json:"rightDelim"`
+	// Maximum bytes of output a template may render, zero is unlimited
+	MaxOutputBytes int64 `json:"maxOutputBytes"`
+	// Maximum bytes of response body the http template functions may read, zero is unlimited
+	MaxHTTPResponseBytes int64 `json:"maxHTTPResponseBytes"`
+	// How deeply UNSAFE_render calls may be nested, defaults to 16
+	UnsafeRenderMaxDepth int `json:"unsafeRenderMaxDepth"`
+	// Notified of template executions and http requests
+	Observer Observer `json:"-"`
+	// Logs requests made by template functions
+	HTTPLogger HTTPLogFunc `json:"-"`
+	// Redacts header values before they are logged, defaults to DefaultHTTPLogRedact
+	HTTPLogRedact func(name, value string) string `json:"-"`
+	// Context values templates may read with ctxValue, keyed by name
+	ContextValueKeys map[string]interface{} `json:"-"`
+	// Maximum size of documents parsed by parseJSON and the json functions, and of the JSON responses of graphql, the lookups and authx, zero is unlimited
+	MaxParseJSONBytes int64 `json:"maxParseJSONBytes"`
+	// Maximum nesting of arrays and objects in the same documents and responses, zero is unlimited
+	MaxParseJSONDepth int `json:"maxParseJSONDepth"`
+	// Adds every sprig function not already provided by this package
+	EnableSprigFull bool `json:"enableSprigFull"`
+	// Adds the named sprig functions not already provided by this package
+	EnableSprig []string `json:"enableSprig"`
+	// Seeds uuid, randomInt and randomFloat64 so renders are reproducible, zero keeps them random
+	DeterministicSeed int64 `json:"deterministicSeed"`
+	// Source of the current time for now and timestamp, defaults to the wall clock
+	Clock func() time.Time `json:"-"`
+	// Limits on the size and complexity of templates parsed with Parse or unmarshaled, zero values are unlimited
+	TemplateLimits Limits `json:"templateLimits"`
+	// Provider for ipLookup, ipLookup fails when unset
+	IPLookupProvider IPLookupProvider `json:"-"`
+	// Endpoint for binLookup with {bin} in place of the BIN, defaults to DefaultBINLookupURL
+	BINLookupURL string `json:"binLookupURL"`
+	// Provider for getSecret, getSecret fails when unset
+	SecretProvider SecretProvider `json:"-"`
+	// Functions whose results are redacted from errors and logs, defaults to DefaultRedactedFuncs
+	RedactedFuncs []string `json:"redactedFuncs"`
+	// Total time sleep may pause an execution, defaults to DefaultMaxSleepPerExecute when nil, zero disables sleep
+	MaxSleepPerExecute *timeutils.ApproxBigDuration `json:"maxSleepPerExecute"`
 }
 
 // Configure calls each of the configuration functions based on the config provided
 func Configure(cfg Config) (err error) {
 	AllowUnsafeRender(cfg.AllowUnsafeRender)
+	if cfg.UnsafeRenderMaxDepth > 0 {
+		SetUnsafeRenderMaxDepth(cfg.UnsafeRenderMaxDepth)
+	}
+	InterpolateEmptyOnError(cfg.InterpolateEmptyOnError)
+	RejectNoValue(cfg.RejectNoValue)
+	SetDelims(cfg.LeftDelim, cfg.RightDelim)
+	SetMaxOutputBytes(cfg.MaxOutputBytes)
+	SetMaxHTTPResponseBytes(cfg.MaxHTTPResponseBytes)
+	SetParseJSONLimits(cfg.MaxParseJSONBytes, cfg.MaxParseJSONDepth)
+	SetTemplateLimits(cfg.TemplateLimits)
+	SetIPLookupProvider(cfg.IPLookupProvider)
+	SetBINLookupURL(cfg.BINLookupURL)
+	SetSecretProvider(cfg.SecretProvider)
+	SetRedactedFuncs(cfg.RedactedFuncs...)
+	if cfg.MaxSleepPerExecute != nil {
+		SetMaxSleepPerExecute(cfg.MaxSleepPerExecute.ToDuration())
+	} else {
+		SetMaxSleepPerExecute(DefaultMaxSleepPerExecute)
+	}
+	if cfg.EnableSprigFull {
+		err = EnableSprig()
+	} else if len(cfg.EnableSprig) > 0 {
+		err = EnableSprig(cfg.EnableSprig...)
+	}
+	if err != nil {
+		return
+	}
+	err = SetHTTPRateLimits(cfg.HTTPRateLimits)
+	if err != nil {
+		return
+	}
+	SetObserver(cfg.Observer)
+	SetClock(cfg.Clock)
+	if cfg.DeterministicSeed != 0 {
+		SetRandSource(rand.NewSource(cfg.DeterministicSeed))
+	} else {
+		SetRandSource(nil)
+	}
+	SetContextValueKeys(cfg.ContextValueKeys)
+	SetHTTPLogger(cfg.HTTPLogger, cfg.HTTPLogRedact)
+	if cfg.CacheBackend != nil {
+		SetCacheBackend(cfg.CacheBackend)
+	}
+	SetAuthXCacheTTL(cfg.AuthXCacheTTL.ToDuration())
+	if cfg.AuthXRefreshMargin != 0 {
+		SetAuthXRefreshMargin(cfg.AuthXRefreshMargin.ToDuration())
+	}
+	if len(cfg.PartialExtensions) > 0 {
+		SetPartialExtensions(cfg.PartialExtensions...)
+	}
+	AllowPartialOverwrite(cfg.AllowPartialOverwrite)
 	if cfg.Partials != nil && len(cfg.Partials) > 0 {
 		err = LoadPartialFiles(cfg.Partials...)
+		if err != nil {
+			return
+		}
 	}
+	err = loadPartialsInline(cfg.PartialsInline)
 	return
 }
 
-var templateCache *ttlcache.TTLCache
-var authxTokenCache *ttlcache.TTLCache
+var defaultTemplateCache Cache
+var authxTokenCache *ttlCache
 var sprigFuncs = sprig.FuncMap()
 
 func init() {
 	// Create template cache
-	templateCache = ttlcache.NewTTLCache(15 * time.Minute)
-	authxTokenCache = ttlcache.NewTTLCache(5 * time.Minute)
+	defaultTemplateCache = newTTLCache(15 * time.Minute)
+	templateCache = defaultTemplateCache
+	authxTokenCache = newTTLCache(5 * time.Minute)
+
+	// Functions that render sub-templates refer back to RootTemplate, so they are added once it exists
+	TemplateFuncs["cacheGetOrSet"] = cacheGetOrSet
+	TemplateFuncs["UNSAFE_render"] = unsafeRender
+	TemplateFuncs["include"] = include
+	TemplateFuncs["tryRender"] = tryRender
+	TemplateFuncs["ctxValue"] = bindContextFunc(context.Background(), ctxValue)
+	for name := range (&renderState{}).funcs() {
+		TemplateFuncs[name] = unscopedRenderFunc(name)
+	}
+	RootTemplate.Funcs(TemplateFuncs)
 }
 
 // TemplateFuncs ...
 // DEPRECATED will become private variable in a future release
-// Add functions to the RootTemplate instead
+// Add functions with RegisterFunc instead
 var TemplateFuncs = map[string]interface{}{
-	"randomFloat64": func() float64 {
-		return rand.Float64()
-	},
+	"randomFloat64": randomFloat64,
 	"randomInt": func(min int, max int) int {
-		return rand.Intn(max-min+1) + min
+		return randomIntn(max-min+1) + min
 	},
 	"uuid": func() (string, error) {
-		id, err := uuid.NewRandom()
+		id, err := newUUID()
 		if err != nil {
 			return "", err
 		}
 		return id.String(), nil
 	},
-	"toJSON": func(v interface{}) string {
-		a, _ := json.Marshal(v)
-		return string(a)
+	"toJSON": func(v interface{}) (string, error) {
+		v, err := normalizeJSONValue(v)
+		if err != nil {
+			return "", err
+		}
+		a, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+		return string(a), nil
 	},
 	"now": func(layout string) string {
-		return time.Now().Format(layout)
+		return clock().Format(layout)
 	},
 	"timestamp": func() int64 {
-		return time.Now().Unix()
+		return clock().Unix()
 	},
 	"env": func(key string) string {
 		return os.Getenv(key)
@@ -180,98 +312,218 @@ var TemplateFuncs = map[string]interface{}{
 		}
 		return xFloat >= yFloat, nil
 	},
-	"normalize_email": func(email string) string {
-		// get everything before the first instance of '+'
-		email = strings.Split(email, "+")[0]
-		// get everything before the first instance of '@'
-		email = strings.Split(email, "@")[0]
-		email = strings.ReplaceAll(email, ".", "")
-		re := regexp.MustCompile("[0-9]")
-		email = re.ReplaceAllString(email, "")
-		email = strings.TrimSpace(email)
-		email = strings.ToLower(email)
-		return email
-	},
+	"normalize_email":     normalizeEmail,
+	"emailValid":          emailValid,
+	"emailParts":          emailParts,
+	"phoneValid":          phoneValid,
+	"phoneCountry":        phoneCountry,
+	"countryLookup":       countryLookup,
+	"countryAlpha2":       countryAlpha2,
+	"countryName":         countryName,
+	"regionCode":          regionCode,
+	"regionName":          regionName,
+	"parseAcceptLanguage": parseAcceptLanguage,
+	"matchLanguage":       matchLanguage,
+	"stripTags":           stripTags,
+	"htmlToText":          htmlToText,
+	"htmlEscape":          htmlEscape,
+	"htmlUnescape":        htmlUnescape,
+	"attrEscape":          attrEscape,
+	"markdownToHTML":      markdownToHTML,
+	"markdownStrip":       markdownStrip,
+	"xmlEscape":           xmlEscape,
+	"cdata":               cdata,
+	"csvField":            csvField,
+	"csvLine":             csvLine,
+	"tsvLine":             tsvLine,
+	"fixedWidthLine":      fixedWidthLine,
+	"b32enc":              b32enc,
+	"b32dec":              b32dec,
+	"base58enc":           base58enc,
+	"base58dec":           base58dec,
+	"bitAnd":              bitAnd,
+	"bitOr":               bitOr,
+	"bitXor":              bitXor,
+	"bitShiftLeft":        bitShiftLeft,
+	"bitShiftRight":       bitShiftRight,
+	"hasBit":              hasBit,
+	"toOrdinal":           toOrdinal,
+	"spellNumber":         spellNumber,
+	"plural":              plural,
+	"pluralWithCount":     pluralWithCount,
+	"pluralize":           pluralize,
+	"singularize":         singularize,
+	"initials":            initials,
+	"nameParts":           nameParts,
+	"levenshtein":         levenshtein,
+	"similarity":          similarity,
+	"jaroWinkler":         jaroWinkler,
+	"soundex":             soundex,
+	"metaphone":           metaphone,
+	"tzOffset":            tzOffset,
+	"isDST":               isDST,
+	"cronNext":            cronNext,
+	"cronMatches":         cronMatches,
+	"semverParse":         semverParse,
+	"semverCompare":       semverCompare,
+	"semverSatisfies":     semverSatisfies,
+	"matchGlob":           matchGlob,
+	"matchAnyGlob":        matchAnyGlob,
+	"ipLookup":            ipLookupFunc(doHTTP, true),
+	"binLookup":           binLookupFunc(doHTTP, true),
+	"fail":                fail,
+	"assert":              assert,
+	"currencyInfo":        currencyInfo,
+	"toMinorUnits":        toMinorUnits,
+	"fromMinorUnits":      fromMinorUnits,
 	"toLower": func(str string) string {
 		return strings.ToLower(str)
 	},
 	"fingerprint": func(vars ...string) (fingerprint string) {
-		fingerprint = strings.Join(vars, "_")
-		re := regexp.MustCompile(`[^\p{L}0-9]`)
-		fingerprint = re.ReplaceAllString(fingerprint, "_")
-		fingerprint = strings.ToLower(fingerprint)
-		return
+		return fingerprintSep("_", vars...)
+	},
+	// fingerprintSep is fingerprint joining and replacing non letter or digit characters with sep instead of an underscore
+	"fingerprintSep": fingerprintSep,
+	// fingerprintTranslit is fingerprint with accented latin letters transliterated first, so "Müller Straße" matches "Muller Strasse"
+	"fingerprintTranslit": func(vars ...string) string {
+		var translit = make([]string, len(vars))
+		for i, v := range vars {
+			translit[i] = transliterate(v)
+		}
+		return fingerprintSep("_", translit...)
 	},
+	"transliterate":    transliterate,
+	"normalizeAddress": normalizeAddress,
 	"fingerprint_address": func(address, city, state, zip, plus4Code interface{}) string {
-		var addressStr, cityStr, stateStr, zipStr, plus4CodeStr string
-		addressStr, _ = address.(string)
-		cityStr, _ = city.(string)
-		stateStr, _ = state.(string)
-		zipStr, _ = zip.(string)
-		plus4CodeStr, _ = plus4Code.(string)
-		fingerprint := strings.Join([]string{addressStr, cityStr, stateStr, zipStr, plus4CodeStr}, "_")
-		re := regexp.MustCompile(`[^\p{L}0-9]`)
-		fingerprint = re.ReplaceAllString(fingerprint, "_")
-		fingerprint = strings.ToLower(fingerprint)
-		return fingerprint
-	},
-	"dict": func(keysAndValues ...interface{}) map[interface{}]interface{} {
+		return fingerprintSep("_", addressPart(address), addressPart(city), addressPart(state), addressPart(zip), addressPart(plus4Code))
+	},
+	// fingerprint_address_map is fingerprint_address taking the parts from an address map with conventional keys
+	"fingerprint_address_map": func(addr interface{}) (string, error) {
+		var parts = make([]string, len(addressKeys))
+		for i, keys := range addressKeys {
+			for _, key := range keys {
+				v, err := mapLookup(addr, key)
+				if err != nil {
+					return "", fmt.Errorf("fingerprint_address_map: %w", err)
+				}
+				if v != nil {
+					parts[i] = addressPart(v)
+					break
+				}
+			}
+		}
+		return fingerprintSep("_", parts...), nil
+	},
+	"dict": func(keysAndValues ...interface{}) (map[interface{}]interface{}, error) {
+		if len(keysAndValues)%2 != 0 {
+			return nil, fmt.Errorf("dict requires an even number of arguments, got %d", len(keysAndValues))
+		}
 		var dict = map[interface{}]interface{}{}
 		for i, s := range keysAndValues {
 			if i%2 != 0 {
 				dict[keysAndValues[i-1]] = s
 			}
 		}
-		return dict
+		return dict, nil
 	},
-	"http": func(method, url string, headers map[interface{}]interface{}) (*http.Response, error) {
-		var req *http.Request
-		var err error
-		req, err = http.NewRequest(method, url, nil)
-		if err != nil {
-			return nil, err
+	// dictStr is like dict but builds a string keyed map, as used by parsed JSON, so it can be passed to funcs expecting one
+	"dictStr": func(keysAndValues ...interface{}) (map[string]interface{}, error) {
+		if len(keysAndValues)%2 != 0 {
+			return nil, fmt.Errorf("dictStr requires an even number of arguments, got %d", len(keysAndValues))
+		}
+		var dict = make(map[string]interface{}, len(keysAndValues)/2)
+		for i := 0; i < len(keysAndValues); i += 2 {
+			switch k := keysAndValues[i].(type) {
+			case string:
+				dict[k] = keysAndValues[i+1]
+			case fmt.Stringer:
+				dict[k.String()] = keysAndValues[i+1]
+			default:
+				return nil, fmt.Errorf("dictStr key must be a string, got %T", keysAndValues[i])
+			}
 		}
-		for k, v := range headers {
-			req.Header.Set(k.(string), v.(string))
+		return dict, nil
+	},
+	// dictFrom copies a string or interface keyed map into a new dict and sets the additional keys and values on the copy
+	"dictFrom": func(m interface{}, keysAndValues ...interface{}) (map[interface{}]interface{}, error) {
+		if len(keysAndValues)%2 != 0 {
+			return nil, fmt.Errorf("dictFrom requires an even number of keys and values, got %d", len(keysAndValues))
+		}
+		var dict = map[interface{}]interface{}{}
+		switch v := m.(type) {
+		case nil:
+		case map[string]interface{}:
+			for k, value := range v {
+				dict[k] = value
+			}
+		case map[interface{}]interface{}:
+			for k, value := range v {
+				dict[k] = value
+			}
+		case map[string]string:
+			for k, value := range v {
+				dict[k] = value
+			}
+		default:
+			return nil, fmt.Errorf("dictFrom requires a map, got %T", m)
 		}
-		return http.DefaultClient.Do(req)
+		for i := 0; i < len(keysAndValues); i += 2 {
+			dict[keysAndValues[i]] = keysAndValues[i+1]
+		}
+		return dict, nil
 	},
-	"http_data": func(method, url string, headers map[interface{}]interface{}, data string) (*http.Response, error) {
-		var req *http.Request
-		var err error
-		req, err = http.NewRequest(method, url, nil)
-		if err != nil {
-			return nil, err
+	"list": func(items ...interface{}) []interface{} {
+		return append([]interface{}{}, items...)
+	},
+	"http":      httpFunc(doHTTP),
+	"http_data": httpDataFunc(doHTTP),
+	"graphql":   graphql,
+	"basicAuth": func(user, pass string) string {
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+pass))
+	},
+	"bearerAuth": func(token string) string {
+		if strings.HasPrefix(token, "Bearer ") {
+			return token
 		}
-		req.Body = io.NopCloser(bytes.NewBufferString(data))
-
+		return "Bearer " + token
+	},
+	// authHeaders copies headers and sets the Authorization header, leaving the original dict untouched
+	"authHeaders": func(headers map[interface{}]interface{}, authorization string) map[interface{}]interface{} {
+		var merged = make(map[interface{}]interface{}, len(headers)+1)
 		for k, v := range headers {
-			req.Header.Set(k.(string), v.(string))
+			merged[k] = v
 		}
-
-		return http.DefaultClient.Do(req)
+		merged["Authorization"] = authorization
+		return merged
 	},
 	"parseJSON": func(data interface{}) (interface{}, error) {
 		var v interface{}
 		var err error
 		switch d := data.(type) {
 		case []byte:
-			err = json.Unmarshal(d, &v)
+			err = unmarshalJSONLimited(d, &v)
 			return v, err
 		case string:
-			err = json.Unmarshal([]byte(d), &v)
+			err = unmarshalJSONLimited([]byte(d), &v)
 			return v, err
 		case bytes.Buffer:
-			err = json.Unmarshal(d.Bytes(), &v)
+			err = unmarshalJSONLimited(d.Bytes(), &v)
 			return v, err
 		case io.Reader:
-			var buf bytes.Buffer
-			buf.ReadFrom(d)
-			err = json.Unmarshal(buf.Bytes(), &v)
+			var b []byte
+			b, err = readJSONLimited(d)
+			if err != nil {
+				return nil, err
+			}
+			err = unmarshalJSONLimited(b, &v)
 			return v, err
 		}
 		return nil, fmt.Errorf("TypeAssertionError")
 	},
+	"toJSONStable":    toJSONStable,
+	"toJSONOmitEmpty": toJSONOmitEmpty,
+	"jsonMerge":       jsonMerge,
+	"jsonPatch":       jsonPatch,
 	"formatTime": func(srcLayout, targetLayout, input string) (string, error) {
 		t, err := time.Parse(srcLayout, input)
 		if err != nil {
@@ -398,6 +650,15 @@ var TemplateFuncs = map[string]interface{}{
 		}
 		return nil
 	},
+	// firstNonEmpty returns the first value that isn't nil, an empty string, a zero number or an empty slice or map
+	"firstNonEmpty": func(values ...interface{}) interface{} {
+		for _, v := range values {
+			if !isEmptyValue(v) {
+				return v
+			}
+		}
+		return nil
+	},
 	"sortMap": func(list []interface{}, sortKey string, dir string) ([]interface{}, error) {
 		if dir != "asc" && dir != "desc" {
 			return nil, fmt.Errorf("invalid sort direction for sortMap")
@@ -442,116 +703,79 @@ var TemplateFuncs = map[string]interface{}{
 		}
 		return list, nil
 	},
-	"add": func(a, b int) int {
-		return a + b
-	},
-	"addInt64": func(a, b int64) int64 {
-		return a + b
-	},
-	"unquote": func(s string) string {
-		if len(s) > 0 && s[0] == '"' {
-			s = s[1:]
-		}
-		if len(s) > 0 && s[len(s)-1] == '"' {
-			s = s[:len(s)-1]
-		}
-		return s
-	},
-	"getAuthXBearerToken": func(authxURL, authxToken, authorizationId string) (string, error) {
-		var cacheKey = strings.Join([]string{authxURL, authxToken, authorizationId}, "::")
-		cachedToken, _ := authxTokenCache.Get(cacheKey)
-		if cachedTokenString, ok := cachedToken.(string); ok {
-			return cachedTokenString, nil
-		}
-		var err error
-		var graphqlQuery = fmt.Sprintf(`query {
-			authorization(id: %q) {
-				token(format:BEARER)
-			}
-		}`, authorizationId)
-		var requestQuery = map[string]interface{}{
-			"query": graphqlQuery,
-		}
-		var requestBody []byte
-		requestBody, err = json.Marshal(requestQuery)
+	"add": func(a, b interface{}) (int, error) {
+		sum, err := addInt64(a, b)
 		if err != nil {
-			return "", err
+			return 0, fmt.Errorf("add: %w", err)
 		}
-		var req *http.Request
-		req, err = http.NewRequest("POST", authxURL, bytes.NewBuffer(requestBody))
-		if err != nil {
-			return "", err
+		if int64(int(sum)) != sum {
+			return 0, fmt.Errorf("add: %d overflows int", sum)
 		}
-		req.Header.Set("Authorization", authxToken)
-		req.Header.Set("Content-Type", "application/json")
-		var res *http.Response
-		res, err = http.DefaultClient.Do(req)
-		if err != nil {
-			return "", err
-		}
-		var body []byte
-		body, err = io.ReadAll(res.Body)
+		return int(sum), nil
+	},
+	"addInt64": func(a, b interface{}) (int64, error) {
+		sum, err := addInt64(a, b)
 		if err != nil {
-			return "", err
-		}
-		defer res.Body.Close()
-		var tokenResponse struct {
-			Errors []struct {
-				Message string
-			}
-			Data struct {
-				Authorization struct {
-					Token string
-				}
-			}
+			return 0, fmt.Errorf("addInt64: %w", err)
 		}
-		err = json.Unmarshal(body, &tokenResponse)
+		return sum, nil
+	},
+	"addFloat": func(a, b interface{}) (float64, error) {
+		x, err := interfaceToFloat64(a)
 		if err != nil {
-			return "", err
-		}
-		if tokenResponse.Errors != nil && len(tokenResponse.Errors) > 0 {
-			return "", fmt.Errorf("authx error: %s", tokenResponse.Errors[0].Message)
+			return 0, fmt.Errorf("addFloat: %w", err)
 		}
-		var authxBearerToken = tokenResponse.Data.Authorization.Token
-		tokenParts := strings.Split(strings.Split(authxBearerToken, " ")[1], ".")
-		jwtBase64 := tokenParts[1]
-		var jwtBytes []byte
-		jwtBytes, err = base64.RawURLEncoding.DecodeString(jwtBase64)
+		y, err := interfaceToFloat64(b)
 		if err != nil {
-			return "", err
+			return 0, fmt.Errorf("addFloat: %w", err)
 		}
-		var jwt struct {
-			AID    string
-			Scopes []string
-			IAT    int64
-			EXP    int64
-			ISS    string
-			SUB    string
-			JTI    string
+		return x + y, nil
+	},
+	// unquote strips a leading and trailing double quote, leaving escapes in place, see unquoteJSON
+	"unquote": func(s string) string {
+		if len(s) > 0 && s[0] == '"' {
+			s = s[1:]
 		}
-		err = json.Unmarshal(jwtBytes, &jwt)
-		if err != nil {
-			return "", err
+		if len(s) > 0 && s[len(s)-1] == '"' {
+			s = s[:len(s)-1]
 		}
-		var expireAt = time.Duration(jwt.EXP-time.Now().Unix())*time.Second - time.Minute
-		authxTokenCache.SetEx(cacheKey, authxBearerToken, expireAt)
-		return authxBearerToken, nil
+		return s
 	},
-	"cacheSet": func(key string, value interface{}, expire interface{}) (interface{}, error) {
-		exp, err := timeutils.InterfaceToApproxBigDuration(expire)
+	// unquoteJSON decodes a JSON quoted string, processing escapes such as \" \n and \u00e9
+	// Input that isn't quoted is returned unchanged, use unquote to only strip the quotes
+	"unquoteJSON": func(s string) (string, error) {
+		if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+			return s, nil
+		}
+		var unquoted string
+		err := json.Unmarshal([]byte(s), &unquoted)
 		if err != nil {
-			return value, err
+			return "", fmt.Errorf("unquoteJSON: %w", err)
 		}
-		return value, templateCache.SetEx(key, value, time.Duration(exp))
+		return unquoted, nil
 	},
-	"cacheGet": func(key string) interface{} {
-		v, _ := templateCache.Get(key)
-		return v
+	// escapeJSON escapes s for use between the quotes of a JSON string in the template
+	"escapeJSON": func(s string) string {
+		var quoted = escapeJSONQuoted(s)
+		return quoted[1 : len(quoted)-1]
 	},
+	// escapeJSONQuoted returns s as a quoted JSON string
+	"escapeJSONQuoted":         escapeJSONQuoted,
+	"getAuthXBearerToken":      authxBearerTokenFunc(doHTTP),
+	"getAuthXBearerTokenFresh": authxBearerTokenFreshFunc(doHTTP),
+	"cacheSet":                 cacheSet,
+	"cacheGet":                 cacheGet,
+	"cacheIncr":                cacheIncr,
+	"cacheDecr":                cacheDecr,
+	"cacheHas":                 cacheHas,
+	"cacheDelete":              cacheDelete,
 	"parseCIDR": func(cidr string) (*net.IPNet, error) {
 		_, ipnet, err := net.ParseCIDR(cidr)
 		return ipnet, err
 	},
+	"signPayload":            signPayload,
+	"signPayloadAt":          signPayloadAt,
+	"verifyPayloadSignature": verifyPayloadSignature,
 	"toApproxBigDuration": func(i interface{}) (timeutils.ApproxBigDuration, error) {
 		return timeutils.InterfaceToApproxBigDuration(i)
 	},
@@ -572,28 +796,20 @@ var TemplateFuncs = map[string]interface{}{
 	"regexReplaceAll": sprigFuncs["regexReplaceAll"],
 	"parseTime":       timeutils.ParseAny,
 	"maybeParseTime":  timeutils.ParseAnyMaybe,
-	"formatAnyTime": func(targetLayout, input string) (string, error) {
-		t, err := timeutils.ParseAny(input)
+	"formatAnyTime": func(targetLayout string, input interface{}) (string, error) {
+		t, err := interfaceToTime(input)
 		if err != nil {
 			return "", err
 		}
 		return t.Format(targetLayout), nil
 	},
-	"maybeFormatAnyTime": func(targetLayout string, input interface{}) *string {
-		switch v := input.(type) {
-		case string:
-			if v == "" {
-				return nil
-			}
-			t := timeutils.ParseAnyMaybe(v)
-			if t == nil {
-				return nil
-			}
-			s := t.Format(targetLayout)
-			return &s
-		default:
-			return nil
+	// maybeFormatAnyTime is formatAnyTime returning an empty string for empty or unparseable input
+	"maybeFormatAnyTime": func(targetLayout string, input interface{}) string {
+		t, err := interfaceToTime(input)
+		if err != nil {
+			return ""
 		}
+		return t.Format(targetLayout)
 	},
 	"left": func(str string, n int) string {
 		if len(str) <= n {
@@ -613,7 +829,10 @@ var TemplateFuncs = map[string]interface{}{
 		if err != nil {
 			return nil, err
 		}
-		json.Unmarshal([]byte(str), &amt)
+		err = json.Unmarshal([]byte(str), &amt)
+		if err != nil {
+			return nil, fmt.Errorf("toAmount: %w", err)
+		}
 		return
 	},
 	"onlyDigits": func(input string) string {
@@ -622,6 +841,24 @@ var TemplateFuncs = map[string]interface{}{
 	"onlyAlpha": func(input string) string {
 		return reNonAlpha.ReplaceAllString(input, "")
 	},
+	"onlyAlphaUnicode": func(input string) string {
+		return reNonAlphaUnicode.ReplaceAllString(input, "")
+	},
+	"onlyAlnum": func(input string) string {
+		return reNonAlnum.ReplaceAllString(input, "")
+	},
+	"onlyAlnumUnicode": func(input string) string {
+		return reNonAlnumUnicode.ReplaceAllString(input, "")
+	},
+	// keepChars removes every character of input that isn't in allowed
+	"keepChars": func(allowed, input string) string {
+		return strings.Map(func(r rune) rune {
+			if strings.ContainsRune(allowed, r) {
+				return r
+			}
+			return -1
+		}, input)
+	},
 	"joseSign": func(payload string, key string, alg jose.SignatureAlgorithm) (string, error) {
 		var jwk jose.JSONWebKey
 		err := jwk.UnmarshalJSON([]byte(key))
@@ -750,20 +987,77 @@ var TemplateFuncs = map[string]interface{}{
 			return nil
 		}
 	},
-	"UNSAFE_render": disabledUnsafeRender,
 }
 
-func disabledUnsafeRender(filename string, data interface{}) (string, error) {
-	return ``, errors.New("UNSAFE_render method is disabled")
-}
+var unsafeRenderAllowed bool
 
+// unsafeRender checks AllowUnsafeRender each time it is called, so the setting applies to every template
+// regardless of whether it was parsed before or after the setting changed
 func unsafeRender(filename string, data interface{}) (string, error) {
+	return unsafeRenderChain(nil, newRenderOverlay(nil), filename, data)
+}
+
+var unsafeRenderMaxDepth = 16
+
+// SetUnsafeRenderMaxDepth sets how deeply UNSAFE_render and include calls may be nested, guarding against templates that render each other
+func SetUnsafeRenderMaxDepth(depth int) {
+	unsafeRenderMaxDepth = depth
+}
+
+// extendRenderChain appends name to the chain of files and partials being rendered, erroring when the depth limit is exceeded
+func extendRenderChain(fn string, chain []string, name string) ([]string, error) {
+	chain = append(chain[:len(chain):len(chain)], name)
+	if len(chain) > unsafeRenderMaxDepth {
+		return nil, fmt.Errorf("%s depth limit of %d exceeded: %s", fn, unsafeRenderMaxDepth, strings.Join(chain, " -> "))
+	}
+	return chain, nil
+}
+
+// renderChainFuncs are the funcs rendering files, partials and templates, bound to the chain rendering them
+// overlay funcs, such as those of a dry run and the render scoped funcs, replace the RootTemplate funcs in every nested render
+func renderChainFuncs(chain []string, overlay map[string]interface{}) map[string]interface{} {
+	var funcs = map[string]interface{}{}
+	for name, fn := range overlay {
+		if name != renderStateKey {
+			funcs[name] = fn
+		}
+	}
+	funcs["UNSAFE_render"] = func(filename string, data interface{}) (string, error) {
+		return unsafeRenderChain(chain, overlay, filename, data)
+	}
+	funcs["include"] = func(name string, data interface{}) (string, error) {
+		return includeChain(chain, overlay, name, data)
+	}
+	funcs["tryRender"] = func(src string, data interface{}, fallback interface{}) interface{} {
+		return tryRenderChain(chain, overlay, src, data, fallback)
+	}
+	// A namespaced cacheGetOrSet in the overlay renders in its namespace
+	if _, ok := overlay["cacheGetOrSet"]; !ok {
+		funcs["cacheGetOrSet"] = func(key string, expire interface{}, src string, data interface{}) (interface{}, error) {
+			return cacheGetOrSetChain(chain, overlay, "", key, expire, src, data)
+		}
+	}
+	return funcs
+}
+
+// unsafeRenderChain renders filename with the chain of files rendering it, nested calls extend the chain
+func unsafeRenderChain(chain []string, overlay map[string]interface{}, filename string, data interface{}) (string, error) {
+	if !unsafeRenderAllowed {
+		return ``, errors.New("UNSAFE_render method is disabled")
+	}
+	chain, err := extendRenderChain("UNSAFE_render", chain, filename)
+	if err != nil {
+		return ``, err
+	}
+
 	tmpl, err := RootTemplate.Clone()
 
 	if err != nil {
 		return ``, err
 	}
 
+	tmpl.Funcs(renderChainFuncs(chain, overlay))
+
 	_, err = tmpl.ParseFiles(filename)
 
 	if err != nil {
@@ -780,31 +1074,101 @@ func unsafeRender(filename string, data interface{}) (string, error) {
 	return tBuf.String(), nil
 }
 
+// tryRender interpolates src with data, returning fallback instead if parsing or executing it fails for any reason
+// It expresses best-effort steps such as optional lookups without failing the whole render
+func tryRender(src string, data interface{}, fallback interface{}) interface{} {
+	return tryRenderChain(nil, newRenderOverlay(nil), src, data, fallback)
+}
+
+// tryRenderChain is tryRender nested in the chain of files and partials rendering it
+func tryRenderChain(chain []string, overlay map[string]interface{}, src string, data interface{}, fallback interface{}) interface{} {
+	res, err := interpolateChain(chain, overlay, "", data, src)
+	if err != nil {
+		return fallback
+	}
+	return res
+}
+
 // RootTemplate can be loaded with partials to be used in other templates
 // It will be cloned
 var RootTemplate = template.New("root").Funcs(TemplateFuncs)
 
-// AllowUnsafeRender adds `USAFE_render` to the RootTemplate funcs
+// AllowUnsafeRender enables the `UNSAFE_render` template func
 // Is is potentially unsafe because it exposes the ability for a template to read any file into a template.
+// The setting is checked when UNSAFE_render is called, so it applies immediately to all templates,
+// including those parsed before it changed.
 func AllowUnsafeRender(allow bool) {
-	if allow {
-		TemplateFuncs["UNSAFE_render"] = unsafeRender
-	} else {
-		TemplateFuncs["UNSAFE_render"] = disabledUnsafeRender
+	unsafeRenderAllowed = allow
+}
+
+var leftDelim, rightDelim string
+
+// SetDelims sets the action delimiters used to parse templates and partials loaded afterwards
+// An empty delimiter means the default, "{{" or "}}"
+func SetDelims(left, right string) {
+	leftDelim, rightDelim = left, right
+	RootTemplate.Delims(left, right)
+}
+
+var reNonLetterDigit = regexp.MustCompile(`[^\p{L}0-9]`)
+
+func fingerprintSep(sep string, vars ...string) string {
+	var fingerprint = strings.Join(vars, sep)
+	fingerprint = reNonLetterDigit.ReplaceAllLiteralString(fingerprint, sep)
+	return strings.ToLower(fingerprint)
+}
+
+// mapLookup returns the value for key in a string or interface keyed map, nil when absent
+func mapLookup(m interface{}, key string) (interface{}, error) {
+	switch v := m.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return v[key], nil
+	case map[interface{}]interface{}:
+		return v[key], nil
+	case map[string]string:
+		if s, ok := v[key]; ok {
+			return s, nil
+		}
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("expected a map, got %T", m)
 	}
-	RootTemplate.Funcs(TemplateFuncs)
 }
 
-// LoadPartialFiles parses the given filenames and adds them to the RootTemplate
-func LoadPartialFiles(filenames ...string) (err error) {
-	_, err = RootTemplate.ParseFiles(filenames...)
-	return
+// transliterations are the letters that don't decompose into a latin letter and combining marks
+var transliterations = strings.NewReplacer(
+	"ß", "ss", "ẞ", "SS", "æ", "ae", "Æ", "AE", "œ", "oe", "Œ", "OE", "ø", "o", "Ø", "O",
+	"đ", "d", "Đ", "D", "ð", "d", "Ð", "D", "ł", "l", "Ł", "L", "þ", "th", "Þ", "TH", "ı", "i",
+)
+
+// transliterate folds accented latin letters to ASCII by decomposing them (NFKD) and dropping combining marks
+// Letters from other scripts are left unchanged
+func transliterate(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFKD.String(transliterations.Replace(s)) {
+		if !unicode.Is(unicode.Mn, r) {
+			b.WriteRune(r)
+		}
+	}
+	return norm.NFC.String(b.String())
 }
 
-// LoadPartial parses the given template strings and adds it to the RootTemplate
-func LoadPartial(name, template string) (err error) {
-	_, err = RootTemplate.New(name).Parse(template)
-	return
+// setHeaders sets each of the headers from a dict on req, failing on keys or values that aren't strings
+func setHeaders(req *http.Request, headers map[interface{}]interface{}) error {
+	for k, v := range headers {
+		key, ok := k.(string)
+		if !ok {
+			return fmt.Errorf("header name must be a string, got %T", k)
+		}
+		value, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("header %s value must be a string, got %T", key, v)
+		}
+		req.Header.Set(key, value)
+	}
+	return nil
 }
 
 // var reDigit = regexp.MustCompile(`[0-9]`)
@@ -812,6 +1176,9 @@ var reNonDigit = regexp.MustCompile(`[^0-9]`)
 
 // var reAlpha = regexp.MustCompile(`[a-zA-Z]`)
 var reNonAlpha = regexp.MustCompile(`[^a-zA-Z]`)
+var reNonAlphaUnicode = regexp.MustCompile(`[^\p{L}]`)
+var reNonAlnum = regexp.MustCompile(`[^a-zA-Z0-9]`)
+var reNonAlnumUnicode = regexp.MustCompile(`[^\p{L}\p{N}]`)
 
 func interfaceSlice(slice interface{}) []interface{} {
 	s := reflect.ValueOf(slice)
@@ -834,35 +1201,137 @@ func interfaceSlice(slice interface{}) []interface{} {
 }
 
 // Interpolate simplifies interpolating a template string with data
+// On error the original text is returned unless InterpolateEmptyOnError is enabled, see InterpolateStrict
 func Interpolate(data interface{}, text string) (string, error) {
-	tmpl, err := RootTemplate.Clone()
-
-	if err != nil {
+	res, err := InterpolateStrict(data, text)
+	if err != nil && !interpolateEmptyOnError {
 		return text, err
 	}
+	return res, err
+}
 
-	_, err = tmpl.Parse(text)
+// InterpolateStrict interpolates a template string with data, returning an empty string on error
+// Unlike Interpolate it never returns the template source, so mishandled errors can't leak it into output
+// Parse and execution errors are wrapped in an ExecError
+func InterpolateStrict(data interface{}, text string) (string, error) {
+	return interpolateKey("", data, text)
+}
+
+// interpolateKey interpolates text as a new render, wrapping errors in an ExecError for the key path
+// Panics are recovered and returned as a PanicError
+func interpolateKey(key string, data interface{}, text string) (string, error) {
+	return interpolateChain(nil, newRenderOverlay(nil), key, data, text)
+}
+
+// interpolateChain is interpolateKey nested in the chain of files and partials rendering it, with overlay funcs
+func interpolateChain(chain []string, overlay map[string]interface{}, key string, data interface{}, text string) (string, error) {
+	return (&interpolator{chain: chain, overlay: overlay}).interpolate(key, data, text)
+}
+
+// interpolator interpolates texts in one render, parsing each as a uniquely named template associated with a single clone of the RootTemplate
+// Cloning is most of the cost of interpolating a short text, so this is much cheaper than a clone per text.
+type interpolator struct {
+	chain   []string
+	overlay map[string]interface{}
+	tmpl    *template.Template
+}
+
+func (ip *interpolator) interpolate(key string, data interface{}, text string) (res string, err error) {
+	if observer != nil {
+		defer func(start time.Time) {
+			observeExecute(key, text, start, err)
+		}(time.Now())
+	}
+	defer recoverPanic(&err)
+
+	// Each text is named after its key, which is unique within a render, so errors name the same template every time
+	var name = RootTemplate.Name()
+	if key != "" {
+		name += "[" + key + "]"
+	}
+	if ip.tmpl != nil && ip.tmpl.Lookup(name) != nil {
+		ip.tmpl = nil
+	}
+	if ip.tmpl == nil {
+		ip.tmpl, err = RootTemplate.Clone()
+
+		if err != nil {
+			return "", err
+		}
+
+		ip.tmpl.Funcs(renderChainFuncs(ip.chain, ip.overlay))
+	}
+
+	var tmpl = ip.tmpl
+	_, err = tmpl.New(name).Parse(text)
 
 	if err != nil {
-		return text, err
+		return "", newExecError(key, text, err)
+	}
+
+	// Templates a text defines must not be visible to later texts, so the clone isn't reused after one that defines any
+	if definesTemplates(tmpl, name) {
+		ip.tmpl = nil
+	}
+
+	if s := renderStateOf(ip.overlay); s != nil {
+		defer s.keyWarnings(s.warningCount(), key)
 	}
 
 	var tBuf bytes.Buffer
-	err = tmpl.Execute(&tBuf, data)
+	err = tmpl.ExecuteTemplate(limitOutput(&tBuf), name, data)
+
+	if err == nil && rejectNoValue {
+		err = checkNoValue(tBuf.Bytes())
+	}
 
 	if err != nil {
-		return text, err
+		return "", newExecError(key, text, redactRenderError(ip.overlay, err))
 	}
 
 	return tBuf.String(), nil
 }
 
+// definesTemplates reports whether parsing the template named name defined or redefined any other templates
+func definesTemplates(tmpl *template.Template, name string) bool {
+	for _, t := range tmpl.Templates() {
+		if t.Name() != name && t.Tree != nil && t.Tree.ParseName == name {
+			return true
+		}
+	}
+	return false
+}
+
+// InterpolateTo interpolates a template string with data, streaming the output to w
+// Output is written as it is rendered, so on error w may already hold partial output
+func InterpolateTo(w io.Writer, data interface{}, text string) error {
+	tmpl, err := Parse(text)
+	if err != nil {
+		return newExecError("", text, err)
+	}
+	return tmpl.Execute(w, data)
+}
+
+var interpolateEmptyOnError bool
+
+// InterpolateEmptyOnError makes Interpolate return an empty string instead of the template source on error
+// This will become the default in the next major version
+func InterpolateEmptyOnError(enable bool) {
+	interpolateEmptyOnError = enable
+}
+
 // InterpolateMap interpolates a recursive map
+// Errors are wrapped in an ExecError carrying the dotted key path of the failing template
+// The templates are a single render, so memo and the counters are shared between them
 func InterpolateMap(data interface{}, templateMap map[string]interface{}) (map[string]interface{}, error) {
+	return interpolateMap(data, templateMap, "", &interpolator{overlay: newRenderOverlay(nil)})
+}
+
+func interpolateMap(data interface{}, templateMap map[string]interface{}, prefix string, ip *interpolator) (map[string]interface{}, error) {
 	var parsed = map[string]interface{}{}
 	for key, i := range templateMap {
 		if v, ok := i.(string); ok {
-			str, err := Interpolate(data, v)
+			str, err := ip.interpolate(prefix+key, data, v)
 			if err != nil {
 				return nil, err
 			}
@@ -882,7 +1351,7 @@ func InterpolateMap(data interface{}, templateMap map[string]interface{}) (map[s
 		} else if v, ok := i.(bool); ok {
 			parsed[key] = v
 		} else if v, ok := i.(map[string]interface{}); ok {
-			deepParsed, err := InterpolateMap(data, v)
+			deepParsed, err := interpolateMap(data, v, prefix+key+".", ip)
 			if err != nil {
 				return nil, err
 			}
@@ -903,6 +1372,21 @@ func InterpolateMap(data interface{}, templateMap map[string]interface{}) (map[s
 // Template is a wrapper that implements unmarshalJSON
 type Template struct {
 	*template.Template
+	// source the template was parsed from, when parsed with Parse or unmarshaled
+	source string
+	// renderScope records whether the template uses render scoped functions, when parsed with Parse or unmarshaled
+	renderScope renderScope
+	// cacheNamespace prefixes the keys of the cache functions, see SetCacheNamespace
+	cacheNamespace string
+}
+
+// Source returns the source the template was parsed from
+// Templates parsed through the embedded text/template methods return the source reconstructed from the parse tree
+func (t *Template) Source() string {
+	if t.source != "" || t.Template == nil || t.Tree == nil {
+		return t.source
+	}
+	return t.Tree.Root.String()
 }
 
 // UnmarshalJSON implementation for Template
@@ -913,17 +1397,60 @@ func (t *Template) UnmarshalJSON(data []byte) (err error) {
 		return err
 	}
 
+	return t.parse(src)
+}
+
+// parse replaces t with a clone of the RootTemplate parsed from src
+func (t *Template) parse(src string) (err error) {
+	return t.parseNamed("", src)
+}
+
+// parseNamed replaces t with a template named name, associated with a clone of the RootTemplate, parsed from src
+// An empty name uses the name of the RootTemplate
+func (t *Template) parseNamed(name, src string) (err error) {
+	return t.parseWithLimits(name, src, templateLimits)
+}
+
+// parseWithLimits is parseNamed rejecting templates that exceed limits
+func (t *Template) parseWithLimits(name, src string, limits Limits) (err error) {
+	err = limits.checkSourceLimits(src)
+	if err != nil {
+		return err
+	}
+
 	t.Template, err = RootTemplate.Clone()
 
 	if err != nil {
 		return err
 	}
 
+	if name != "" && name != t.Template.Name() {
+		if t.Template.Lookup(name) != nil {
+			return fmt.Errorf("template name %q is already used by a partial", name)
+		}
+		t.Template = t.Template.New(name)
+	}
+
 	_, err = t.Template.Parse(src)
+	if err == nil {
+		err = limits.checkTreeLimits(t)
+	}
+	t.source = src
+	t.renderScope = renderScopeUnknown
+	if err == nil {
+		t.renderScope = renderScopeOf(t.Template)
+	}
 
 	return
 }
 
+// ExecuteTo executes the template, streaming the output to w, and is equivalent to Execute
+// Output is written as it is rendered, so on error w may already hold partial output
+// unless RejectNoValue is enabled, which buffers the output until it has been checked
+func (t *Template) ExecuteTo(w io.Writer, data interface{}) error {
+	return t.Execute(w, data)
+}
+
 // ExecuteToString executes the template and returns the result as a string
 func (t *Template) ExecuteToString(data interface{}) (string, error) {
 	var tBuf bytes.Buffer
@@ -948,24 +1475,115 @@ func (t *Template) ExecuteToInt(data interface{}) (int, error) {
 	return strconv.Atoi(tBuf.String())
 }
 
+// ExecuteToStringSlice executes the template and splits the result on sep
+// Elements are trimmed of whitespace and empty trailing elements are dropped
+func (t *Template) ExecuteToStringSlice(data interface{}, sep string) ([]string, error) {
+	var tBuf bytes.Buffer
+	var err = t.Execute(&tBuf, data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var elems = strings.Split(tBuf.String(), sep)
+	for i, e := range elems {
+		elems[i] = strings.TrimSpace(e)
+	}
+	for len(elems) > 0 && elems[len(elems)-1] == "" {
+		elems = elems[:len(elems)-1]
+	}
+
+	return elems, nil
+}
+
+// ExecuteToMap executes the template and unmarshals the result, which must be a JSON object
+// Numbers are decoded as json.Number
+func (t *Template) ExecuteToMap(data interface{}) (map[string]interface{}, error) {
+	var tBuf bytes.Buffer
+	var err = t.Execute(&tBuf, data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var m map[string]interface{}
+	var dec = json.NewDecoder(&tBuf)
+	dec.UseNumber()
+	err = dec.Decode(&m)
+	if err != nil {
+		return nil, fmt.Errorf("template output is not a JSON object: %w", err)
+	}
+	if m == nil {
+		return nil, fmt.Errorf("template output is not a JSON object: null")
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("template output is not a JSON object: unexpected data after object")
+	}
+
+	return m, nil
+}
+
+// MarshalJSON implementation for Template, emitting the template source as a string
 func (t Template) MarshalJSON() ([]byte, error) {
-	return []byte(fmt.Sprintf("%q", t.Root.String())), nil
+	return json.Marshal(t.Source())
 }
 
 // Parse is a shorthand for template.Parse using templatefuncs
 // Uses a clone of RootTemplate as a base
 func Parse(src string) (*Template, error) {
-	t, err := RootTemplate.Clone()
+	var t Template
+	err := t.parse(src)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = t.Parse(src)
+	return &t, nil
+}
+
+// ParseNamed is Parse with the template given a name, which is returned by Name and reported in
+// errors and to the Observer in place of the name shared by every template parsed with Parse
+func ParseNamed(name, src string) (*Template, error) {
+	var t Template
+	err := t.parseNamed(name, src)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Template{t}, nil
+	return &t, nil
+}
+
+// NamedTemplates unmarshals a JSON object of template sources, naming each template after its key
+// Use it in place of map[string]*Template so errors identify the template they came from
+type NamedTemplates map[string]*Template
+
+// UnmarshalJSON implementation for NamedTemplates
+func (n *NamedTemplates) UnmarshalJSON(data []byte) error {
+	var sources map[string]string
+	err := json.Unmarshal(data, &sources)
+	if err != nil {
+		return err
+	}
+	var templates = make(NamedTemplates, len(sources))
+	for name, src := range sources {
+		var t Template
+		err = t.parseNamed(name, src)
+		if err != nil {
+			return fmt.Errorf("template %q: %w", name, err)
+		}
+		templates[name] = &t
+	}
+	*n = templates
+	return nil
+}
+
+// Delims sets the action delimiters of t, overriding those set with SetDelims
+// It can be used on a zero Template before Parse, e.g. new(Template).Delims("[[", "]]").Parse(src)
+func (t *Template) Delims(left, right string) *Template {
+	if t.Template == nil {
+		t.Template = template.Must(RootTemplate.Clone())
+	}
+	t.Template.Delims(left, right)
+	return t
 }
 
 // Must is an feature copy of template.Must
@@ -976,6 +1594,26 @@ func Must(t *Template, err error) *Template {
 	return t
 }
 
+// MustInterpolate is Interpolate that panics on error, for static or trusted templates only such as
+// constants in tests and init paths. The panic value is an error wrapping the original error.
+func MustInterpolate(data interface{}, text string) string {
+	res, err := InterpolateStrict(data, text)
+	if err != nil {
+		panic(fmt.Errorf("MustInterpolate: %w", err))
+	}
+	return res
+}
+
+// MustExecuteToString is ExecuteToString that panics on error, for static or trusted templates only
+// The panic value is an error wrapping the original error
+func (t *Template) MustExecuteToString(data interface{}) string {
+	res, err := t.ExecuteToString(data)
+	if err != nil {
+		panic(fmt.Errorf("MustExecuteToString: %w", err))
+	}
+	return res
+}
+
 func interfaceToInt64(i interface{}) (int64, error) {
 	switch v := i.(type) {
 	case int64:
@@ -1001,6 +1639,108 @@ func interfaceToInt64(i interface{}) (int64, error) {
 	}
 }
 
+// interfaceToWholeInt64 is interfaceToInt64, but fails rather than truncating floats with a fractional part
+func interfaceToWholeInt64(i interface{}) (int64, error) {
+	switch v := i.(type) {
+	case int32:
+		return int64(v), nil
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return 0, fmt.Errorf("%v is not an integer", v)
+		}
+		return int64(v), nil
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, nil
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return 0, err
+		}
+		return interfaceToWholeInt64(f)
+	default:
+		return interfaceToInt64(i)
+	}
+}
+
+// addInt64 adds two integers of any supported type, failing on overflow
+func addInt64(a, b interface{}) (int64, error) {
+	x, err := interfaceToWholeInt64(a)
+	if err != nil {
+		return 0, err
+	}
+	y, err := interfaceToWholeInt64(b)
+	if err != nil {
+		return 0, err
+	}
+	var sum = x + y
+	if (y > 0 && sum < x) || (y < 0 && sum > x) {
+		return 0, fmt.Errorf("%d + %d overflows int64", x, y)
+	}
+	return sum, nil
+}
+
+func interfaceToFloat64(i interface{}) (float64, error) {
+	switch v := i.(type) {
+	case float64:
+		return v, nil
+	case int:
+		return float64(v), nil
+	case int64:
+		return float64(v), nil
+	case string:
+		return strconv.ParseFloat(v, 64)
+	case json.Number:
+		return v.Float64()
+	default:
+		return 0, fmt.Errorf("unable to convert type %T to float64", i)
+	}
+}
+
+// unixMillisThreshold is the magnitude above which unix timestamps are taken to be in milliseconds, around the year 2286 in seconds
+const unixMillisThreshold = 1e10
+
+// unixAuto converts a unix timestamp in seconds or milliseconds to a UTC time
+func unixAuto(f float64) time.Time {
+	if math.Abs(f) >= unixMillisThreshold {
+		return time.UnixMilli(int64(f)).UTC()
+	}
+	sec, frac := math.Modf(f)
+	return time.Unix(int64(sec), int64(frac*1e9)).UTC()
+}
+
+// interfaceToTime converts a time string in any of the timeutils.ParseAny layouts, a unix timestamp
+// in seconds or milliseconds, or a time.Time to a time.Time
+func interfaceToTime(i interface{}) (time.Time, error) {
+	switch v := i.(type) {
+	case time.Time:
+		return v, nil
+	case *time.Time:
+		if v == nil {
+			return time.Time{}, fmt.Errorf("nil time")
+		}
+		return *v, nil
+	case string:
+		return timeutils.ParseAny(v)
+	case []byte:
+		return timeutils.ParseAny(string(v))
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return time.Time{}, err
+		}
+		return unixAuto(f), nil
+	case int:
+		return unixAuto(float64(v)), nil
+	case int64:
+		return unixAuto(float64(v)), nil
+	case float64:
+		return unixAuto(v), nil
+	default:
+		return time.Time{}, fmt.Errorf("invalid type %T for time", i)
+	}
+}
+
 func interfaceToString(i interface{}) (string, error) {
 	switch v := i.(type) {
 	case int64:
@@ -1017,3 +1757,84 @@ func interfaceToString(i interface{}) (string, error) {
 		return "0", fmt.Errorf("unable to convert type to string")
 	}
 }
+
+// normalizeJSONValue recursively converts interface-keyed maps (as built by dict) into string-keyed maps so they can be marshaled to JSON
+func normalizeJSONValue(i interface{}) (interface{}, error) {
+	switch v := i.(type) {
+	case map[interface{}]interface{}:
+		var m = make(map[string]interface{}, len(v))
+		for key, value := range v {
+			var k string
+			switch kv := key.(type) {
+			case string:
+				k = kv
+			case fmt.Stringer:
+				k = kv.String()
+			default:
+				return nil, fmt.Errorf("unable to convert map key of type %T to string", key)
+			}
+			normalized, err := normalizeJSONValue(value)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = normalized
+		}
+		return m, nil
+	case map[string]interface{}:
+		var m = make(map[string]interface{}, len(v))
+		for k, value := range v {
+			normalized, err := normalizeJSONValue(value)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = normalized
+		}
+		return m, nil
+	case []interface{}:
+		var list = make([]interface{}, len(v))
+		for idx, value := range v {
+			normalized, err := normalizeJSONValue(value)
+			if err != nil {
+				return nil, err
+			}
+			list[idx] = normalized
+		}
+		return list, nil
+	default:
+		return i, nil
+	}
+}
+
+// isEmptyValue reports whether i is nil, a nil pointer, an empty string, a zero number or an empty slice, array or map
+func isEmptyValue(i interface{}) bool {
+	if n, ok := i.(json.Number); ok {
+		f, err := n.Float64()
+		return err == nil && f == 0
+	}
+	var v = reflect.ValueOf(i)
+	switch v.Kind() {
+	case reflect.Invalid:
+		return true
+	case reflect.Ptr, reflect.Interface:
+		return v.IsNil()
+	case reflect.String, reflect.Slice, reflect.Array, reflect.Map:
+		return v.Len() == 0
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	default:
+		return false
+	}
+}
+
+// escapeJSONQuoted encodes s as a JSON string, leaving <, > and & unescaped
+func escapeJSONQuoted(s string) string {
+	var buf bytes.Buffer
+	var enc = json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
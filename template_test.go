@@ -3,8 +3,13 @@ package template
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"math"
 	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"text/template"
 	"time"
@@ -66,6 +71,93 @@ func TestInterpolateMapTypes(t *testing.T) {
 	}
 }
 
+func TestInterpolateMapSharedClone(t *testing.T) {
+	var data = map[string]interface{}{"name": "x"}
+	for i := 0; i < 20; i++ {
+		res, err := InterpolateMap(data, map[string]interface{}{
+			"a":       "{{ .name }}",
+			"empty":   "",
+			"comment": "{{/* nothing */}}",
+			"b":       "b-{{ .name }}",
+			"nested":  map[string]interface{}{"c": "", "d": "{{ .name }}!"},
+		})
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		var expected = map[string]interface{}{
+			"a": "x", "empty": "", "comment": "", "b": "b-x",
+			"nested": map[string]interface{}{"c": "", "d": "x!"},
+		}
+		if !reflect.DeepEqual(res, expected) {
+			t.Errorf(`Unexpected result %v`, res)
+			return
+		}
+	}
+
+	// Templates defined by one key aren't visible to the others, whichever order they run in
+	for i := 0; i < 20; i++ {
+		_, err := InterpolateMap(data, map[string]interface{}{
+			"define": `{{ define "x" }}defined{{ end }}{{ template "x" }}`,
+			"use":    `{{ template "x" }}`,
+		})
+		var execErr *ExecError
+		if !errors.As(err, &execErr) || execErr.Key != "use" {
+			t.Errorf(`Unexpected error %v`, err)
+			return
+		}
+	}
+
+	// A parse error leaves the clone usable, and errors name the template after its key
+	var ip = &interpolator{overlay: newRenderOverlay(nil)}
+	if _, err := ip.interpolate("bad", data, "{{ .name "); err == nil || !strings.Contains(err.Error(), "template: root[bad]:") {
+		t.Errorf(`Unexpected error %v`, err)
+	}
+	if res, err := ip.interpolate("good", data, "{{ .name }}"); err != nil || res != "x" {
+		t.Errorf(`Unexpected result %q: %v`, res, err)
+	}
+	if _, err := Interpolate(data, "{{ .name "); err == nil || !strings.Contains(err.Error(), "template: root:") {
+		t.Errorf(`Unexpected error %v`, err)
+	}
+
+	// Only texts that define templates stop the clone being shared
+	var shared = ip.tmpl
+	if res, err := ip.interpolate("block", map[string]interface{}{"blockId": 1}, "{{ .blockId }}"); err != nil || res != "1" || ip.tmpl != shared {
+		t.Errorf(`Unexpected result %q: %v`, res, err)
+	}
+
+	// Partials redefined by one key are unchanged for the others
+	restoreRootTemplate(t)
+	err := LoadPartialNamed("shared_clone_partial", "partial")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	for i := 0; i < 20; i++ {
+		res, err := InterpolateMap(data, map[string]interface{}{
+			"define": `{{ define "shared_clone_partial" }}redefined{{ end }}{{ template "shared_clone_partial" }}`,
+			"use":    `{{ template "shared_clone_partial" }}`,
+		})
+		if err != nil || res["define"] != "redefined" || res["use"] != "partial" {
+			t.Errorf(`Unexpected result %v: %v`, res, err)
+			return
+		}
+	}
+}
+
+// BenchmarkInterpolateMap100 interpolates a 100 key map, see BenchmarkInterpolateMapWide for 300 keys
+func BenchmarkInterpolateMap100(b *testing.B) {
+	var tmpl = wideTemplateMap(100)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := InterpolateMap(wideTemplateData, tmpl)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
 func TestTemplateFuncFormatTime(t *testing.T) {
 	var tpl = `{{formatTime "2006-01-02" "Mon Jan 2 2006" "2020-11-23"}}`
 	tmpl, err := template.New(t.Name()).Funcs(map[string]interface{}{
@@ -1175,6 +1267,71 @@ func TestExecuteToInt(t *testing.T) {
 	}
 }
 
+func TestExecuteToStringSlice(t *testing.T) {
+	var err error
+	var jsondata = []byte(`"{{ range .tags }}{{ . }}, {{ end }}"`)
+	var tmpl *Template
+	err = json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var s []string
+	s, err = tmpl.ExecuteToStringSlice(map[string]interface{}{
+		"tags": []string{"a", " b", "", "c"},
+	}, ",")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(s, []string{"a", "b", "", "c"}) {
+		t.Errorf(`Unexpected result %q`, s)
+	}
+	s, err = tmpl.ExecuteToStringSlice(map[string]interface{}{}, ",")
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if len(s) != 0 {
+		t.Errorf(`Unexpected result %q`, s)
+	}
+}
+
+func TestExecuteToMap(t *testing.T) {
+	var err error
+	var jsondata = []byte(`"{\"id\":{{ .id | toJSON }},\"amount\":{{ .amount }}}"`)
+	var tmpl *Template
+	err = json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var m map[string]interface{}
+	m, err = tmpl.ExecuteToMap(map[string]interface{}{
+		"id":     "abc",
+		"amount": 12.5,
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(m, map[string]interface{}{"id": "abc", "amount": json.Number("12.5")}) {
+		t.Errorf(`Unexpected result %v`, m)
+	}
+
+	for _, src := range []string{`[1,2]`, `null`, `"a"`, `{"a":1} {"b":2}`, `{"a":`} {
+		tmpl, err = Parse(src)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		_, err = tmpl.ExecuteToMap(nil)
+		if err == nil {
+			t.Errorf(`Expected an error for output %s`, src)
+		}
+	}
+}
+
 func TestMaybeFormatAnyTimeExists(t *testing.T) {
 	var err error
 	var jsondata = []byte(`"{{ .sometimes_time | maybeFormatAnyTime \"2006-01-02\" }}"`)
@@ -1213,12 +1370,49 @@ func TestMaybeFormatAnyTimeNoExists(t *testing.T) {
 		t.Error(err)
 		return
 	}
-	if buf.String() != "<nil>" {
+	if buf.String() != "" {
 		t.Log(buf.String())
 		t.Fail()
 	}
 }
 
+func TestFormatAnyTimeInputs(t *testing.T) {
+	var ts = time.Date(2023, 11, 14, 22, 13, 20, 0, time.UTC)
+	for _, input := range []interface{}{
+		"2023-11-14T22:13:20Z",
+		[]byte("2023-11-14T22:13:20Z"),
+		json.Number("1700000000"),
+		json.Number("1700000000000"),
+		1700000000,
+		int64(1700000000000),
+		float64(1700000000),
+		ts,
+		&ts,
+	} {
+		for _, fn := range []string{"formatAnyTime", "maybeFormatAnyTime"} {
+			res, err := InterpolateStrict(map[string]interface{}{"t": input}, `{{ `+fn+` "2006-01-02T15:04:05" .t }}`)
+			if err != nil {
+				t.Errorf(`%s %T: %v`, fn, input, err)
+				continue
+			}
+			if res != "2023-11-14T22:13:20" {
+				t.Errorf(`%s %T: Unexpected result %q`, fn, input, res)
+			}
+		}
+	}
+
+	for _, input := range []interface{}{nil, "", "not a time", (*time.Time)(nil), true} {
+		res, err := InterpolateStrict(map[string]interface{}{"t": input}, `{{ maybeFormatAnyTime "2006-01-02" .t }}`)
+		if err != nil || res != "" {
+			t.Errorf(`maybeFormatAnyTime %#v: Unexpected result %q %v`, input, res, err)
+		}
+		_, err = InterpolateStrict(map[string]interface{}{"t": input}, `{{ formatAnyTime "2006-01-02" .t }}`)
+		if err == nil {
+			t.Errorf(`formatAnyTime %#v: Expected an error`, input)
+		}
+	}
+}
+
 func TestFingerprintAddress(t *testing.T) {
 	var err error
 	var jsondata = []byte(`"{{ fingerprint_address \"1234 adams st.\" \"city\" \"state\" \"12345\" \"1234\" }}"`)
@@ -1723,6 +1917,50 @@ func TestJsonDisallowUnsafeRender(t *testing.T) {
 	}
 }
 
+func TestAllowUnsafeRenderToggle(t *testing.T) {
+	f, err := os.CreateTemp(``, `go.template.test.render.*.tmp`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = f.WriteString(`{{ .data }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	defer os.Remove(f.Name())
+	defer AllowUnsafeRender(false)
+
+	var src = fmt.Sprintf(`{{ UNSAFE_render "%s" . }}`, f.Name())
+	var data = map[string]interface{}{"data": "x"}
+
+	// Parsed while disabled, then enabled
+	before, err := Parse(src)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	AllowUnsafeRender(true)
+	res, err := before.ExecuteToString(data)
+	if err != nil || res != "x" {
+		t.Errorf(`Expected a template parsed before enabling to render, got %q %v`, res, err)
+	}
+
+	// Parsed while enabled, then disabled
+	after, err := Parse(src)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	AllowUnsafeRender(false)
+	if _, err = after.ExecuteToString(data); err == nil {
+		t.Errorf(`Expected a template parsed while enabled to fail once disabled`)
+	}
+	if _, err = before.ExecuteToString(data); err == nil {
+		t.Errorf(`Expected a template parsed before enabling to fail once disabled`)
+	}
+}
+
 func TestMarshalJson(t *testing.T) {
 	var err error
 	var jsondata = []byte(`{"template":"{{ print (int 0) }}"}`)
@@ -1737,7 +1975,7 @@ func TestMarshalJson(t *testing.T) {
 		t.Error(err)
 		return
 	}
-	if string(b) != `{"template":"{{print (int 0)}}"}` {
+	if string(b) != string(jsondata) {
 		t.Fail()
 	}
 }
@@ -1808,3 +2046,515 @@ func TestNilSafeIndexWithoutNil2(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestBasicAuth(t *testing.T) {
+	var err error
+	var jsondata = []byte(`"{{ basicAuth \"user\" \"pass\" }}"`)
+	var tmpl *Template
+	err = json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != `Basic dXNlcjpwYXNz` {
+		t.Log(buf.String())
+		t.Fail()
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	var err error
+	var jsondata = []byte(`"{{ bearerAuth .token }}|{{ bearerAuth .token | bearerAuth }}"`)
+	var tmpl *Template
+	err = json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{
+		"token": "abc.def",
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != `Bearer abc.def|Bearer abc.def` {
+		t.Log(buf.String())
+		t.Fail()
+	}
+}
+
+func TestAuthHeaders(t *testing.T) {
+	var err error
+	var jsondata = []byte(`"{{ $headers := dict \"Accept\" \"application/json\" }}{{ $auth := basicAuth \"user\" \"\" | authHeaders $headers }}{{ index $auth \"Authorization\" }}|{{ index $auth \"Accept\" }}|{{ len $headers }}"`)
+	var tmpl *Template
+	err = json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != `Basic dXNlcjo=|application/json|1` {
+		t.Log(buf.String())
+		t.Fail()
+	}
+}
+
+func TestCacheHasAndDelete(t *testing.T) {
+	var err error
+	var jsondata = []byte(`"{{ cacheHas \"test3\" }} {{ $_ := cacheSet \"test3\" false \"1m\" }}{{ cacheHas \"test3\" }} {{ cacheGet \"test3\" }} {{ cacheDelete \"test3\" }} {{ cacheHas \"test3\" }} {{ cacheDelete \"test3\" }}"`)
+	var tmpl *Template
+	err = json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != "false true false true false false" {
+		t.Errorf(`Unexpected result %q`, buf.String())
+	}
+}
+
+func TestInterpolateErrorReturnsText(t *testing.T) {
+	var text = `{{ .secret_key | nonexistentFunc }}`
+	res, err := Interpolate(map[string]interface{}{}, text)
+	if err == nil {
+		t.Error("Expected error")
+		return
+	}
+	if res != text {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestInterpolateStrictError(t *testing.T) {
+	for _, text := range []string{`{{ .secret_key | nonexistentFunc }}`, `{{ index .secret_key 5 }}`} {
+		res, err := InterpolateStrict(map[string]interface{}{}, text)
+		if err == nil {
+			t.Error("Expected error")
+			return
+		}
+		if res != "" {
+			t.Errorf(`Unexpected result %q`, res)
+		}
+	}
+}
+
+func TestInterpolateEmptyOnError(t *testing.T) {
+	InterpolateEmptyOnError(true)
+	defer InterpolateEmptyOnError(false)
+	res, err := Interpolate(map[string]interface{}{}, `{{ .secret_key | nonexistentFunc }}`)
+	if err == nil {
+		t.Error("Expected error")
+		return
+	}
+	if res != "" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	parsed, err := InterpolateMap(map[string]interface{}{}, map[string]interface{}{
+		"key": `{{ .secret_key | nonexistentFunc }}`,
+	})
+	if err == nil || parsed != nil {
+		t.Errorf(`Expected error and no output, got %v`, parsed)
+	}
+}
+
+func TestDelims(t *testing.T) {
+	restoreRootTemplate(t)
+	SetDelims("[[", "]]")
+	defer SetDelims("", "")
+
+	err := LoadPartialNamed("delims_partial", `[[ .name ]] {{ .Values.name }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	var tmpl *Template
+	err = json.Unmarshal([]byte(`"name: {{ .Values.name }} [[ .name ]] [[ template \"delims_partial\" . ]]"`), &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := tmpl.ExecuteToString(map[string]interface{}{"name": "x"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "name: {{ .Values.name }} x x {{ .Values.name }}" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+
+	res, err = Interpolate(map[string]interface{}{"name": "x"}, `{{ .name }} [[ .name ]]`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "{{ .name }} x" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestTemplateDelims(t *testing.T) {
+	var tmpl = new(Template)
+	_, err := tmpl.Delims("<%", "%>").Parse(`{{ .name }} <% .name %>`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := tmpl.ExecuteToString(map[string]interface{}{"name": "x"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "{{ .name }} x" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestTemplateFuncDictOddArguments(t *testing.T) {
+	_, err := Interpolate(nil, `{{ dict "a" 1 "b" }}`)
+	if err == nil {
+		t.Errorf(`Expected odd number of arguments to dict to fail`)
+	}
+	_, err = Interpolate(nil, `{{ dictStr "a" }}`)
+	if err == nil {
+		t.Errorf(`Expected odd number of arguments to dictStr to fail`)
+	}
+	_, err = Interpolate(nil, `{{ dictStr 1 2 }}`)
+	if err == nil {
+		t.Errorf(`Expected non-string key to dictStr to fail`)
+	}
+}
+
+func TestTemplateFuncDictToJSON(t *testing.T) {
+	var jsondata = []byte(`"{{ dict \"a\" 1 \"b\" (dict \"c\" .c) | toJSON }} {{ dictStr \"a\" 1 | toJSON }} {{ printf \"%T\" (dictStr \"a\" 1) }}"`)
+	var tmpl *Template
+	err := json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{"c": []interface{}{"x"}})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != `{"a":1,"b":{"c":["x"]}} {"a":1} map[string]interface {}` {
+		t.Errorf(`Unexpected result %q`, buf.String())
+	}
+
+	_, err = Interpolate(nil, `{{ dict 1 2 | toJSON }}`)
+	if err == nil {
+		t.Errorf(`Expected toJSON of a non-string keyed map to fail`)
+	}
+}
+
+func TestTemplateFuncDictFrom(t *testing.T) {
+	var jsondata = []byte(`"{{ $h := dictFrom .headers \"Accept\" \"application/json\" \"X-Id\" .id }}{{ index $h \"Accept\" }}|{{ index $h \"X-Id\" }}|{{ index $h \"X-Tenant\" }}|{{ len .headers }}"`)
+	var tmpl *Template
+	err := json.Unmarshal(jsondata, &tmpl)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var headers = map[string]interface{}{"X-Tenant": "t1", "Accept": "text/plain"}
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, map[string]interface{}{"headers": headers, "id": "1"})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if buf.String() != "application/json|1|t1|2" {
+		t.Errorf(`Unexpected result %q`, buf.String())
+	}
+	if headers["Accept"] != "text/plain" {
+		t.Errorf(`Expected source map to be unchanged, got %v`, headers)
+	}
+
+	_, err = Interpolate(map[string]interface{}{"headers": "x"}, `{{ dictFrom .headers }}`)
+	if err == nil {
+		t.Errorf(`Expected dictFrom of a non-map to fail`)
+	}
+}
+
+func TestTemplateFuncList(t *testing.T) {
+	res, err := Interpolate(map[string]interface{}{"b": "y"}, `{{ $l := list "x" .b 3 }}{{ len $l }}{{ range $l }}|{{ . }}{{ end }} {{ list | toJSON }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "3|x|y|3 []" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestTemplateFuncFirstNonEmpty(t *testing.T) {
+	var data = map[string]interface{}{
+		"paymentOptionPpd": map[string]interface{}{
+			"card_network": "",
+		},
+		"zero":  json.Number("0"),
+		"list":  []interface{}{},
+		"count": 0,
+	}
+	res, err := Interpolate(data, `{{ coalesce .paymentOptionPpd.card_network "visa" }}|{{ firstNonEmpty .paymentOptionPpd.card_network "visa" }}|{{ firstNonEmpty .missing .zero .list .count "x" }}|{{ firstNonEmpty "" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "|visa|x|<no value>" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestUnquoteJSON(t *testing.T) {
+	var data = map[string]interface{}{
+		"comments": `quote "this"` + "\n" + `café`,
+		"plain":    `not quoted`,
+		"escaped":  `"\u00e9\t\"x\""`,
+		"invalid":  `"bad \x"`,
+	}
+	res, err := Interpolate(data, `{{ .comments | toJSON | unquoteJSON }}|{{ unquoteJSON .plain }}|{{ unquoteJSON .escaped }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "quote \"this\"\ncafé|not quoted|é\t\"x\"" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	_, err = Interpolate(data, `{{ unquoteJSON .invalid }}`)
+	if err == nil {
+		t.Errorf(`Expected invalid quoted string to fail`)
+	}
+}
+
+func TestEscapeJSON(t *testing.T) {
+	var data = map[string]interface{}{
+		"comment": "say \"hi\"\nto <me> & \U0001F600\t\x01",
+	}
+	res, err := Interpolate(data, `{"comment": "{{ escapeJSON .comment }}", "quoted": {{ escapeJSONQuoted .comment }}}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != `{"comment": "say \"hi\"\nto <me> & `+"\U0001F600"+`\t\u0001", "quoted": "say \"hi\"\nto <me> & `+"\U0001F600"+`\t\u0001"}` {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	var parsed map[string]string
+	err = json.Unmarshal([]byte(res), &parsed)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if parsed["comment"] != data["comment"] || parsed["quoted"] != data["comment"] {
+		t.Errorf(`Unexpected round trip %q`, parsed)
+	}
+}
+
+func TestTemplateFuncFingerprintTranslit(t *testing.T) {
+	res, err := Interpolate(nil, `{{ fingerprint "Müller Straße" "Köln" }}|{{ fingerprintTranslit "Müller Straße" "Köln" }}|{{ fingerprintTranslit "Muller Strasse" "Koln" }}|{{ fingerprintTranslit "台江区" "Ærø" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "müller_straße_köln|muller_strasse_koln|muller_strasse_koln|台江区_aero" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestTemplateFuncFingerprintSep(t *testing.T) {
+	res, err := Interpolate(nil, `{{ fingerprintSep "-" "1234 Adams St." "City" }}|{{ fingerprintSep "" "a b" "c" }}|{{ fingerprint "1234 Adams St." "City" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "1234-adams-st--city|abc|1234_adams_st__city" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestFingerprintAddressNumericZip(t *testing.T) {
+	var data = map[string]interface{}{
+		"zip":   json.Number("12345"),
+		"plus4": 1234,
+	}
+	res, err := Interpolate(data, `{{ fingerprint_address "1234 adams st." "city" "state" .zip .plus4 }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "1234_adams_st__city_state_12345_1234" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestFingerprintAddressMap(t *testing.T) {
+	var data map[string]interface{}
+	err := json.Unmarshal([]byte(`{
+		"billing": {"line1": "1234 adams st.", "city": "city", "region": "state", "postal_code": 12345, "plus4": "1234"},
+		"shipping": {"address": "1234 adams st.", "city": "city", "state": "state", "zip": "12345"}
+	}`), &data)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	res, err := Interpolate(data, `{{ fingerprint_address_map .billing }}|{{ fingerprint_address_map .shipping }}|{{ fingerprint_address_map (dict "address" "a" "zip" "1") }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "1234_adams_st__city_state_12345_1234|1234_adams_st__city_state_12345_|a___1_" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	_, err = Interpolate(data, `{{ fingerprint_address_map .billing.city }}`)
+	if err == nil {
+		t.Errorf(`Expected a non-map address to fail`)
+	}
+}
+
+func TestCharacterFilters(t *testing.T) {
+	var data = map[string]interface{}{
+		"name": "José Núñez-O'Brien 3rd",
+		"cjk":  "福州 350000号",
+	}
+	res, err := Interpolate(data, `{{ onlyAlpha .name }}|{{ onlyAlphaUnicode .name }}|{{ onlyAlnum .name }}|{{ onlyAlnumUnicode .name }}|{{ onlyAlphaUnicode .cjk }}|{{ onlyAlnumUnicode .cjk }}|{{ keepChars "abcdefghijklmnopqrstuvwxyz-" .name }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "JosNezOBrienrd|JoséNúñezOBrienrd|JosNezOBrien3rd|JoséNúñezOBrien3rd|福州号|福州350000号|osez-rienrd" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
+
+func TestAddMixedTypes(t *testing.T) {
+	var data = map[string]interface{}{
+		"n":     json.Number("5"),
+		"f":     float64(2),
+		"half":  2.5,
+		"s":     "3",
+		"big":   int64(math.MaxInt64),
+		"price": json.Number("1.25"),
+	}
+	res, err := Interpolate(data, `{{ add .n 6 }}|{{ add .f .s }}|{{ addInt64 .n .f }}|{{ addFloat .half .price }}|{{ addFloat .s 1 }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "11|5|7|3.75|4" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	for _, src := range []string{`{{ add .half 1 }}`, `{{ addInt64 .price 1 }}`, `{{ addInt64 .big 1 }}`, `{{ add "x" 1 }}`} {
+		_, err = Interpolate(data, src)
+		if err == nil {
+			t.Errorf(`Expected %s to fail`, src)
+		}
+	}
+}
+
+func TestUnsafeRenderDepthLimit(t *testing.T) {
+	var dir = t.TempDir()
+	var a, b = filepath.Join(dir, "a.tmpl"), filepath.Join(dir, "b.tmpl")
+	err := os.WriteFile(a, []byte(`a{{ UNSAFE_render .b . }}`), 0600)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = os.WriteFile(b, []byte(`b{{ if lt .depth 3 }}{{ UNSAFE_render .a (dict "a" .a "b" .b "depth" (add .depth 1)) }}{{ end }}`), 0600)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	AllowUnsafeRender(true)
+	defer AllowUnsafeRender(false)
+
+	res, err := InterpolateStrict(map[string]interface{}{"a": a, "b": b, "depth": 0}, `{{ UNSAFE_render .a . }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "abababab" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+
+	err = os.WriteFile(b, []byte(`b{{ UNSAFE_render .a . }}`), 0600)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, err = InterpolateStrict(map[string]interface{}{"a": a, "b": b}, `{{ UNSAFE_render .a . }}`)
+	if err == nil || !strings.Contains(err.Error(), "depth limit of 16 exceeded: "+a+" -> "+b+" -> "+a) {
+		t.Errorf(`Expected depth limit error, got %v`, err)
+	}
+}
+
+func TestMustInterpolate(t *testing.T) {
+	if res := MustInterpolate(map[string]interface{}{"a": "b"}, "{{ .a }}"); res != "b" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+	if res := Must(Parse("{{ .a }}")).MustExecuteToString(map[string]interface{}{"a": "c"}); res != "c" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+
+	var expectPanic = func(name string, fn func()) {
+		defer func() {
+			r := recover()
+			err, ok := r.(error)
+			if !ok {
+				t.Errorf(`%s: expected an error panic, got %v`, name, r)
+				return
+			}
+			var execErr *ExecError
+			if !errors.As(err, &execErr) {
+				t.Errorf(`%s: expected the panic to wrap an ExecError, got %v`, name, err)
+			}
+		}()
+		fn()
+	}
+	expectPanic("MustInterpolate", func() {
+		MustInterpolate(nil, "{{ nonexistentFunc }}")
+	})
+	expectPanic("MustExecuteToString", func() {
+		Must(Parse(`{{ parseJSON "{" }}`)).MustExecuteToString(nil)
+	})
+}
+
+func TestTryRender(t *testing.T) {
+	var data = map[string]interface{}{"doc": `{"bin":"411111"}`, "bad": `{`}
+	for src, expected := range map[string]string{
+		`{{ tryRender "{{ (parseJSON .doc).bin }}" . "unknown" }}`:    "411111",
+		`{{ tryRender "{{ (parseJSON .bad).bin }}" . "unknown" }}`:    "unknown",
+		`{{ tryRender "{{ nonexistentFunc }}" . "unknown" }}`:         "unknown",
+		`{{ tryRender "{{ .doc " . "unparseable" }}`:                  "unparseable",
+		`{{ with tryRender "{{ parseJSON .bad }}" . nil }}x{{ end }}`: "",
+	} {
+		res, err := InterpolateStrict(data, src)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if res != expected {
+			t.Errorf(`Unexpected result %q for %s`, res, src)
+		}
+	}
+}
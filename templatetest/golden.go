@@ -0,0 +1,148 @@
+// Package templatetest provides helpers for testing templates against golden files
+package templatetest
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	template "github.com/nickcarenza/go-template"
+)
+
+// Update rewrites golden files with the rendered output instead of comparing against them, set with go test -update
+var Update = flag.Bool("update", false, "rewrite golden files with the rendered output")
+
+// GoldenTime is the time now and timestamp return while ExecuteGolden renders
+var GoldenTime = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// GoldenSeed seeds uuid, randomInt and randomFloat64 while ExecuteGolden renders
+const GoldenSeed = 1
+
+// ExecuteGolden executes tmpl against data and compares the output with the golden file at goldenPath,
+// failing t with a line diff on mismatch. With -update the golden file is written instead.
+// The render uses GoldenTime as the clock and GoldenSeed as the random source so output is reproducible,
+// and restores the wall clock and default random source afterwards, so tests using it must not run in parallel.
+func ExecuteGolden(t testing.TB, tmpl *template.Template, data interface{}, goldenPath string) {
+	t.Helper()
+	template.SetClock(template.FixedClock(GoldenTime))
+	template.SetRandSource(rand.NewSource(GoldenSeed))
+	var buf bytes.Buffer
+	err := tmpl.Execute(&buf, data)
+	template.SetClock(nil)
+	template.SetRandSource(nil)
+	if err != nil {
+		t.Errorf("%s: %v", goldenPath, err)
+		return
+	}
+
+	if *Update {
+		err = os.MkdirAll(filepath.Dir(goldenPath), 0o755)
+		if err == nil {
+			err = os.WriteFile(goldenPath, buf.Bytes(), 0o644)
+		}
+		if err != nil {
+			t.Errorf("%s: %v", goldenPath, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(goldenPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("%s: golden file does not exist, run go test with -update to create it", goldenPath)
+		return
+	} else if err != nil {
+		t.Errorf("%s: %v", goldenPath, err)
+		return
+	}
+	if !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("%s: output does not match golden file, run go test with -update to accept it\n%s", goldenPath, diff(string(want), buf.String()))
+	}
+}
+
+// diffContext is the number of unchanged lines shown around each change
+const diffContext = 2
+
+// diff returns a line diff from want to got, unchanged lines are prefixed with a space,
+// removed lines with - and added lines with +
+func diff(want, got string) string {
+	var a, b = splitLines(want), splitLines(got)
+	// lcs[i][j] is the length of the longest common subsequence of a[i:] and b[j:]
+	var lcs = make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	type line struct {
+		op   byte
+		text string
+	}
+	var lines []line
+	var i, j int
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			lines = append(lines, line{' ', a[i]})
+			i++
+			j++
+		case i < len(a) && (j == len(b) || lcs[i+1][j] >= lcs[i][j+1]):
+			lines = append(lines, line{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, line{'+', b[j]})
+			j++
+		}
+	}
+
+	var out strings.Builder
+	var last = -1
+	for k, l := range lines {
+		if l.op == ' ' {
+			var near bool
+			for d := max(0, k-diffContext); d <= min(len(lines)-1, k+diffContext); d++ {
+				if lines[d].op != ' ' {
+					near = true
+					break
+				}
+			}
+			if !near {
+				continue
+			}
+		}
+		if last >= 0 && k > last+1 {
+			out.WriteString("...\n")
+		}
+		last = k
+		var text = l.text
+		if !strings.HasSuffix(text, "\n") {
+			text += "\n\\ No newline at end\n"
+		}
+		fmt.Fprintf(&out, "%c %s", l.op, text)
+	}
+	return out.String()
+}
+
+// splitLines splits s after each newline, without the empty line following a final newline
+func splitLines(s string) []string {
+	var lines = strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
@@ -0,0 +1,69 @@
+package templatetest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	template "github.com/nickcarenza/go-template"
+)
+
+// recorder captures failures reported to it in place of failing the test
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestExecuteGolden(t *testing.T) {
+	var tmpl = template.Must(template.Parse("id: {{ uuid }}\nat: {{ now \"2006\" }}\nname: {{ .name }}\n"))
+	ExecuteGolden(t, tmpl, map[string]interface{}{"name": "Ada"}, "testdata/welcome.golden")
+
+	var r = &recorder{TB: t}
+	ExecuteGolden(r, tmpl, map[string]interface{}{"name": "Grace"}, "testdata/welcome.golden")
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "- name: Ada\n+ name: Grace\n") {
+		t.Errorf(`Unexpected failures %q`, r.errors)
+	}
+
+	r = &recorder{TB: t}
+	ExecuteGolden(r, tmpl, nil, filepath.Join(t.TempDir(), "missing.golden"))
+	if len(r.errors) != 1 || !strings.Contains(r.errors[0], "-update") {
+		t.Errorf(`Unexpected failures %q`, r.errors)
+	}
+}
+
+func TestExecuteGoldenUpdate(t *testing.T) {
+	*Update = true
+	defer func() { *Update = false }()
+	var path = filepath.Join(t.TempDir(), "out", "update.golden")
+	ExecuteGolden(t, template.Must(template.Parse(`{{ .n }}`)), map[string]interface{}{"n": 1}, path)
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if string(got) != "1" {
+		t.Errorf(`Unexpected golden file %q`, got)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	var res = diff("a\nb\nc\nd\ne\nf\ng\n", "a\nb\nc\nD\ne\nf\ng\nh")
+	var expected = "  b\n  c\n- d\n+ D\n  e\n  f\n  g\n+ h\n\\ No newline at end\n"
+	if res != expected {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+
+	res = diff("1\n2\n3\n4\n5\n6\n7\n8\n", "0\n1\n2\n3\n4\n5\n6\n7\n")
+	expected = "+ 0\n  1\n  2\n...\n  6\n  7\n- 8\n"
+	if res != expected {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
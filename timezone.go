@@ -0,0 +1,51 @@
+package template
+
+import (
+	"fmt"
+	"time"
+)
+
+// loadZone loads an IANA time zone, such as "America/New_York", rejecting an empty name rather than reading it as UTC
+func loadZone(location string) (*time.Location, error) {
+	if location == "" {
+		return nil, fmt.Errorf("empty time zone name")
+	}
+	return time.LoadLocation(location)
+}
+
+// timeInZone converts a time input accepted by interfaceToTime to the time zone location
+func timeInZone(location string, t interface{}) (time.Time, error) {
+	loc, err := loadZone(location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	tm, err := interfaceToTime(t)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return tm.In(loc), nil
+}
+
+// tzOffset returns the UTC offset in effect in a time zone at t, as an offset string ("-05:00"),
+// seconds east of UTC and the zone abbreviation ("EST")
+func tzOffset(location string, t interface{}) (map[string]interface{}, error) {
+	tm, err := timeInZone(location, t)
+	if err != nil {
+		return nil, err
+	}
+	name, seconds := tm.Zone()
+	return map[string]interface{}{
+		"offset":       tm.Format("-07:00"),
+		"seconds":      seconds,
+		"abbreviation": name,
+	}, nil
+}
+
+// isDST reports whether daylight saving time is in effect in a time zone at t
+func isDST(location string, t interface{}) (bool, error) {
+	tm, err := timeInZone(location, t)
+	if err != nil {
+		return false, err
+	}
+	return tm.IsDST(), nil
+}
@@ -0,0 +1,63 @@
+package template
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTZOffset(t *testing.T) {
+	// Daylight saving time started in New York at 2024-03-10 07:00 UTC
+	var cases = []struct {
+		location string
+		t        interface{}
+		expected map[string]interface{}
+		dst      bool
+	}{
+		{"America/New_York", "2024-03-10T06:59:59Z", map[string]interface{}{"offset": "-05:00", "seconds": -18000, "abbreviation": "EST"}, false},
+		{"America/New_York", "2024-03-10T07:00:00Z", map[string]interface{}{"offset": "-04:00", "seconds": -14400, "abbreviation": "EDT"}, true},
+		{"America/New_York", json.Number("1710053999"), map[string]interface{}{"offset": "-05:00", "seconds": -18000, "abbreviation": "EST"}, false},
+		{"America/New_York", time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC), map[string]interface{}{"offset": "-04:00", "seconds": -14400, "abbreviation": "EDT"}, true},
+		{"Asia/Kolkata", int64(1710053999), map[string]interface{}{"offset": "+05:30", "seconds": 19800, "abbreviation": "IST"}, false},
+		{"UTC", "2024-07-01T00:00:00Z", map[string]interface{}{"offset": "+00:00", "seconds": 0, "abbreviation": "UTC"}, false},
+	}
+	for _, c := range cases {
+		res, err := tzOffset(c.location, c.t)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if !reflect.DeepEqual(res, c.expected) {
+			t.Errorf(`Unexpected offset %v for %v in %s`, res, c.t, c.location)
+		}
+		dst, err := isDST(c.location, c.t)
+		if err != nil {
+			t.Error(err)
+			continue
+		}
+		if dst != c.dst {
+			t.Errorf(`Unexpected isDST %v for %v in %s`, dst, c.t, c.location)
+		}
+	}
+
+	for _, location := range []string{"", "Mars/Olympus_Mons"} {
+		_, err := tzOffset(location, "2024-07-01T00:00:00Z")
+		if err == nil {
+			t.Errorf(`Expected unknown zone %q to fail`, location)
+		}
+		_, err = isDST(location, "2024-07-01T00:00:00Z")
+		if err == nil {
+			t.Errorf(`Expected unknown zone %q to fail`, location)
+		}
+	}
+
+	res, err := InterpolateStrict(map[string]interface{}{"at": "2024-11-03T06:30:00Z"}, `{{ (tzOffset "America/Chicago" .at).offset }} {{ isDST "America/Chicago" .at }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if res != "-05:00 true" {
+		t.Errorf(`Unexpected result %q`, res)
+	}
+}
@@ -0,0 +1,220 @@
+package template
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+// ValidationError describes a problem found in template source by Validate
+type ValidationError struct {
+	// Key path of the template within a map passed to ValidateMap
+	Key string `json:"key,omitempty"`
+	// Line and column of the problem in the template source, 1-based, zero when unknown
+	Line   int `json:"line,omitempty"`
+	Column int `json:"column,omitempty"`
+	// Message describing the problem
+	Message string `json:"message"`
+}
+
+// Error implementation for ValidationError
+func (e ValidationError) Error() string {
+	var prefix string
+	if e.Key != "" {
+		prefix = e.Key + ": "
+	}
+	if e.Line > 0 {
+		return fmt.Sprintf("%s%d:%d: %s", prefix, e.Line, e.Column, e.Message)
+	}
+	return prefix + e.Message
+}
+
+// ValidationErrors is a list of problems found by Validate or ValidateMap
+type ValidationErrors []ValidationError
+
+// Error implementation for ValidationErrors
+func (errs ValidationErrors) Error() string {
+	var msgs = make([]string, len(errs))
+	for i, e := range errs {
+		msgs[i] = e.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// builtinFuncs are the functions text/template provides to every template
+var builtinFuncs = map[string]bool{
+	"and": true, "or": true, "not": true, "len": true, "index": true, "slice": true,
+	"print": true, "printf": true, "println": true, "html": true, "js": true, "urlquery": true,
+	"eq": true, "ne": true, "lt": true, "le": true, "gt": true, "ge": true, "call": true,
+}
+
+var reParseErrorLine = regexp.MustCompile(`^template: [^:]*:(\d+):(?:(\d+):)? ?(.*)$`)
+
+// Validate parses src and reports every call to a function that isn't registered and every template that isn't defined
+// The returned error is a ValidationErrors when problems are found
+func Validate(src string) error {
+	errs := validate(src)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validate(src string) ValidationErrors {
+	// Skip the parser's function check so that all unknown functions are reported rather than only the first
+	var tree = parse.New(RootTemplate.Name())
+	tree.Mode = parse.SkipFuncCheck
+	var trees = map[string]*parse.Tree{}
+	_, err := tree.Parse(src, leftDelim, rightDelim, trees)
+	if err != nil {
+		return ValidationErrors{parseValidationError(err)}
+	}
+	var errs ValidationErrors
+	var names = make([]string, 0, len(trees))
+	for name := range trees {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		walkNodes(trees[name].Root, func(node parse.Node) {
+			if tn, ok := node.(*parse.TemplateNode); ok {
+				if _, defined := trees[tn.Name]; !defined && RootTemplate.Lookup(tn.Name) == nil {
+					line, col := lineColumn(src, int(tn.Position()))
+					errs = append(errs, ValidationError{
+						Line:    line,
+						Column:  col,
+						Message: fmt.Sprintf("template %q not defined", tn.Name),
+					})
+				}
+				return
+			}
+			ident, ok := node.(*parse.IdentifierNode)
+			if !ok || builtinFuncs[ident.Ident] {
+				return
+			}
+			if _, ok := TemplateFuncs[ident.Ident]; ok {
+				return
+			}
+			line, col := lineColumn(src, int(ident.Position()))
+			errs = append(errs, ValidationError{
+				Line:    line,
+				Column:  col,
+				Message: fmt.Sprintf("function %q not defined", ident.Ident),
+			})
+		})
+	}
+	return errs
+}
+
+// ValidateMap runs Validate over every string in a nested template map, reporting errors with their key paths
+// The returned error is a ValidationErrors when problems are found
+func ValidateMap(templateMap map[string]interface{}) error {
+	errs := validateValue("", templateMap)
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+func validateValue(keyPath string, i interface{}) ValidationErrors {
+	var errs ValidationErrors
+	switch v := i.(type) {
+	case string:
+		for _, e := range validate(v) {
+			e.Key = keyPath
+			errs = append(errs, e)
+		}
+	case map[string]interface{}:
+		var keys = make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			var childPath = key
+			if keyPath != "" {
+				childPath = keyPath + "." + key
+			}
+			errs = append(errs, validateValue(childPath, v[key])...)
+		}
+	case []interface{}:
+		for idx, item := range v {
+			errs = append(errs, validateValue(keyPath+"["+strconv.Itoa(idx)+"]", item)...)
+		}
+	}
+	return errs
+}
+
+// parseValidationError converts a text/template parse error into a ValidationError, extracting its position
+func parseValidationError(err error) ValidationError {
+	var msg = err.Error()
+	if m := reParseErrorLine.FindStringSubmatch(msg); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		col, _ := strconv.Atoi(m[2])
+		return ValidationError{Line: line, Column: col, Message: m[3]}
+	}
+	return ValidationError{Message: msg}
+}
+
+// lineColumn converts a byte offset in src into a 1-based line and column
+func lineColumn(src string, pos int) (int, int) {
+	if pos > len(src) {
+		pos = len(src)
+	}
+	var before = src[:pos]
+	var line = strings.Count(before, "\n") + 1
+	var col = pos - strings.LastIndex(before, "\n")
+	return line, col
+}
+
+// walkNodes calls fn for node and each node beneath it in the template AST
+func walkNodes(node parse.Node, fn func(parse.Node)) {
+	if node == nil {
+		return
+	}
+	fn(node)
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			walkNodes(child, fn)
+		}
+	case *parse.ActionNode:
+		walkNodes(n.Pipe, fn)
+	case *parse.IfNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.RangeNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.WithNode:
+		walkBranch(&n.BranchNode, fn)
+	case *parse.TemplateNode:
+		walkNodes(n.Pipe, fn)
+	case *parse.PipeNode:
+		if n == nil {
+			return
+		}
+		for _, decl := range n.Decl {
+			walkNodes(decl, fn)
+		}
+		for _, cmd := range n.Cmds {
+			walkNodes(cmd, fn)
+		}
+	case *parse.CommandNode:
+		for _, arg := range n.Args {
+			walkNodes(arg, fn)
+		}
+	case *parse.ChainNode:
+		walkNodes(n.Node, fn)
+	}
+}
+
+func walkBranch(n *parse.BranchNode, fn func(parse.Node)) {
+	walkNodes(n.Pipe, fn)
+	walkNodes(n.List, fn)
+	walkNodes(n.ElseList, fn)
+}
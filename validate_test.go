@@ -0,0 +1,74 @@
+package template
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	err := Validate(`{{ .event.id | toLower }}{{ if eq .a "b" }}{{ printf "%s" .c }}{{ end }}`)
+	if err != nil {
+		t.Error(err)
+	}
+}
+
+func TestValidateUnknownFuncs(t *testing.T) {
+	err := Validate("{{ .a | toLower }}\n{{ if nope .b }}{{ alsoNope }}{{ end }}")
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Errorf(`Expected ValidationErrors, got %v`, err)
+		return
+	}
+	if len(errs) != 2 {
+		t.Errorf(`Expected 2 errors, got %v`, errs)
+		return
+	}
+	if errs[0].Line != 2 || errs[0].Column != 7 || errs[0].Message != `function "nope" not defined` {
+		t.Errorf(`Unexpected error %+v`, errs[0])
+	}
+	if errs[1].Message != `function "alsoNope" not defined` {
+		t.Errorf(`Unexpected error %+v`, errs[1])
+	}
+}
+
+func TestValidateUndefinedTemplate(t *testing.T) {
+	err := Validate(`{{ define "local" }}x{{ end }}{{ template "local" }}{{ template "missing" . }}`)
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 1 || errs[0].Message != `template "missing" not defined` {
+		t.Errorf(`Unexpected errors %v`, err)
+	}
+}
+
+func TestValidateSyntaxError(t *testing.T) {
+	err := Validate("line one\n{{ if .a }}")
+	var errs ValidationErrors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Errorf(`Expected a ValidationErrors, got %v`, err)
+		return
+	}
+	if errs[0].Line != 2 {
+		t.Errorf(`Unexpected error %+v`, errs[0])
+	}
+}
+
+func TestValidateMap(t *testing.T) {
+	err := ValidateMap(map[string]interface{}{
+		"ok":    "{{ .a }}",
+		"count": 5,
+		"nested": map[string]interface{}{
+			"bad": "{{ nope }}",
+			"list": []interface{}{
+				"{{ .b }}",
+				"{{ if }}",
+			},
+		},
+	})
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		t.Errorf(`Expected ValidationErrors, got %v`, err)
+		return
+	}
+	if len(errs) != 2 || errs[0].Key != "nested.bad" || errs[1].Key != "nested.list[1]" {
+		t.Errorf(`Unexpected errors %v`, errs)
+	}
+}
@@ -0,0 +1,373 @@
+package template
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template/parse"
+)
+
+// varOrigin is the data path a template variable or dot refers to, ok is false when it isn't a plain data path
+type varOrigin struct {
+	path string
+	ok   bool
+}
+
+// variableCollector walks a template AST collecting the data paths it references
+// paths maps each path to whether it is required, i.e. referenced outside of any if, with or range condition or body
+type variableCollector struct {
+	paths   map[string]bool
+	guarded int
+	// trees are the templates that can be called by name, with template or include
+	trees map[string]*parse.Tree
+	// called records the templates called, calling those being walked so recursive templates are walked once
+	called  map[string]bool
+	calling map[string]bool
+}
+
+func (c *variableCollector) add(p string) {
+	c.paths[p] = c.paths[p] || c.guarded == 0
+}
+
+// ListVariables returns the sorted, deduplicated data paths (".event.id") referenced by the template source
+// Paths inside range blocks refer to each element with a "[]" segment (".items[].name", or ".[].name" when ranging over the data itself).
+// Variables declared with $x := are resolved to the path they were assigned from when possible and are never reported themselves.
+// Paths used within templates it defines and partials it calls are included, relative to the dot they are called with.
+func ListVariables(src string) ([]string, error) {
+	var tree = parse.New(RootTemplate.Name())
+	tree.Mode = parse.SkipFuncCheck
+	var defined = map[string]*parse.Tree{}
+	_, err := tree.Parse(src, leftDelim, rightDelim, defined)
+	if err != nil {
+		return nil, err
+	}
+	return listTreeVariables(collectTreeVariables(tree, defined)), nil
+}
+
+// Variables returns the data paths referenced by the template, see ListVariables
+func (t *Template) Variables() []string {
+	if t.Tree == nil {
+		return nil
+	}
+	return listTreeVariables(t.collectVariables())
+}
+
+// collectVariables collects the paths referenced by the template and the templates it defines and calls
+func (t *Template) collectVariables() map[string]bool {
+	var defined = map[string]*parse.Tree{}
+	for _, tmpl := range t.Templates() {
+		if tmpl.Tree != nil && (tmpl.Name() == t.Name() || RootTemplate.Lookup(tmpl.Name()) == nil) {
+			defined[tmpl.Name()] = tmpl.Tree
+		}
+	}
+	return collectTreeVariables(t.Tree, defined)
+}
+
+func listTreeVariables(vars map[string]bool) []string {
+	var paths = make([]string, 0, len(vars))
+	for p := range vars {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// collectTreeVariables collects the paths referenced by tree, following the templates it calls with their dot
+// The templates in defined that it doesn't call, such as those only executed by name, are collected as executed with the data.
+// Templates are looked up in defined, then in the loaded partials.
+func collectTreeVariables(tree *parse.Tree, defined map[string]*parse.Tree) map[string]bool {
+	var trees = map[string]*parse.Tree{}
+	for _, tmpl := range RootTemplate.Templates() {
+		if tmpl.Tree != nil {
+			trees[tmpl.Name()] = tmpl.Tree
+		}
+	}
+	var names = make([]string, 0, len(defined))
+	for name, t := range defined {
+		trees[name] = t
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var c = variableCollector{
+		paths:   map[string]bool{},
+		trees:   trees,
+		called:  map[string]bool{tree.Name: true},
+		calling: map[string]bool{tree.Name: true},
+	}
+	var root = varOrigin{ok: true}
+	c.list(tree.Root, root, map[string]varOrigin{"$": root})
+	for _, name := range names {
+		if !c.called[name] {
+			c.template(name, root)
+		}
+	}
+	return c.paths
+}
+
+// template collects the paths referenced by the named template called with dot
+func (c *variableCollector) template(name string, dot varOrigin) {
+	c.called[name] = true
+	var tree = c.trees[name]
+	if tree == nil || !dot.ok || c.calling[name] {
+		return
+	}
+	c.calling[name] = true
+	c.list(tree.Root, dot, map[string]varOrigin{"$": dot})
+	delete(c.calling, name)
+}
+
+func copyVars(vars map[string]varOrigin) map[string]varOrigin {
+	var copied = make(map[string]varOrigin, len(vars))
+	for k, v := range vars {
+		copied[k] = v
+	}
+	return copied
+}
+
+func (c *variableCollector) list(list *parse.ListNode, dot varOrigin, vars map[string]varOrigin) {
+	if list == nil {
+		return
+	}
+	for _, node := range list.Nodes {
+		switch n := node.(type) {
+		case *parse.ActionNode:
+			c.pipe(n.Pipe, dot, vars)
+		case *parse.TemplateNode:
+			c.template(n.Name, c.pipe(n.Pipe, dot, vars))
+		case *parse.IfNode:
+			c.guarded++
+			c.pipe(n.Pipe, dot, vars)
+			c.list(n.List, dot, copyVars(vars))
+			c.list(n.ElseList, dot, copyVars(vars))
+			c.guarded--
+		case *parse.WithNode:
+			c.guarded++
+			var inner = copyVars(vars)
+			var origin = c.pipe(n.Pipe, dot, inner)
+			c.list(n.List, origin, inner)
+			c.list(n.ElseList, dot, copyVars(vars))
+			c.guarded--
+		case *parse.RangeNode:
+			var inner = copyVars(vars)
+			c.guarded++
+			var origin = c.pipeNoDecl(n.Pipe, dot, inner)
+			c.guarded--
+			var path = origin.path
+			if path == "" {
+				// Ranging over the data itself, as in {{ range . }}
+				path = "."
+			}
+			var elem = varOrigin{path: path + "[]", ok: origin.ok}
+			switch len(n.Pipe.Decl) {
+			case 1:
+				inner[n.Pipe.Decl[0].Ident[0]] = elem
+			case 2:
+				inner[n.Pipe.Decl[0].Ident[0]] = varOrigin{}
+				inner[n.Pipe.Decl[1].Ident[0]] = elem
+			}
+			// Paths within each element are required, but only if there are elements to range over
+			c.list(n.List, elem, inner)
+			c.guarded++
+			c.list(n.ElseList, dot, copyVars(vars))
+			c.guarded--
+		}
+	}
+}
+
+// pipe collects the paths used by a pipeline, declares its variables, and returns the origin of its result
+func (c *variableCollector) pipe(pipe *parse.PipeNode, dot varOrigin, vars map[string]varOrigin) varOrigin {
+	var origin = c.pipeNoDecl(pipe, dot, vars)
+	if pipe != nil {
+		for _, decl := range pipe.Decl {
+			vars[decl.Ident[0]] = origin
+		}
+	}
+	return origin
+}
+
+func (c *variableCollector) pipeNoDecl(pipe *parse.PipeNode, dot varOrigin, vars map[string]varOrigin) varOrigin {
+	if pipe == nil {
+		return varOrigin{}
+	}
+	var origin varOrigin
+	for _, cmd := range pipe.Cmds {
+		var origins = make([]varOrigin, len(cmd.Args))
+		for i, arg := range cmd.Args {
+			origins[i] = c.arg(arg, dot, vars)
+			origin = origins[i]
+		}
+		// include "name" data calls a partial like template does
+		if len(cmd.Args) == 3 {
+			ident, isIdent := cmd.Args[0].(*parse.IdentifierNode)
+			name, isString := cmd.Args[1].(*parse.StringNode)
+			if isIdent && isString && ident.Ident == "include" {
+				c.template(name.Text, origins[2])
+			}
+		}
+	}
+	if len(pipe.Cmds) != 1 || len(pipe.Cmds[0].Args) != 1 {
+		return varOrigin{}
+	}
+	return origin
+}
+
+// arg collects the path referenced by a command argument and returns its origin
+func (c *variableCollector) arg(arg parse.Node, dot varOrigin, vars map[string]varOrigin) varOrigin {
+	switch a := arg.(type) {
+	case *parse.DotNode:
+		return dot
+	case *parse.FieldNode:
+		if !dot.ok {
+			return varOrigin{}
+		}
+		var p = dot.path + "." + strings.Join(a.Ident, ".")
+		c.add(p)
+		return varOrigin{path: p, ok: true}
+	case *parse.VariableNode:
+		origin, ok := vars[a.Ident[0]]
+		if !ok || !origin.ok {
+			return varOrigin{}
+		}
+		if len(a.Ident) == 1 {
+			return origin
+		}
+		var p = origin.path + "." + strings.Join(a.Ident[1:], ".")
+		c.add(p)
+		return varOrigin{path: p, ok: true}
+	case *parse.PipeNode:
+		return c.pipe(a, dot, vars)
+	case *parse.ChainNode:
+		c.arg(a.Node, dot, vars)
+	}
+	return varOrigin{}
+}
+
+// MissingDataError lists the data paths referenced by a template that are absent from the data it was checked against
+type MissingDataError struct {
+	// Paths referenced unconditionally
+	Required []string
+	// Paths only referenced within if, with or range conditions and bodies
+	Optional []string
+}
+
+// Error implementation for MissingDataError
+func (e *MissingDataError) Error() string {
+	return "missing required data: " + strings.Join(e.Required, ", ")
+}
+
+// CheckData reports the data paths referenced by the template that are absent or nil in data
+// It returns a *MissingDataError when any required path is missing, listing all missing required and optional paths
+// Each missing path is reported once, at the shallowest segment that is absent.
+// Range element paths are checked against every element and reported with the element index (".items[1].sku").
+func (t *Template) CheckData(data interface{}) error {
+	if t.Tree == nil {
+		return nil
+	}
+	var missing MissingDataError
+	var paths = t.collectVariables()
+	var sorted = make([]string, 0, len(paths))
+	for p := range paths {
+		sorted = append(sorted, p)
+	}
+	sort.Strings(sorted)
+	var seen = map[string]bool{}
+	for _, p := range sorted {
+		for _, absent := range missingDataPaths(data, "", strings.Split(strings.TrimPrefix(p, "."), ".")) {
+			if seen[absent] {
+				continue
+			}
+			seen[absent] = true
+			if paths[p] {
+				missing.Required = append(missing.Required, absent)
+			} else {
+				missing.Optional = append(missing.Optional, absent)
+			}
+		}
+	}
+	if len(missing.Required) == 0 {
+		return nil
+	}
+	return &missing
+}
+
+// missingDataPaths walks segments of a data path from v, returning the concrete paths that are missing
+func missingDataPaths(v interface{}, prefix string, segments []string) []string {
+	if len(segments) == 0 {
+		return nil
+	}
+	var segment = segments[0]
+	var depth int
+	for strings.HasSuffix(segment, "[]") {
+		depth++
+		segment = strings.TrimSuffix(segment, "[]")
+	}
+	if segment == "" {
+		// The value itself is ranged over, as in {{ range . }}
+		if prefix == "" {
+			prefix = "."
+		}
+		return missingElementPaths(v, prefix, depth, segments[1:])
+	}
+	var p = prefix + "." + segment
+
+	var rv = reflect.ValueOf(v)
+	for rv.Kind() == reflect.Interface || rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return []string{p}
+		}
+		rv = rv.Elem()
+	}
+	var child reflect.Value
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String && rv.Type().Key().Kind() != reflect.Interface {
+			return []string{p}
+		}
+		child = rv.MapIndex(reflect.ValueOf(segment).Convert(rv.Type().Key()))
+	case reflect.Struct:
+		child = rv.FieldByName(segment)
+	}
+	if !child.IsValid() {
+		// Fields may also be niladic methods, such as .Int64 on a json.Number
+		if reflect.ValueOf(v).MethodByName(segment).IsValid() {
+			return nil
+		}
+		return []string{p}
+	}
+	for child.Kind() == reflect.Interface {
+		child = child.Elem()
+	}
+	if !child.IsValid() || ((child.Kind() == reflect.Ptr || child.Kind() == reflect.Map || child.Kind() == reflect.Slice) && child.IsNil()) {
+		return []string{p}
+	}
+	if depth == 0 {
+		return missingDataPaths(child.Interface(), p, segments[1:])
+	}
+	return missingElementPaths(child.Interface(), p, depth, segments[1:])
+}
+
+// missingElementPaths walks the remaining segments from each element of the list v, nested depth lists deep
+func missingElementPaths(v interface{}, prefix string, depth int, segments []string) []string {
+	var rv = reflect.ValueOf(v)
+	for rv.Kind() == reflect.Interface || rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil
+	}
+	var missing []string
+	for i := 0; i < rv.Len(); i++ {
+		var p = prefix + "[" + strconv.Itoa(i) + "]"
+		if depth > 1 {
+			missing = append(missing, missingElementPaths(rv.Index(i).Interface(), p, depth-1, segments)...)
+		} else {
+			missing = append(missing, missingDataPaths(rv.Index(i).Interface(), p, segments)...)
+		}
+	}
+	return missing
+}
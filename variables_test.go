@@ -0,0 +1,180 @@
+package template
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestListVariables(t *testing.T) {
+	vars, err := ListVariables(`{{ .event.id }}{{ if .customer.email }}{{ .customer.email | toLower }}{{ end }}{{ with .customer.address }}{{ .city }}{{ else }}{{ .fallback }}{{ end }}{{ range $i, $item := .items }}{{ $item.sku }}{{ .qty }}{{ $.event.type }}{{ end }}{{ $name := .customer.name }}{{ $name.first }}{{ $count := len .items }}{{ $count }}{{ printf "%s" (index .tags 0) }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var expected = []string{
+		".customer.address",
+		".customer.address.city",
+		".customer.email",
+		".customer.name",
+		".customer.name.first",
+		".event.id",
+		".event.type",
+		".fallback",
+		".items",
+		".items[].qty",
+		".items[].sku",
+		".tags",
+	}
+	if strings.Join(vars, ",") != strings.Join(expected, ",") {
+		t.Errorf(`Unexpected variables %q`, vars)
+	}
+}
+
+func TestListVariablesParseError(t *testing.T) {
+	_, err := ListVariables(`{{ if .a }}`)
+	if err == nil {
+		t.Error("Expected parse error")
+	}
+}
+
+func TestListVariablesTemplates(t *testing.T) {
+	restoreRootTemplate(t)
+	err := LoadPartialNamed("variables_address", `{{ .city }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	vars, err := ListVariables(`{{ define "customer" }}{{ .email }}{{ $.event.id }}{{ end }}` +
+		`{{ define "uncalled" }}{{ .reason }}{{ end }}` +
+		`{{ template "customer" .customer }}{{ block "footer" . }}{{ .company.name }}{{ end }}` +
+		`{{ template "variables_address" .shipping }}{{ include "variables_address" .billing }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var expected = []string{
+		".billing",
+		".billing.city",
+		".company.name",
+		".customer",
+		".customer.email",
+		".customer.event.id",
+		".reason",
+		".shipping",
+		".shipping.city",
+	}
+	if strings.Join(vars, ",") != strings.Join(expected, ",") {
+		t.Errorf(`Unexpected variables %q`, vars)
+	}
+
+	tmpl, err := Parse(`{{ define "row" }}{{ .sku }}{{ end }}{{ range .items }}{{ template "row" . }}{{ end }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if vars := strings.Join(tmpl.Variables(), ","); vars != ".items,.items[].sku" {
+		t.Errorf(`Unexpected variables %q`, vars)
+	}
+}
+
+func TestTemplateVariables(t *testing.T) {
+	tmpl, err := Parse(`{{ .a }}{{ .b.c }}{{ .a }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if vars := strings.Join(tmpl.Variables(), ","); vars != ".a,.b.c" {
+		t.Errorf(`Unexpected variables %q`, vars)
+	}
+}
+
+func TestCheckData(t *testing.T) {
+	tmpl, err := Parse(`{{ .event.id }}{{ .customer.email }}{{ if .customer.phone }}{{ .customer.phone }}{{ end }}{{ with .promo }}{{ .code }}{{ end }}{{ range .items }}{{ .sku }}{{ end }}{{ .amount.Int64 }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	err = tmpl.CheckData(map[string]interface{}{
+		"event":    map[string]interface{}{"id": "1"},
+		"customer": map[string]interface{}{"email": "a@example.com"},
+		"items": []interface{}{
+			map[string]interface{}{"sku": "a"},
+		},
+		"amount": json.Number("5"),
+	})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+
+	err = tmpl.CheckData(map[string]interface{}{
+		"customer": map[string]interface{}{},
+		"items": []interface{}{
+			map[string]interface{}{"sku": "a"},
+			map[string]interface{}{},
+		},
+	})
+	var missing *MissingDataError
+	if !errors.As(err, &missing) {
+		t.Errorf(`Expected MissingDataError, got %v`, err)
+		return
+	}
+	if required := strings.Join(missing.Required, ","); required != ".amount,.customer.email,.event,.items[1].sku" {
+		t.Errorf(`Unexpected required paths %q`, required)
+	}
+	if optional := strings.Join(missing.Optional, ","); optional != ".customer.phone,.promo" {
+		t.Errorf(`Unexpected optional paths %q`, optional)
+	}
+}
+
+func TestCheckDataRangeDot(t *testing.T) {
+	var cases = []struct {
+		src      string
+		data     interface{}
+		required string
+		optional string
+	}{
+		{`{{ range . }}{{ .x }}{{ end }}`, []interface{}{map[string]interface{}{"x": 1}}, "", ""},
+		{`{{ range . }}{{ .x }}{{ end }}`, []interface{}{map[string]interface{}{"x": 1}, map[string]interface{}{}}, ".[1].x", ""},
+		{`{{ range . }}{{ .x }}{{ end }}`, nil, "", ""},
+		{`{{ with index . 0 }}{{ range $ }}{{ .x }}{{ end }}{{ end }}`, []interface{}{map[string]interface{}{"x": 1}}, "", ""},
+		{`{{ range . }}{{ .x }}{{ end }}{{ with index . 0 }}{{ range $ }}{{ .y }}{{ end }}{{ end }}`, []interface{}{map[string]interface{}{"x": 1, "y": 1}, map[string]interface{}{}}, ".[1].x", ".[1].y"},
+		{`{{ range .items }}{{ range . }}{{ .x }}{{ end }}{{ end }}`, map[string]interface{}{"items": []interface{}{[]interface{}{map[string]interface{}{}}}}, ".items[0][0].x", ""},
+	}
+	for _, c := range cases {
+		tmpl, err := Parse(c.src)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		err = tmpl.CheckData(c.data)
+		var missing *MissingDataError
+		if c.required == "" {
+			if err != nil {
+				t.Errorf(`Unexpected error for %s: %v`, c.src, err)
+			}
+			continue
+		}
+		if !errors.As(err, &missing) {
+			t.Errorf(`Expected MissingDataError for %s, got %v`, c.src, err)
+			continue
+		}
+		if required := strings.Join(missing.Required, ","); required != c.required {
+			t.Errorf(`Unexpected required paths %q for %s`, required, c.src)
+		}
+		if optional := strings.Join(missing.Optional, ","); optional != c.optional {
+			t.Errorf(`Unexpected optional paths %q for %s`, optional, c.src)
+		}
+	}
+
+	vars, err := ListVariables(`{{ range . }}{{ .x }}{{ end }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if strings.Join(vars, ",") != ".[].x" {
+		t.Errorf(`Unexpected variables %v`, vars)
+	}
+}
@@ -0,0 +1,88 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+)
+
+// warning is a message recorded with warn, with the key path of the template within an interpolated map
+type warning struct {
+	key     string
+	message string
+}
+
+func (w warning) String() string {
+	if w.key != "" {
+		return w.key + ": " + w.message
+	}
+	return w.message
+}
+
+// warn records message as a warning of the render and returns value unchanged
+// Warnings don't fail the render. They are returned by ExecuteToStringWithWarnings and InterpolateMapWithWarnings,
+// and dropped by the other functions.
+func (s *renderState) warn(message interface{}, value interface{}) interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.warnings = append(s.warnings, warning{message: fmt.Sprint(message)})
+	return value
+}
+
+// warningCount returns the number of warnings recorded so far, for keyWarnings
+func (s *renderState) warningCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.warnings)
+}
+
+// keyWarnings sets key as the key path of the warnings recorded since the first from that don't have one
+func (s *renderState) keyWarnings(from int, key string) {
+	if key == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := from; i < len(s.warnings); i++ {
+		if s.warnings[i].key == "" {
+			s.warnings[i].key = key
+		}
+	}
+}
+
+// warningStrings returns the warnings of the render ordered by key path, then in the order they were recorded
+func (s *renderState) warningStrings() []string {
+	s.mu.Lock()
+	var warnings = append([]warning(nil), s.warnings...)
+	s.mu.Unlock()
+	sort.SliceStable(warnings, func(i, j int) bool {
+		return warnings[i].key < warnings[j].key
+	})
+	var strs = make([]string, len(warnings))
+	for i, w := range warnings {
+		strs[i] = w.String()
+	}
+	return strs
+}
+
+// ExecuteToStringWithWarnings is ExecuteToString also returning the messages the template recorded with warn
+// Warnings recorded before an error are returned with it.
+func (t *Template) ExecuteToStringWithWarnings(data interface{}) (string, []string, error) {
+	var overlay = newRenderOverlay(nil)
+	var buf bytes.Buffer
+	var err = t.execute(&buf, data, rejectNoValue, overlay)
+	var warnings = renderStateOf(overlay).warningStrings()
+	if err != nil {
+		return "", warnings, err
+	}
+	return buf.String(), warnings, nil
+}
+
+// InterpolateMapWithWarnings is InterpolateMap also returning the messages the templates recorded with warn
+// Each warning is prefixed with the dotted key path of its template, and they are ordered by key path.
+// Warnings recorded before an error are returned with it.
+func InterpolateMapWithWarnings(data interface{}, templateMap map[string]interface{}) (map[string]interface{}, []string, error) {
+	var overlay = newRenderOverlay(nil)
+	res, err := interpolateMap(data, templateMap, "", &interpolator{overlay: overlay})
+	return res, renderStateOf(overlay).warningStrings(), err
+}
@@ -0,0 +1,96 @@
+package template
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExecuteToStringWithWarnings(t *testing.T) {
+	tmpl, err := Parse(`{{ with .brand }}{{ . }}{{ else }}{{ warn "fallback brand used" "generic" }}{{ end }}/{{ warn 42 .n }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	result, warnings, err := tmpl.ExecuteToStringWithWarnings(map[string]interface{}{"n": 7})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if result != "generic/7" {
+		t.Errorf(`Unexpected result %q`, result)
+	}
+	if !reflect.DeepEqual(warnings, []string{"fallback brand used", "42"}) {
+		t.Errorf(`Unexpected warnings %q`, warnings)
+	}
+
+	// Output is the same without collecting warnings, and executions don't share them
+	result, err = tmpl.ExecuteToString(map[string]interface{}{"n": 7})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if result != "generic/7" {
+		t.Errorf(`Unexpected result %q`, result)
+	}
+	result, warnings, err = tmpl.ExecuteToStringWithWarnings(map[string]interface{}{"brand": "acme", "n": 7})
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if result != "acme/7" || !reflect.DeepEqual(warnings, []string{"42"}) {
+		t.Errorf(`Unexpected result %q with warnings %q`, result, warnings)
+	}
+
+	// Warnings recorded before an error are returned with it
+	tmpl, err = Parse(`{{ warn "first" "" }}{{ fail "stop" }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	_, warnings, err = tmpl.ExecuteToStringWithWarnings(nil)
+	if err == nil || !reflect.DeepEqual(warnings, []string{"first"}) {
+		t.Errorf(`Unexpected warnings %q with error %v`, warnings, err)
+	}
+}
+
+func TestInterpolateMapWithWarnings(t *testing.T) {
+	restoreRootTemplate(t)
+	err := LoadPartial("brand", `{{ with .brand }}{{ . }}{{ else }}{{ warn "fallback brand used" "generic" }}{{ end }}`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var templateMap = map[string]interface{}{
+		"name": `{{ warn "name is a placeholder" "n/a" }}`,
+		"card": map[string]interface{}{
+			"brand":  `{{ include "brand" . }}`,
+			"number": `{{ .number }}`,
+		},
+		"total": `{{ memo "total" "{{ warn \"total estimated\" 10 }}" . }}`,
+	}
+	result, warnings, err := InterpolateMapWithWarnings(map[string]interface{}{"number": "4111"}, templateMap)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var expected = map[string]interface{}{
+		"name":  "n/a",
+		"card":  map[string]interface{}{"brand": "generic", "number": "4111"},
+		"total": "10",
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf(`Unexpected result %v`, result)
+	}
+	if !reflect.DeepEqual(warnings, []string{"card.brand: fallback brand used", "name: name is a placeholder", "total: total estimated"}) {
+		t.Errorf(`Unexpected warnings %q`, warnings)
+	}
+
+	plain, err := InterpolateMap(map[string]interface{}{"number": "4111"}, templateMap)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	if !reflect.DeepEqual(plain, expected) {
+		t.Errorf(`Unexpected result %v`, plain)
+	}
+}
@@ -0,0 +1,36 @@
+package template
+
+import (
+	"strings"
+)
+
+// xmlEscaper escapes the characters with special meaning in XML element content and attribute values
+var xmlEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", "'", "&apos;", `"`, "&quot;")
+
+// isXMLChar reports whether r is allowed in an XML 1.0 document
+func isXMLChar(r rune) bool {
+	return r == '\t' || r == '\n' || r == '\r' ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}
+
+// xmlSanitize replaces characters that can't appear in an XML document, such as control characters, with U+FFFD
+func xmlSanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		if !isXMLChar(r) {
+			return '\uFFFD'
+		}
+		return r
+	}, s)
+}
+
+// xmlEscape escapes &, <, >, ' and " so s can be placed in XML element content or a quoted attribute value
+func xmlEscape(s string) string {
+	return xmlEscaper.Replace(xmlSanitize(s))
+}
+
+// cdata wraps s in a CDATA section, splitting it around any ]]> so the sequence can't end the section early
+func cdata(s string) string {
+	return "<![CDATA[" + strings.ReplaceAll(xmlSanitize(s), "]]>", "]]]]><![CDATA[>") + "]]>"
+}
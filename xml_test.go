@@ -0,0 +1,54 @@
+package template
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func TestXMLEscape(t *testing.T) {
+	for s, expected := range map[string]string{
+		`Smith & Sons <Ltd>`: `Smith &amp; Sons &lt;Ltd&gt;`,
+		`O'Brien "Jr"`:       `O&apos;Brien &quot;Jr&quot;`,
+		`Zoë ☺`:              `Zoë ☺`,
+		"bell\x07 and\ttab":  "bell\uFFFD and\ttab",
+		``:                   ``,
+	} {
+		if res := xmlEscape(s); res != expected {
+			t.Errorf(`Unexpected result %q for %q`, res, s)
+		}
+	}
+}
+
+func TestCDATA(t *testing.T) {
+	for s, expected := range map[string]string{
+		`a < b && c`: `<![CDATA[a < b && c]]>`,
+		`x]]>y`:      `<![CDATA[x]]]]><![CDATA[>y]]>`,
+		`]]>]]>`:     `<![CDATA[]]]]><![CDATA[>]]]]><![CDATA[>]]>`,
+		``:           `<![CDATA[]]>`,
+	} {
+		if res := cdata(s); res != expected {
+			t.Errorf(`Unexpected result %q for %q`, res, s)
+		}
+	}
+}
+
+func TestXMLTemplateRoundTrip(t *testing.T) {
+	var data = map[string]interface{}{"name": `Tom & "Jerry's" <Co>`, "notes": `if a]]>b & c<d`}
+	res, err := InterpolateStrict(data, `<Customer name="{{ xmlEscape .name }}"><Name>{{ xmlEscape .name }}</Name><Notes>{{ cdata .notes }}</Notes></Customer>`)
+	if err != nil {
+		t.Error(err)
+		return
+	}
+	var customer struct {
+		Attr  string `xml:"name,attr"`
+		Name  string `xml:"Name"`
+		Notes string `xml:"Notes"`
+	}
+	if err := xml.Unmarshal([]byte(res), &customer); err != nil {
+		t.Error(err)
+		return
+	}
+	if customer.Attr != data["name"] || customer.Name != data["name"] || customer.Notes != data["notes"] {
+		t.Errorf(`Unexpected result %+v`, customer)
+	}
+}